@@ -6,10 +6,12 @@ import (
 	"fmt"
 	"html/template"
 	"log"
+	"net"
 	"net/http"
 	"os"
 	"os/signal"
 	"path/filepath"
+	"strconv"
 	"syscall"
 	"time"
 
@@ -18,11 +20,14 @@ import (
 	"github.com/ceesaxp/tour-guide-editor/internal/handlers"
 	"github.com/ceesaxp/tour-guide-editor/internal/middleware"
 	"github.com/ceesaxp/tour-guide-editor/internal/mocks"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
 	"github.com/ceesaxp/tour-guide-editor/internal/services"
+	"github.com/ceesaxp/tour-guide-editor/internal/storage"
 )
 
 func main() {
 	configPath := flag.String("config", "config/config.yaml", "path to config file")
+	mockS3 := flag.Bool("mock-s3", false, "discard media uploads instead of storing them in S3, for development")
 	flag.Parse()
 
 	// Load configuration
@@ -33,22 +38,45 @@ func main() {
 	log.Printf("TTL is %d", cfg.Auth.TokenTTL)
 
 	// Initialize services
-	tourService := services.NewTourService()
+	s3Client, err := storage.NewS3Client(context.Background(), cfg.S3)
+	if err != nil {
+		log.Fatalf("Failed to create S3 client: %v", err)
+	}
+	tours, err := newTourRepository(cfg, s3Client)
+	if err != nil {
+		log.Fatalf("Failed to open tour storage: %v", err)
+	}
+	tourService := services.NewTourService(tours)
+	if cfg.Editor.SessionTTL > 0 {
+		tourService.SetSessionTTL(time.Duration(cfg.Editor.SessionTTL) * time.Minute)
+	}
 
-	// For development, use mock S3 client
-	mockS3 := &mocks.MockS3Client{
-		PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
-			return &s3.PutObjectOutput{}, nil
-		},
+	var mediaS3 storage.S3Client = s3Client
+	if *mockS3 {
+		log.Printf("WARN: media uploads are discarded (-mock-s3)")
+		mediaS3 = &mocks.MockS3Client{
+			PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+				return &s3.PutObjectOutput{}, nil
+			},
+		}
+	}
+	media, err := newMediaStore(cfg, mediaS3)
+	if err != nil {
+		log.Fatalf("Failed to open media storage: %v", err)
 	}
 
 	mediaService := services.NewMediaService(services.MediaConfig{
-		MaxFileSize:    cfg.Media.MaxFileSize,
-		AllowedFormats: cfg.Media.AllowedFormats,
-		ImageMaxWidth:  cfg.Media.ImageMaxWidth,
-		ImageMaxHeight: cfg.Media.ImageMaxHeight,
-		S3Bucket:       cfg.S3.MediaBucket,
-	}, mockS3)
+		MaxFileSize:      cfg.Media.MaxFileSize,
+		AllowedFormats:   cfg.Media.AllowedFormats,
+		ImageMaxWidth:    cfg.Media.ImageMaxWidth,
+		ImageMaxHeight:   cfg.Media.ImageMaxHeight,
+		FFmpeg:           cfg.Media.FFmpeg,
+		FFprobe:          cfg.Media.FFprobe,
+		AudioBitrate:     cfg.Media.AudioBitrate,
+		AudioChannels:    cfg.Media.AudioChannels,
+		LoudnessTarget:   cfg.Media.LoudnessTarget,
+		TranscodeTimeout: time.Duration(cfg.Media.TranscodeTimeout) * time.Second,
+	}, media)
 
 	// Initialize auth handler
 	authTemplates, err := template.ParseGlob(filepath.Join("templates", "*.html"))
@@ -66,16 +94,21 @@ func main() {
 	if err != nil {
 		log.Fatalf("Failed to create editor handler: %v", err)
 	}
+	dashboardHandler := handlers.NewDashboardHandler("templates", tourService)
 
 	// Setup routes with middleware
-	router := setupRoutes(editorHandler, authHandler, cfg.Auth)
+	var mediaFiles http.Handler // S3 serves its files itself
+	if cfg.Media.Backend == config.StorageFilesystem {
+		mediaFiles = handlers.MediaFiles(media)
+	}
+	router := setupRoutes(editorHandler, dashboardHandler, authHandler, cfg.Auth, mediaFiles)
 
 	// Add global middleware
 	handler := middleware.Chain(
 		router,
 		middleware.Logger,
+		handlers.IfMatch,
 		//middleware.RequireAuth(cfg.Auth.SecretKey),
-		middleware.SessionID, // Add this middleware to generate session IDs
 	)
 
 	// Create server
@@ -119,8 +152,48 @@ func main() {
 	}
 }
 
+// newTourRepository opens the tour storage selected in the config.
+func newTourRepository(cfg *config.Config, client *s3.Client) (services.TourRepository, error) {
+	switch cfg.Storage.Backend {
+	case config.StorageFilesystem:
+		return repository.NewFilesystem(cfg.Storage.Dir)
+	case config.StorageMemory:
+		return repository.NewMemory(), nil
+	case config.StorageS3:
+		return repository.NewS3(client, cfg.S3.TourBucket), nil
+	default:
+		return nil, fmt.Errorf("unknown storage backend %q", cfg.Storage.Backend)
+	}
+}
+
+// newMediaStore opens the media storage selected in the config. With the
+// s3 backend uploads go through client.
+func newMediaStore(cfg *config.Config, client storage.S3Client) (storage.MediaStore, error) {
+	switch cfg.Media.Backend {
+	case config.StorageFilesystem:
+		return storage.NewFilesystem(cfg.Media.Dir, mediaFilesURL(cfg))
+	case config.StorageS3:
+		return storage.NewS3Store(client, cfg.S3.MediaBucket, cfg.S3.MediaURL()), nil
+	default:
+		return nil, fmt.Errorf("unknown media backend %q", cfg.Media.Backend)
+	}
+}
+
+// mediaFilesURL returns the base URL of the filesystem media backend's
+// files: media.public_url, or else the app's own. Tours need absolute URLs.
+func mediaFilesURL(cfg *config.Config) string {
+	if cfg.Media.PublicURL != "" {
+		return cfg.Media.PublicURL
+	}
+	host := cfg.Server.Host
+	if host == "" || host == "0.0.0.0" {
+		host = "localhost"
+	}
+	return fmt.Sprintf("http://%s%s", net.JoinHostPort(host, strconv.Itoa(cfg.Server.Port)), storage.FilesURL)
+}
+
 // Update cmd/server/main.go setupRoutes
-func setupRoutes(e *handlers.EditorHandler, a *handlers.AuthHandler, cfg config.Auth) http.Handler {
+func setupRoutes(e *handlers.EditorHandler, d *handlers.DashboardHandler, a *handlers.AuthHandler, cfg config.Auth, mediaFiles http.Handler) http.Handler {
 	mux := http.NewServeMux()
 
 	// Auth routes (unprotected)
@@ -130,21 +203,67 @@ func setupRoutes(e *handlers.EditorHandler, a *handlers.AuthHandler, cfg config.
 
 	// Static files
 	mux.Handle("/static/", http.StripPrefix("/static/", http.FileServer(http.Dir("static"))))
+	if mediaFiles != nil {
+		mux.Handle("GET "+storage.FilesURL+"/", mediaFiles)
+	}
 
 	// Protected routes
 	protected := middleware.RequireAuth(cfg.SecretKey)
 
+	// Dashboard routes
+	mux.Handle("GET /tours", protected(d))
+	mux.Handle("POST /tours", protected(http.HandlerFunc(d.HandleTourCreate)))
+	mux.Handle("POST /tours/{id}/open", protected(http.HandlerFunc(d.HandleTourOpen)))
+	mux.Handle("POST /tours/{id}/duplicate", protected(http.HandlerFunc(d.HandleTourDuplicate)))
+	mux.Handle("POST /tours/{id}/archive", protected(http.HandlerFunc(d.HandleTourArchive)))
+	mux.Handle("POST /tours/{id}/restore", protected(http.HandlerFunc(d.HandleTourRestore)))
+
 	// Editor routes
 	mux.Handle("/", protected(http.HandlerFunc(e.ServeHTTP)))
 	mux.Handle("/tour/metadata", protected(http.HandlerFunc(e.HandleTourMetadata)))
-	//mux.Handle("/tour/preview", protected(http.HandlerFunc(e.HandleTourPreview)))
-	//mux.Handle("/tour/export", protected(http.HandlerFunc(e.HandleTourExport)))
-	//mux.Handle("/nodes/new", protected(http.HandlerFunc(e.HandleNewNode)))
+	mux.Handle("/tour/validate", protected(http.HandlerFunc(e.HandleTourValidate)))
+	mux.Handle("/tour/translations/{lang}", protected(http.HandlerFunc(e.HandleTranslationsExport)))
+	mux.Handle("POST /tour/translations/{lang}", protected(http.HandlerFunc(e.HandleTranslationsImport)))
+	mux.Handle("POST /tour/import/check", protected(http.HandlerFunc(e.HandleTourImportCheck)))
+	mux.Handle("POST /tour/import", protected(http.HandlerFunc(e.HandleTourImport)))
+	mux.Handle("GET /tour/preview", protected(http.HandlerFunc(e.HandleTourPreview)))
+	mux.Handle("GET /tour/export", protected(http.HandlerFunc(e.HandleTourExport)))
+	mux.Handle("GET /tour/history", protected(http.HandlerFunc(e.HandleTourHistory)))
+	mux.Handle("GET /tour/history/{rev}", protected(http.HandlerFunc(e.HandleRevisionView)))
+	mux.Handle("GET /tour/history/{rev}/diff", protected(http.HandlerFunc(e.HandleRevisionDiff)))
+	mux.Handle("POST /tour/history/{rev}/restore", protected(http.HandlerFunc(e.HandleRevisionRestore)))
+	mux.Handle("GET /tour/undo-redo", protected(http.HandlerFunc(e.HandleUndoRedo)))
+	mux.Handle("GET /tour/audio-time", protected(http.HandlerFunc(e.HandleTourAudioTime)))
+	mux.Handle("POST /tour/undo", protected(http.HandlerFunc(e.HandleUndo)))
+	mux.Handle("POST /tour/redo", protected(http.HandlerFunc(e.HandleRedo)))
+	mux.Handle("GET /nodes/new", protected(http.HandlerFunc(e.HandleNodeEditor)))
 	mux.Handle("/nodes", protected(http.HandlerFunc(e.HandleNodesList)))
 	mux.Handle("/nodes/{id}/edit", protected(http.HandlerFunc(e.HandleNodeEditor)))
 	mux.Handle("/nodes/{id}", protected(http.HandlerFunc(e.HandleNodeSave)))
+	mux.Handle("DELETE /nodes/{id}", protected(http.HandlerFunc(e.HandleNodeDelete)))
+	mux.Handle("GET /nodes/{id}/media/new", protected(http.HandlerFunc(e.HandleNodeMediaNew)))
+	mux.Handle("POST /nodes/{id}/media", protected(http.HandlerFunc(e.HandleNodeMediaAdd)))
+	mux.Handle("POST /nodes/{id}/media/{mediaID}/move", protected(http.HandlerFunc(e.HandleNodeMediaMove)))
+	mux.Handle("PUT /nodes/{id}/media/{mediaID}", protected(http.HandlerFunc(e.HandleNodeMediaSave)))
+	mux.Handle("DELETE /nodes/{id}/media/{mediaID}", protected(http.HandlerFunc(e.HandleNodeMediaDelete)))
+	mux.Handle("POST /nodes/{id}/audio-narrative", protected(http.HandlerFunc(e.HandleAudioNarrativeUpload)))
+	mux.Handle("DELETE /nodes/{id}/audio-narrative", protected(http.HandlerFunc(e.HandleAudioNarrativeDelete)))
+	mux.Handle("/nodes/{id}/translations/{lang}", protected(http.HandlerFunc(e.HandleNodeTranslations)))
+	mux.Handle("/edges", protected(http.HandlerFunc(e.HandleEdgesList)))
+	mux.Handle("POST /edges", protected(http.HandlerFunc(e.HandleEdgeSave)))
+	mux.Handle("GET /edges/new", protected(http.HandlerFunc(e.HandleEdgeEditor)))
+	mux.Handle("/edges/{index}/edit", protected(http.HandlerFunc(e.HandleEdgeEditor)))
+	mux.Handle("/edges/{index}/move", protected(http.HandlerFunc(e.HandleEdgeMove)))
+	mux.Handle("/edges/{index}", protected(http.HandlerFunc(e.HandleEdgeSave)))
+	mux.Handle("DELETE /edges/{index}", protected(http.HandlerFunc(e.HandleEdgeDelete)))
+	mux.Handle("GET /condition/new", protected(http.HandlerFunc(e.HandleConditionNew)))
+	mux.Handle("GET /condition/remove", protected(http.HandlerFunc(e.HandleConditionRemove)))
+	mux.Handle("GET /condition/type-fields", protected(http.HandlerFunc(e.HandleConditionTypeFields)))
+	mux.Handle("GET /condition/new-option", protected(http.HandlerFunc(e.HandleConditionNewOption)))
+	mux.Handle("GET /condition/new-hint", protected(http.HandlerFunc(e.HandleConditionNewHint)))
 	mux.Handle("/media/upload", protected(http.HandlerFunc(e.HandleMediaUpload)))
 	mux.Handle("/media/validate-url", protected(http.HandlerFunc(e.HandleMediaValidation)))
+	mux.Handle("/media/row", protected(http.HandlerFunc(e.HandleMediaRow)))
 
 	return mux
 }
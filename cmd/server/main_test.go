@@ -0,0 +1,40 @@
+// cmd/server/main_test.go
+package main
+
+import (
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/config"
+	"github.com/ceesaxp/tour-guide-editor/internal/handlers"
+)
+
+func TestSetupRoutes(t *testing.T) {
+	// ServeMux panics on conflicting patterns
+	if setupRoutes(&handlers.EditorHandler{}, &handlers.DashboardHandler{}, &handlers.AuthHandler{}, config.Auth{}, handlers.MediaFiles(nil)) == nil {
+		t.Fatal("setupRoutes returned nil")
+	}
+}
+
+func TestMediaFilesURL(t *testing.T) {
+	tests := []struct {
+		name      string
+		host      string
+		publicURL string
+		want      string
+	}{
+		{"server address", "localhost", "", "http://localhost:8081/media/files"},
+		{"all interfaces", "0.0.0.0", "", "http://localhost:8081/media/files"},
+		{"public URL", "localhost", "https://editor.example.com/media/files", "https://editor.example.com/media/files"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			cfg := &config.Config{Media: config.Media{PublicURL: tt.publicURL}}
+			cfg.Server.Host = tt.host
+			cfg.Server.Port = 8081
+			if got := mediaFilesURL(cfg); got != tt.want {
+				t.Errorf("mediaFilesURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,411 @@
+// internal/binder/binder.go
+
+// Package binder decodes HTML form values into structs.
+//
+// Form keys follow the YAML layout of the tour: nested structs are addressed
+// with dots ("location.lat") and slices with an index ("media_files[3].uri")
+// or, for slices of plain values, by repeating the key ("hints"). Field names
+// come from the `form` struct tag and fall back to the `yaml` tag, so models
+// bind without extra annotations. `form:"-"` excludes a field.
+//
+// A bound struct mirrors its form:
+//
+//   - Strings, numbers, times and encoding.TextUnmarshaler fields are only
+//     set when their key is present. Times are read as entered in date and
+//     datetime-local inputs, or in RFC 3339; an empty value is the zero time.
+//   - Booleans are checkboxes, so a missing key means false.
+//   - Slices hold exactly the rows present in the form. Indexes may be
+//     sparse; rows are kept in index order and empty values are skipped.
+//   - Pointers to structs are nil when no key under their name is present.
+//
+// Slice rows reuse the previous element with the same value in the field
+// tagged `form:",key"`, or at the same position when there is no key field,
+// so data the form does not show (such as translations) survives an edit.
+// Rows whose `form:",required"` fields are empty are dropped as blank.
+package binder
+
+import (
+	"encoding"
+	"fmt"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Errors maps form keys to a description of why their value was rejected.
+type Errors map[string]string
+
+func (e Errors) Error() string {
+	keys := make([]string, 0, len(e))
+	for key := range e {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+
+	msgs := make([]string, len(keys))
+	for i, key := range keys {
+		msgs[i] = key + ": " + e[key]
+	}
+	return strings.Join(msgs, "; ")
+}
+
+// Bind decodes values into the struct dst points to. Values that cannot be
+// parsed leave their field unchanged and are reported in the returned
+// Errors; all other fields are still bound.
+func Bind(values url.Values, dst interface{}) error {
+	return BindPrefix(values, "", dst)
+}
+
+// BindPrefix is like Bind for a struct whose keys are nested under prefix,
+// e.g. "entry_condition".
+func BindPrefix(values url.Values, prefix string, dst interface{}) error {
+	v := reflect.ValueOf(dst)
+	if v.Kind() != reflect.Pointer || v.IsNil() || v.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("binder: destination must be a non-nil pointer to a struct, got %T", dst)
+	}
+
+	errs := Errors{}
+	bindStruct(values, prefix, v.Elem(), errs)
+	if len(errs) > 0 {
+		return errs
+	}
+	return nil
+}
+
+var (
+	textUnmarshalerType = reflect.TypeOf((*encoding.TextUnmarshaler)(nil)).Elem()
+	timeType            = reflect.TypeOf(time.Time{})
+)
+
+// timeLayouts are the formats times are accepted in: those of date and
+// datetime-local inputs, then RFC 3339.
+var timeLayouts = []string{"2006-01-02", "2006-01-02T15:04", "2006-01-02T15:04:05", time.RFC3339}
+
+type fieldInfo struct {
+	name     string
+	key      bool // identifies slice rows across edits
+	required bool // rows with this field empty are blank
+}
+
+func fieldInfoOf(f reflect.StructField) (fieldInfo, bool) {
+	if !f.IsExported() {
+		return fieldInfo{}, false
+	}
+
+	tag, ok := f.Tag.Lookup("form")
+	if tag == "-" {
+		return fieldInfo{}, false
+	}
+	name, opts, _ := strings.Cut(tag, ",")
+	if !ok || name == "" {
+		name, _, _ = strings.Cut(f.Tag.Get("yaml"), ",")
+	}
+	if name == "" || name == "-" {
+		return fieldInfo{}, false
+	}
+
+	info := fieldInfo{name: name}
+	for _, opt := range strings.Split(opts, ",") {
+		switch opt {
+		case "key":
+			info.key = true
+		case "required":
+			info.required = true
+		}
+	}
+	return info, true
+}
+
+func join(prefix, name string) string {
+	if prefix == "" {
+		return name
+	}
+	return prefix + "." + name
+}
+
+func bindStruct(values url.Values, prefix string, v reflect.Value, errs Errors) {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		info, ok := fieldInfoOf(t.Field(i))
+		if !ok {
+			continue
+		}
+		bindValue(values, join(prefix, info.name), v.Field(i), errs)
+	}
+}
+
+func bindValue(values url.Values, key string, v reflect.Value, errs Errors) {
+	if isScalar(v.Type()) {
+		if _, ok := values[key]; ok || v.Kind() == reflect.Bool {
+			if err := setScalar(v, values.Get(key)); err != nil {
+				errs[key] = err.Error()
+			}
+		}
+		return
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		bindStruct(values, key, v, errs)
+	case reflect.Pointer:
+		if v.Type().Elem().Kind() != reflect.Struct {
+			return
+		}
+		if !hasPrefix(values, key) {
+			v.Set(reflect.Zero(v.Type()))
+			return
+		}
+		// Bind into a copy so the previous value is not modified in place
+		elem := reflect.New(v.Type().Elem())
+		if !v.IsNil() {
+			elem.Elem().Set(v.Elem())
+		}
+		bindStruct(values, key, elem.Elem(), errs)
+		v.Set(elem)
+	case reflect.Slice:
+		bindSlice(values, key, v, errs)
+	}
+}
+
+// isScalar reports whether a value of type t is read from a single form
+// value.
+func isScalar(t reflect.Type) bool {
+	if reflect.PointerTo(t).Implements(textUnmarshalerType) {
+		return true
+	}
+	switch t.Kind() {
+	case reflect.String, reflect.Bool,
+		reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return true
+	}
+	return false
+}
+
+func setScalar(v reflect.Value, s string) error {
+	if v.Type() == timeType {
+		return setTime(v, strings.TrimSpace(s))
+	}
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+
+	s = strings.TrimSpace(s)
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		switch strings.ToLower(s) {
+		case "on", "yes":
+			v.SetBool(true)
+		case "", "off", "no":
+			v.SetBool(false)
+		default:
+			b, err := strconv.ParseBool(s)
+			if err != nil {
+				return fmt.Errorf("must be true or false")
+			}
+			v.SetBool(b)
+		}
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		if s == "" {
+			v.SetInt(0)
+			return nil
+		}
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a whole number")
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		if s == "" {
+			v.SetUint(0)
+			return nil
+		}
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a positive whole number")
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		if s == "" {
+			v.SetFloat(0)
+			return nil
+		}
+		f, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return fmt.Errorf("must be a number")
+		}
+		v.SetFloat(f)
+	}
+	return nil
+}
+
+func setTime(v reflect.Value, s string) error {
+	if s == "" {
+		v.Set(reflect.ValueOf(time.Time{}))
+		return nil
+	}
+	for _, layout := range timeLayouts {
+		if t, err := time.Parse(layout, s); err == nil {
+			v.Set(reflect.ValueOf(t))
+			return nil
+		}
+	}
+	return fmt.Errorf("must be a date")
+}
+
+// hasPrefix reports whether values holds key itself or any key nested
+// under it.
+func hasPrefix(values url.Values, key string) bool {
+	for k := range values {
+		if k == key || strings.HasPrefix(k, key+".") || strings.HasPrefix(k, key+"[") {
+			return true
+		}
+	}
+	return false
+}
+
+// indexes returns the distinct indexes used in keys of the form
+// "key[i]..." in ascending order. Malformed indexes are reported in errs.
+func indexes(values url.Values, key string, errs Errors) []int {
+	seen := make(map[int]bool)
+	for k := range values {
+		rest, ok := strings.CutPrefix(k, key+"[")
+		if !ok {
+			continue
+		}
+		index, _, ok := strings.Cut(rest, "]")
+		i, err := strconv.Atoi(index)
+		if !ok || err != nil || i < 0 {
+			errs[k] = "invalid index"
+			continue
+		}
+		seen[i] = true
+	}
+
+	list := make([]int, 0, len(seen))
+	for i := range seen {
+		list = append(list, i)
+	}
+	sort.Ints(list)
+	return list
+}
+
+func bindSlice(values url.Values, key string, v reflect.Value, errs Errors) {
+	elemType := v.Type().Elem()
+	previous := reflect.ValueOf(v.Interface()) // the slice before binding
+	result := reflect.MakeSlice(v.Type(), 0, 0)
+
+	if isScalar(elemType) {
+		// Repeated keys first, then indexed ones
+		raw := append([]string(nil), values[key]...)
+		for _, i := range indexes(values, key, errs) {
+			raw = append(raw, values.Get(fmt.Sprintf("%s[%d]", key, i)))
+		}
+
+		for _, s := range raw {
+			if strings.TrimSpace(s) == "" {
+				continue
+			}
+			elem := reflect.New(elemType).Elem()
+			if n := result.Len(); n < previous.Len() {
+				elem.Set(previous.Index(n))
+			}
+			rowKey := fmt.Sprintf("%s[%d]", key, result.Len())
+			if err := setScalar(elem, s); err != nil {
+				errs[rowKey] = err.Error()
+				continue
+			}
+			result = reflect.Append(result, elem)
+		}
+	} else if elemType.Kind() == reflect.Struct {
+		keyField, required := rowFields(elemType)
+		for _, i := range indexes(values, key, errs) {
+			row := fmt.Sprintf("%s[%d]", key, i)
+			if isBlankRow(values, row, required) {
+				continue
+			}
+
+			elem := reflect.New(elemType).Elem()
+			if prev, ok := previousRow(values, row, previous, keyField, result.Len()); ok {
+				elem.Set(prev)
+			}
+			bindStruct(values, row, elem, errs)
+			result = reflect.Append(result, elem)
+		}
+	} else {
+		return
+	}
+
+	if result.Len() == 0 {
+		v.Set(reflect.Zero(v.Type()))
+		return
+	}
+	v.Set(result)
+}
+
+// rowFields returns the names of the key field and the required fields of
+// a slice element struct.
+func rowFields(t reflect.Type) (key string, required []string) {
+	for i := 0; i < t.NumField(); i++ {
+		info, ok := fieldInfoOf(t.Field(i))
+		if !ok {
+			continue
+		}
+		if info.key {
+			key = info.name
+		}
+		if info.required {
+			required = append(required, info.name)
+		}
+	}
+	return key, required
+}
+
+func isBlankRow(values url.Values, row string, required []string) bool {
+	for _, name := range required {
+		if strings.TrimSpace(values.Get(row+"."+name)) == "" {
+			return true
+		}
+	}
+	return false
+}
+
+// previousRow finds the element of previous that the form row at position
+// n edits: the one with the same key field value, or the one at the same
+// position when the element has no key field.
+func previousRow(values url.Values, row string, previous reflect.Value, keyField string, n int) (reflect.Value, bool) {
+	if keyField == "" {
+		if n < previous.Len() {
+			return previous.Index(n), true
+		}
+		return reflect.Value{}, false
+	}
+
+	id := values.Get(row + "." + keyField)
+	if id == "" {
+		return reflect.Value{}, false
+	}
+	for i := 0; i < previous.Len(); i++ {
+		elem := previous.Index(i)
+		if keyValue(elem, keyField) == id {
+			return elem, true
+		}
+	}
+	return reflect.Value{}, false
+}
+
+func keyValue(v reflect.Value, name string) string {
+	t := v.Type()
+	for i := 0; i < t.NumField(); i++ {
+		if info, ok := fieldInfoOf(t.Field(i)); ok && info.name == name {
+			return fmt.Sprint(v.Field(i).Interface())
+		}
+	}
+	return ""
+}
@@ -0,0 +1,271 @@
+// internal/binder/binder_test.go
+package binder
+
+import (
+	"net/url"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type label struct {
+	Value string
+	Extra string // not in the form; must survive binding
+}
+
+func (l *label) UnmarshalText(data []byte) error {
+	l.Value = string(data)
+	return nil
+}
+
+type file struct {
+	ID    string `yaml:"id" form:"id,key"`
+	URI   string `yaml:"uri" form:"uri,required"`
+	Delay int    `yaml:"send_delay"`
+	Note  label  `yaml:"narrative"`
+}
+
+type quiz struct {
+	Type    string  `yaml:"type"`
+	Strict  bool    `yaml:"strict"`
+	Options []label `yaml:"options"`
+}
+
+type place struct {
+	ID       int    `yaml:"id"`
+	Title    label  `yaml:"title"`
+	Internal string `yaml:"internal" form:"-"`
+	Open     bool   `yaml:"open"`
+	Location struct {
+		Lat float64 `yaml:"lat"`
+		Lon float64 `yaml:"lon"`
+	} `yaml:"location"`
+	Files []file `yaml:"media_files"`
+	Quiz  *quiz  `yaml:"quiz"`
+	Tags  []string
+}
+
+func TestBind(t *testing.T) {
+	previous := func() place {
+		p := place{ID: 1, Title: label{Value: "Gate", Extra: "kept"}, Internal: "secret", Open: true}
+		p.Files = []file{
+			{ID: "a", URI: "http://example.com/a.jpg", Note: label{Value: "A", Extra: "a-extra"}},
+			{ID: "b", URI: "http://example.com/b.jpg", Note: label{Value: "B", Extra: "b-extra"}},
+		}
+		p.Quiz = &quiz{Type: "quiz", Options: []label{{Value: "One", Extra: "one-extra"}}}
+		return p
+	}
+
+	tests := []struct {
+		name   string
+		values url.Values
+		check  func(t *testing.T, p place)
+	}{
+		{
+			name: "scalars and nested structs",
+			values: url.Values{
+				"id":           {"7"},
+				"title":        {"Tower"},
+				"internal":     {"overwritten"},
+				"location.lat": {"45.5"},
+				"location.lon": {" 20.25 "},
+			},
+			check: func(t *testing.T, p place) {
+				if p.ID != 7 || p.Location.Lat != 45.5 || p.Location.Lon != 20.25 {
+					t.Errorf("scalars not bound: %+v", p)
+				}
+				if p.Title != (label{Value: "Tower", Extra: "kept"}) {
+					t.Errorf("text unmarshaler lost its state: %+v", p.Title)
+				}
+				if p.Internal != "secret" {
+					t.Errorf("excluded field was bound: %q", p.Internal)
+				}
+			},
+		},
+		{
+			name:   "missing checkbox is false",
+			values: url.Values{"title": {"Gate"}},
+			check: func(t *testing.T, p place) {
+				if p.Open {
+					t.Error("expected unchecked checkbox to clear the flag")
+				}
+				if p.ID != 1 {
+					t.Errorf("absent field changed: %d", p.ID)
+				}
+			},
+		},
+		{
+			name: "sparse rows keyed by ID",
+			values: url.Values{
+				"media_files[12].id":       {"a"},
+				"media_files[12].uri":      {"http://example.com/a2.jpg"},
+				"media_files[3].id":        {"new"},
+				"media_files[3].uri":       {"http://example.com/new.jpg"},
+				"media_files[3].narrative": {"New"},
+				"media_files[40].id":       {"blank"},
+				"media_files[40].uri":      {""},
+			},
+			check: func(t *testing.T, p place) {
+				want := []file{
+					{ID: "new", URI: "http://example.com/new.jpg", Note: label{Value: "New"}},
+					{ID: "a", URI: "http://example.com/a2.jpg", Note: label{Value: "A", Extra: "a-extra"}},
+				}
+				if !reflect.DeepEqual(p.Files, want) {
+					t.Errorf("Files = %+v, want %+v", p.Files, want)
+				}
+			},
+		},
+		{
+			name:   "all rows removed",
+			values: url.Values{"title": {"Gate"}},
+			check: func(t *testing.T, p place) {
+				if p.Files != nil {
+					t.Errorf("expected no media files, got %+v", p.Files)
+				}
+			},
+		},
+		{
+			name: "repeated values and absent pointers",
+			values: url.Values{
+				"quiz.type":    {"quiz"},
+				"quiz.strict":  {"on"},
+				"quiz.options": {"First", "", "Second"},
+				"Tags":         {"x"},
+			},
+			check: func(t *testing.T, p place) {
+				want := &quiz{Type: "quiz", Strict: true, Options: []label{{Value: "First", Extra: "one-extra"}, {Value: "Second"}}}
+				if !reflect.DeepEqual(p.Quiz, want) {
+					t.Errorf("Quiz = %+v, want %+v", p.Quiz, want)
+				}
+				if p.Tags != nil {
+					t.Errorf("field without form or yaml name was bound: %v", p.Tags)
+				}
+			},
+		},
+		{
+			name:   "removed struct",
+			values: url.Values{"id": {"1"}},
+			check: func(t *testing.T, p place) {
+				if p.Quiz != nil {
+					t.Errorf("expected quiz to be removed, got %+v", p.Quiz)
+				}
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := previous()
+			original := previous()
+			if err := Bind(tt.values, &p); err != nil {
+				t.Fatalf("Bind() error = %v", err)
+			}
+			tt.check(t, p)
+
+			if original.Quiz.Type != "quiz" || original.Files[0].URI != "http://example.com/a.jpg" {
+				t.Error("binding modified the previous value")
+			}
+		})
+	}
+}
+
+func TestBind_Errors(t *testing.T) {
+	p := place{ID: 3, Location: struct {
+		Lat float64 `yaml:"lat"`
+		Lon float64 `yaml:"lon"`
+	}{Lat: 1}}
+
+	values := url.Values{
+		"id":                        {"three"},
+		"title":                     {"Still bound"},
+		"location.lat":              {"north"},
+		"open":                      {"maybe"},
+		"media_files[0].id":         {"a"},
+		"media_files[0].uri":        {"http://example.com/a.jpg"},
+		"media_files[0].send_delay": {"1.5"},
+		"media_files[x].uri":        {"http://example.com/x.jpg"},
+	}
+
+	err := Bind(values, &p)
+	errs, ok := err.(Errors)
+	if !ok {
+		t.Fatalf("expected Errors, got %T: %v", err, err)
+	}
+
+	want := Errors{
+		"id":                        "must be a whole number",
+		"location.lat":              "must be a number",
+		"open":                      "must be true or false",
+		"media_files[0].send_delay": "must be a whole number",
+		"media_files[x].uri":        "invalid index",
+	}
+	if !reflect.DeepEqual(errs, want) {
+		t.Errorf("errors = %v, want %v", errs, want)
+	}
+	if p.ID != 3 || p.Location.Lat != 1 {
+		t.Errorf("fields with invalid input changed: %+v", p)
+	}
+	if p.Title.Value != "Still bound" {
+		t.Errorf("valid fields not bound alongside errors: %+v", p.Title)
+	}
+	if !strings.Contains(err.Error(), "id: must be a whole number") {
+		t.Errorf("unexpected error message %q", err)
+	}
+}
+
+func TestBindPrefix(t *testing.T) {
+	var q quiz
+	values := url.Values{"exit.type": {"q&a"}, "entry.type": {"quiz"}}
+	if err := BindPrefix(values, "exit", &q); err != nil {
+		t.Fatal(err)
+	}
+	if q.Type != "q&a" {
+		t.Errorf("bound wrong prefix: %+v", q)
+	}
+
+	if err := Bind(values, q); err == nil {
+		t.Error("expected error binding into a non-pointer")
+	}
+}
+
+func TestBind_Times(t *testing.T) {
+	type period struct {
+		Start time.Time `yaml:"start_date"`
+		End   time.Time `yaml:"end_date"`
+	}
+	kept := time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC)
+
+	tests := []struct {
+		name    string
+		value   string
+		want    time.Time
+		wantErr bool
+	}{
+		{name: "date input", value: "2024-05-01", want: time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)},
+		{name: "datetime-local input", value: "2024-05-01T09:30", want: time.Date(2024, 5, 1, 9, 30, 0, 0, time.UTC)},
+		{name: "RFC 3339", value: "2024-05-01T09:30:00+02:00", want: time.Date(2024, 5, 1, 7, 30, 0, 0, time.UTC)},
+		{name: "empty", value: "", want: time.Time{}},
+		{name: "malformed", value: "01/05/2024", want: kept, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			p := period{Start: kept, End: kept}
+			err := Bind(url.Values{"start_date": {tt.value}}, &p)
+			if tt.wantErr {
+				if errs, ok := err.(Errors); !ok || errs["start_date"] != "must be a date" {
+					t.Fatalf("expected start_date error, got %v", err)
+				}
+			} else if err != nil {
+				t.Fatal(err)
+			}
+			if !p.Start.Equal(tt.want) {
+				t.Errorf("Start = %v, want %v", p.Start, tt.want)
+			}
+			if !p.End.Equal(kept) {
+				t.Errorf("End without a form value changed to %v", p.End)
+			}
+		})
+	}
+}
@@ -4,6 +4,7 @@ package config
 import (
 	"fmt"
 	"os"
+	"strings"
 
 	"gopkg.in/yaml.v3"
 )
@@ -13,24 +14,92 @@ type Auth struct {
 	TokenTTL  int    `yaml:"token_ttl"`
 }
 
+// S3 configures the buckets media files and, with the s3 storage backend,
+// tours are kept in. Endpoint is set for S3-compatible services such as
+// LocalStack; PublicURL is the base URL uploaded media is served from, see
+// MediaURL.
+type S3 struct {
+	MediaBucket string `yaml:"media_bucket"`
+	TourBucket  string `yaml:"tour_bucket"`
+	Region      string `yaml:"region"`
+	Endpoint    string `yaml:"endpoint"`
+	PublicURL   string `yaml:"public_url"`
+}
+
+// MediaURL returns the base URL of the media bucket: PublicURL if set, such
+// as a CDN in front of the bucket, or else the bucket's own URL at the
+// endpoint or in S3.
+func (s S3) MediaURL() string {
+	switch {
+	case s.PublicURL != "":
+		return strings.TrimSuffix(s.PublicURL, "/")
+	case s.Endpoint != "":
+		return strings.TrimSuffix(s.Endpoint, "/") + "/" + s.MediaBucket
+	case s.Region != "":
+		return fmt.Sprintf("https://%s.s3.%s.amazonaws.com", s.MediaBucket, s.Region)
+	default:
+		return fmt.Sprintf("https://%s.s3.amazonaws.com", s.MediaBucket)
+	}
+}
+
+// Storage backends for Storage.Backend and Media.Backend, which has no
+// memory backend.
+const (
+	StorageFilesystem = "filesystem"
+	StorageMemory     = "memory"
+	StorageS3         = "s3"
+)
+
+// Storage selects where tours are kept. The filesystem backend writes one
+// YAML file per tour to Dir; the S3 backend uses S3.TourBucket.
+type Storage struct {
+	Backend string `yaml:"backend"`
+	Dir     string `yaml:"dir"`
+}
+
+// Editor configures the editing sessions. SessionTTL is the number of
+// minutes a tour stays open without being used; 0 keeps the default.
+type Editor struct {
+	SessionTTL int `yaml:"session_ttl"`
+}
+
+// Media configures media uploads. Backend selects where files are kept:
+// the s3 backend uses S3.MediaBucket, the filesystem backend writes them to
+// Dir and serves them through the app. PublicURL is the base URL of the
+// filesystem backend's files, by default /media/files at Server.Host and
+// Server.Port.
+type Media struct {
+	Backend        string   `yaml:"backend"`
+	Dir            string   `yaml:"dir"`
+	PublicURL      string   `yaml:"public_url"`
+	MaxFileSize    int64    `yaml:"max_file_size"`
+	AllowedFormats []string `yaml:"allowed_formats"`
+	ImageMaxWidth  int      `yaml:"image_max_width"`
+	ImageMaxHeight int      `yaml:"image_max_height"`
+
+	// Audio narratives are transcoded to Ogg/Opus with FFmpeg, at
+	// AudioBitrate kbit/s, within TranscodeTimeout seconds. FFprobe reads
+	// the duration of uploaded audio. If LoudnessTarget is set, in LUFS,
+	// all audio is normalized to it. Zero values keep the defaults of
+	// services.MediaConfig.
+	FFmpeg           string  `yaml:"ffmpeg"`
+	FFprobe          string  `yaml:"ffprobe"`
+	AudioBitrate     int     `yaml:"audio_bitrate"`
+	AudioChannels    int     `yaml:"audio_channels"`
+	LoudnessTarget   float64 `yaml:"loudness_target"`
+	TranscodeTimeout int     `yaml:"transcode_timeout"`
+}
+
 type Config struct {
 	Server struct {
 		Port int    `yaml:"port"`
 		Host string `yaml:"host"`
 	} `yaml:"server"`
-	Auth Auth `yaml:"auth"`
-	S3   struct {
-		MediaBucket string `yaml:"media_bucket"`
-		TourBucket  string `yaml:"tour_bucket"`
-		Region      string `yaml:"region"`
-		Endpoint    string `yaml:"endpoint"`
-	} `yaml:"s3"`
-	Media struct {
-		MaxFileSize    int64    `yaml:"max_file_size"`
-		AllowedFormats []string `yaml:"allowed_formats"`
-		ImageMaxWidth  int      `yaml:"image_max_width"`
-		ImageMaxHeight int      `yaml:"image_max_height"`
-	} `yaml:"media"`
+	Auth    Auth    `yaml:"auth"`
+	S3      S3      `yaml:"s3"`
+	Storage Storage `yaml:"storage"`
+	Editor  Editor  `yaml:"editor"`
+	Media   Media   `yaml:"media"`
 }
 
 func Load(path string) (*Config, error) {
@@ -44,5 +113,19 @@ func Load(path string) (*Config, error) {
 		return nil, fmt.Errorf("parsing config file: %w", err)
 	}
 
+	if cfg.Storage.Backend == "" {
+		cfg.Storage.Backend = StorageFilesystem
+	}
+	if cfg.Storage.Dir == "" {
+		cfg.Storage.Dir = "data/tours"
+	}
+
+	if cfg.Media.Backend == "" {
+		cfg.Media.Backend = StorageS3
+	}
+	if cfg.Media.Dir == "" {
+		cfg.Media.Dir = "data/media"
+	}
+
 	return &cfg, nil
 }
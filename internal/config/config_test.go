@@ -20,7 +20,12 @@ s3:
   tour_bucket: "test-tours"
   region: "us-west-2"
   endpoint: "http://localhost:4566"
+storage:
+  backend: "s3"
+editor:
+  session_ttl: 30
 media:
+  backend: "filesystem"
   max_file_size: 10485760
   allowed_formats:
     - "image/jpeg"
@@ -55,4 +60,34 @@ media:
     if cfg.Auth.SecretKey != "test-key" {
         t.Errorf("Expected secret_key 'test-key', got %s", cfg.Auth.SecretKey)
     }
+    if cfg.Storage.Backend != StorageS3 || cfg.Storage.Dir != "data/tours" {
+        t.Errorf("Expected s3 storage with the default directory, got %+v", cfg.Storage)
+    }
+    if cfg.Editor.SessionTTL != 30 {
+        t.Errorf("Expected session_ttl 30, got %d", cfg.Editor.SessionTTL)
+    }
+    if cfg.Media.Backend != StorageFilesystem || cfg.Media.Dir != "data/media" {
+        t.Errorf("Expected filesystem media with the default directory, got %+v", cfg.Media)
+    }
+}
+
+func TestS3_MediaURL(t *testing.T) {
+	tests := []struct {
+		name string
+		s3   S3
+		want string
+	}{
+		{"public URL", S3{MediaBucket: "media", Endpoint: "http://localhost:4566", PublicURL: "https://cdn.example.com/"}, "https://cdn.example.com"},
+		{"custom endpoint", S3{MediaBucket: "media", Endpoint: "http://localhost:4566"}, "http://localhost:4566/media"},
+		{"region", S3{MediaBucket: "media", Region: "eu-west-1"}, "https://media.s3.eu-west-1.amazonaws.com"},
+		{"no region", S3{MediaBucket: "media"}, "https://media.s3.amazonaws.com"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.s3.MediaURL(); got != tt.want {
+				t.Errorf("MediaURL() = %q, want %q", got, tt.want)
+			}
+		})
+	}
 }
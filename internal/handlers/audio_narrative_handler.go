@@ -0,0 +1,99 @@
+// internal/handlers/audio_narrative_handler.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+)
+
+// HandleAudioNarrativeUpload transcodes an uploaded audio file, or the file
+// at the posted url, and makes it the node's audio narrative.
+func (h *EditorHandler) HandleAudioNarrativeUpload(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+	if !h.nodeExists(tour, nodeID) {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+
+	var processed *services.ProcessedMedia
+	file, header, err := r.FormFile("file")
+	switch {
+	case err == nil:
+		defer file.Close()
+		processed, err = h.mediaService.ProcessAudioNarrative(file, header)
+	case r.FormValue("url") != "":
+		processed, err = h.mediaService.ProcessAudioNarrativeURL(r.FormValue("url"))
+	default:
+		http.Error(w, "Upload an audio file or enter a URL", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tourService.SetAudioNarrative(r.Context(), tour, nodeID, processed.URL, processed.Duration); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	h.renderAudioNarrative(w, tour, nodeID)
+}
+
+// HandleAudioNarrativeDelete removes the node's audio narrative. The file
+// stays stored, as other nodes or revisions may link it.
+func (h *EditorHandler) HandleAudioNarrativeDelete(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+	if err := h.tourService.SetAudioNarrative(r.Context(), tour, nodeID, "", 0); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		}
+		return
+	}
+
+	h.renderAudioNarrative(w, tour, nodeID)
+}
+
+func (h *EditorHandler) nodeExists(tour *models.Tour, nodeID int) bool {
+	var exists bool
+	h.tourService.ViewTour(tour, func(tour *models.Tour) {
+		exists = tour.GetNode(nodeID) != nil
+	})
+	return exists
+}
+
+// renderAudioNarrative re-renders the audio narrative of the tour's node
+// nodeID after a change to it.
+func (h *EditorHandler) renderAudioNarrative(w http.ResponseWriter, tour *models.Tour, nodeID int) {
+	var data nodeMediaData
+	h.tourService.ViewTour(tour, func(tour *models.Tour) {
+		w.Header().Set("ETag", etag(tour.Record.Revision))
+		if node := tour.GetNode(nodeID); node != nil {
+			data = newNodeMediaData(node)
+		}
+	})
+
+	w.Header().Set("HX-Trigger", "tourChanged")
+	if err := h.templates.ExecuteTemplate(w, "node-audio-narrative", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,92 @@
+// internal/handlers/audio_narrative_handler_test.go
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/mocks"
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+	"github.com/ceesaxp/tour-guide-editor/internal/storage"
+)
+
+func TestEditorHandler_AudioNarrative(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("fake ffmpeg is a shell script")
+	}
+	ffmpeg := filepath.Join(t.TempDir(), "ffmpeg")
+	script := "#!/bin/sh\nfor arg; do out=\"$arg\"; done; printf 'OggS' > \"$out\"\n"
+	if err := os.WriteFile(ffmpeg, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	ffprobe := filepath.Join(t.TempDir(), "ffprobe")
+	script = "#!/bin/sh\necho '{\"streams\": [{\"codec_name\": \"opus\", \"sample_rate\": \"48000\"}], \"format\": {\"duration\": \"61.5\"}}'\n"
+	if err := os.WriteFile(ffprobe, []byte(script), 0o755); err != nil {
+		t.Fatal(err)
+	}
+
+	handler, tourService, ctx := newTestEditor(t)
+	handler.mediaService = services.NewMediaService(services.MediaConfig{
+		MaxFileSize:    1024 * 1024,
+		AllowedFormats: []string{"image/", "audio/"},
+		FFmpeg:         ffmpeg,
+		FFprobe:        ffprobe,
+	}, storage.NewS3Store(mocks.NewFakeS3(), "test-bucket", "https://test-bucket.s3.amazonaws.com"))
+	tour := tourService.GetCurrentTour(ctx)
+
+	upload := func(filename string, data []byte) *httptest.ResponseRecorder {
+		var body bytes.Buffer
+		writer := multipart.NewWriter(&body)
+		part, _ := writer.CreateFormFile("file", filename)
+		part.Write(data)
+		writer.Close()
+
+		req := httptest.NewRequest("POST", "/nodes/1/audio-narrative", &body)
+		req.Header.Set("Content-Type", writer.FormDataContentType())
+		req.SetPathValue("id", "1")
+		rr := httptest.NewRecorder()
+		handler.HandleAudioNarrativeUpload(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	rr := upload("story.mp3", append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), make([]byte, 64)...))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	narrative := tour.GetNode(1).AudioNarrative
+	if !strings.HasPrefix(narrative, "https://test-bucket.s3.amazonaws.com/sha256/") || !strings.HasSuffix(narrative, ".ogg") {
+		t.Errorf("expected the transcoded narrative linked, got %q", narrative)
+	}
+	if !strings.Contains(rr.Body.String(), narrative) {
+		t.Errorf("expected the narrative rendered, got %s", rr.Body)
+	}
+	if duration := tour.GetNode(1).AudioNarrativeDuration; duration != 61.5 {
+		t.Errorf("expected the probed duration stored, got %v", duration)
+	}
+	if !strings.Contains(rr.Body.String(), ">1:02<") {
+		t.Errorf("expected the duration rendered, got %s", rr.Body)
+	}
+
+	// Images are not narrated
+	if rr := upload("photo.jpg", createTestImage(t)); rr.Code != http.StatusBadRequest {
+		t.Errorf("expected an image to be rejected, got %v", rr.Code)
+	}
+
+	req := httptest.NewRequest("DELETE", "/nodes/1/audio-narrative", nil)
+	req.SetPathValue("id", "1")
+	rr = httptest.NewRecorder()
+	handler.HandleAudioNarrativeDelete(rr, req.WithContext(ctx))
+	if rr.Code != http.StatusOK {
+		t.Fatalf("delete returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if node := tour.GetNode(1); node.AudioNarrative != "" || node.AudioNarrativeDuration != 0 {
+		t.Errorf("expected the narrative removed, got %q of %vs", node.AudioNarrative, node.AudioNarrativeDuration)
+	}
+}
@@ -0,0 +1,113 @@
+// internal/handlers/condition_handler.go
+package handlers
+
+import (
+	"log"
+	"net/http"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/binder"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+// conditionEditorData renders a condition whose form fields are named
+// "<Prefix>.<field>", e.g. "entry_condition.question". A nil Condition
+// renders a button for adding one.
+type conditionEditorData struct {
+	Prefix    string
+	Condition *models.Condition
+}
+
+// Item returns the data for one option or hint input of the condition.
+func (d conditionEditorData) Item(text models.Text) conditionItemData {
+	return conditionItemData{Prefix: d.Prefix, Value: text.Value}
+}
+
+type conditionItemData struct {
+	Prefix string
+	Value  string
+}
+
+// conditionPrefixes are the form prefixes conditions are edited under.
+var conditionPrefixes = map[string]bool{
+	"entry_condition": true,
+	"exit_condition":  true,
+	"condition":       true, // edges
+}
+
+func conditionPrefix(w http.ResponseWriter, r *http.Request) (string, bool) {
+	prefix := r.FormValue("prefix")
+	if !conditionPrefixes[prefix] {
+		http.Error(w, "Invalid condition prefix", http.StatusBadRequest)
+		return "", false
+	}
+	return prefix, true
+}
+
+// HandleConditionNew returns the editor for a new Q&A condition.
+func (h *EditorHandler) HandleConditionNew(w http.ResponseWriter, r *http.Request) {
+	prefix, ok := conditionPrefix(w, r)
+	if !ok {
+		return
+	}
+
+	data := conditionEditorData{Prefix: prefix, Condition: &models.Condition{Type: "q&a"}}
+	h.renderCondition(w, "condition-slot", data)
+}
+
+// HandleConditionRemove returns an empty condition slot and asks the
+// surrounding form to save, which drops the condition.
+func (h *EditorHandler) HandleConditionRemove(w http.ResponseWriter, r *http.Request) {
+	prefix, ok := conditionPrefix(w, r)
+	if !ok {
+		return
+	}
+
+	w.Header().Set("HX-Trigger", "conditionChanged")
+	h.renderCondition(w, "condition-slot", conditionEditorData{Prefix: prefix})
+}
+
+// HandleConditionTypeFields re-renders a condition's fields for the type
+// selected in the request, keeping the values entered so far.
+func (h *EditorHandler) HandleConditionTypeFields(w http.ResponseWriter, r *http.Request) {
+	prefix, ok := conditionPrefix(w, r)
+	if !ok {
+		return
+	}
+
+	condition := &models.Condition{}
+	if err := binder.BindPrefix(r.Form, prefix, condition); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if condition.Type == "" {
+		http.Error(w, "Missing condition type", http.StatusBadRequest)
+		return
+	}
+
+	h.renderCondition(w, "condition-fields", conditionEditorData{Prefix: prefix, Condition: condition})
+}
+
+func (h *EditorHandler) HandleConditionNewOption(w http.ResponseWriter, r *http.Request) {
+	prefix, ok := conditionPrefix(w, r)
+	if !ok {
+		return
+	}
+
+	h.renderCondition(w, "condition-option", conditionItemData{Prefix: prefix})
+}
+
+func (h *EditorHandler) HandleConditionNewHint(w http.ResponseWriter, r *http.Request) {
+	prefix, ok := conditionPrefix(w, r)
+	if !ok {
+		return
+	}
+
+	h.renderCondition(w, "condition-hint", conditionItemData{Prefix: prefix})
+}
+
+func (h *EditorHandler) renderCondition(w http.ResponseWriter, name string, data interface{}) {
+	if err := h.templates.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,165 @@
+// internal/handlers/condition_handler_test.go
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestEditorHandler_ConditionTypeFields(t *testing.T) {
+	handler, _, ctx := newTestEditor(t)
+
+	tests := []struct {
+		name          string
+		conditionType string
+		want          []string
+		notWant       []string
+	}{
+		{
+			name:          "quiz shows options",
+			conditionType: "quiz",
+			want:          []string{`name="exit_condition.question"`, `value="Which year?"`, `name="exit_condition.options" value="1850"`, "/condition/new-option?prefix=exit_condition"},
+			notWant:       []string{"exit_condition.media_link"},
+		},
+		{
+			name:          "puzzle shows media link",
+			conditionType: "puzzle",
+			want:          []string{`name="exit_condition.question"`, `value="Which year?"`, `name="exit_condition.media_link"`},
+			notWant:       []string{"exit_condition.options"},
+		},
+		{
+			name:          "q&a has neither",
+			conditionType: "q&a",
+			want:          []string{`name="exit_condition.correct_answer"`, `value="1850"`},
+			notWant:       []string{"exit_condition.options", "exit_condition.media_link"},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			query := url.Values{
+				"prefix":                        {"exit_condition"},
+				"exit_condition.type":           {tt.conditionType},
+				"exit_condition.question":       {"Which year?"},
+				"exit_condition.correct_answer": {"1850"},
+				"exit_condition.options":        {"1850", "", "1900"},
+			}
+			req := httptest.NewRequest("GET", "/condition/type-fields?"+query.Encode(), nil)
+			rr := httptest.NewRecorder()
+			handler.HandleConditionTypeFields(rr, req.WithContext(ctx))
+
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+			}
+			body := rr.Body.String()
+			for _, want := range tt.want {
+				if !strings.Contains(body, want) {
+					t.Errorf("expected %q in response:\n%s", want, body)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(body, notWant) {
+					t.Errorf("unexpected %q in response:\n%s", notWant, body)
+				}
+			}
+		})
+	}
+}
+
+func TestEditorHandler_ConditionItems(t *testing.T) {
+	handler, _, ctx := newTestEditor(t)
+
+	tests := []struct {
+		path    string
+		handle  http.HandlerFunc
+		want    string
+		wantErr bool
+	}{
+		{path: "/condition/new-option?prefix=entry_condition", handle: handler.HandleConditionNewOption, want: `name="entry_condition.options"`},
+		{path: "/condition/new-hint?prefix=condition", handle: handler.HandleConditionNewHint, want: `name="condition.hints"`},
+		{path: "/condition/new?prefix=exit_condition", handle: handler.HandleConditionNew, want: `name="exit_condition.type"`},
+		{path: "/condition/remove?prefix=exit_condition", handle: handler.HandleConditionRemove, want: "/condition/new?prefix=exit_condition"},
+		{path: "/condition/new-hint?prefix=bogus", handle: handler.HandleConditionNewHint, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.path, func(t *testing.T) {
+			req := httptest.NewRequest("GET", tt.path, nil)
+			rr := httptest.NewRecorder()
+			tt.handle(rr, req.WithContext(ctx))
+
+			if tt.wantErr {
+				if rr.Code != http.StatusBadRequest {
+					t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+				}
+				return
+			}
+			if rr.Code != http.StatusOK {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+			}
+			if !strings.Contains(rr.Body.String(), tt.want) {
+				t.Errorf("expected %q in response:\n%s", tt.want, rr.Body)
+			}
+		})
+	}
+}
+
+func TestEditorHandler_NodeSaveConditions(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+
+	save := func(form url.Values) {
+		t.Helper()
+		form.Set("id", "1")
+		form.Set("short_description", "Gate")
+		form.Set("narrative", "First")
+		form.Set("start", "on")
+		form.Set("location.lat", "45.0")
+		form.Set("location.lon", "20.0")
+		req := httptest.NewRequest("PUT", "/nodes/1", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("id", "1")
+		rr := httptest.NewRecorder()
+		handler.HandleNodeSave(rr, req.WithContext(ctx))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("save returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+		}
+	}
+
+	save(url.Values{
+		"entry_condition.type":           {"quiz"},
+		"entry_condition.question":       {"Which gate?"},
+		"entry_condition.correct_answer": {"North"},
+		"entry_condition.options":        {"North", "South"},
+		"exit_condition.type":            {"q&a"},
+		"exit_condition.question":        {"How tall?"},
+		"exit_condition.correct_answer":  {"30m"},
+		"exit_condition.hints":           {"Count the floors"},
+	})
+
+	node := tourService.GetCurrentTour(ctx).GetNode(1)
+	if node.EntryCondition == nil || node.EntryCondition.Question.String() != "Which gate?" || len(node.EntryCondition.Options) != 2 {
+		t.Fatalf("entry condition not saved: %+v", node.EntryCondition)
+	}
+	if node.ExitCondition == nil || node.ExitCondition.Question.String() != "How tall?" || len(node.ExitCondition.Options) != 0 || len(node.ExitCondition.Hints) != 1 {
+		t.Fatalf("exit condition not saved independently: %+v", node.ExitCondition)
+	}
+
+	// Removing the exit condition leaves the entry condition alone
+	save(url.Values{
+		"entry_condition.type":           {"quiz"},
+		"entry_condition.question":       {"Which gate?"},
+		"entry_condition.correct_answer": {"North"},
+		"entry_condition.options":        {"North", "South"},
+	})
+
+	node = tourService.GetCurrentTour(ctx).GetNode(1)
+	if node.ExitCondition != nil {
+		t.Errorf("expected exit condition to be removed, got %+v", node.ExitCondition)
+	}
+	if node.EntryCondition == nil || node.EntryCondition.Type != "quiz" {
+		t.Errorf("entry condition changed: %+v", node.EntryCondition)
+	}
+}
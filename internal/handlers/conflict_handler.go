@@ -0,0 +1,90 @@
+// internal/handlers/conflict_handler.go
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+)
+
+// IfMatch makes changes requested with an If-Match header apply only to the
+// revision of the tour it names, see services.WithRevision. The editor page
+// sends the ETag of the revision it shows, so a change made on top of an
+// outdated page is answered with a conflict. "*" matches any revision.
+func IfMatch(next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		value := r.Header.Get("If-Match")
+		if value == "" || r.Method == http.MethodGet || r.Method == http.MethodHead {
+			next.ServeHTTP(w, r)
+			return
+		}
+
+		revision, err := parseETag(value)
+		if err != nil {
+			http.Error(w, "Invalid If-Match header", http.StatusBadRequest)
+			return
+		}
+		next.ServeHTTP(w, r.WithContext(services.WithRevision(r.Context(), revision)))
+	})
+}
+
+// etag returns the entity tag of a tour revision.
+func etag(revision int) string {
+	return strconv.Quote(strconv.Itoa(revision))
+}
+
+// parseETag reads the revision of an entity tag written by etag.
+func parseETag(value string) (int, error) {
+	if value = strings.TrimSpace(value); value == "*" {
+		return services.AnyRevision, nil
+	}
+	unquoted, err := strconv.Unquote(strings.TrimPrefix(value, "W/"))
+	if err != nil {
+		return 0, err
+	}
+	revision, err := strconv.Atoi(unquoted)
+	if err != nil || revision < 0 {
+		return 0, errors.New("invalid revision")
+	}
+	return revision, nil
+}
+
+// setRevision reports the revision a change saved the tour as.
+func (h *EditorHandler) setRevision(w http.ResponseWriter, tour *models.Tour) {
+	h.tourService.ViewTour(tour, func(tour *models.Tour) {
+		w.Header().Set("ETag", etag(tour.Record.Revision))
+	})
+}
+
+type conflictData struct {
+	Revision int
+	ETag     string
+	Diff     []services.DiffLine
+}
+
+// renderConflict responds to a change made on top of an outdated revision
+// with the difference it would make to the current one, letting the author
+// save it anyway or discard it. It reports false if err is not a conflict,
+// leaving the response to the caller.
+func (h *EditorHandler) renderConflict(w http.ResponseWriter, err error) bool {
+	var conflict *services.ConflictError
+	if !errors.As(err, &conflict) {
+		return false
+	}
+
+	data := conflictData{Revision: conflict.Revision, ETag: etag(conflict.Revision), Diff: conflict.Diff}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Header().Set("ETag", data.ETag)
+	w.Header().Set("HX-Retarget", "#conflict")
+	w.Header().Set("HX-Reswap", "innerHTML")
+	w.WriteHeader(http.StatusConflict)
+	if err := h.templates.ExecuteTemplate(w, "conflict-report", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+	return true
+}
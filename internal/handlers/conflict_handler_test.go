@@ -0,0 +1,192 @@
+// internal/handlers/conflict_handler_test.go
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+)
+
+func TestParseETag(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    int
+		wantErr bool
+	}{
+		{value: `"3"`, want: 3},
+		{value: `W/"3"`, want: 3},
+		{value: ` "12" `, want: 12},
+		{value: `*`, want: services.AnyRevision},
+		{value: `3`, wantErr: true},
+		{value: `"three"`, wantErr: true},
+		{value: `"-2"`, wantErr: true},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.value, func(t *testing.T) {
+			got, err := parseETag(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("parseETag() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if !tt.wantErr && got != tt.want {
+				t.Errorf("parseETag() = %d, want %d", got, tt.want)
+			}
+			if !tt.wantErr && tt.want >= 0 {
+				if back, _ := parseETag(etag(got)); back != got {
+					t.Errorf("etag(%d) does not parse back", got)
+				}
+			}
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	called := false
+	handler := IfMatch(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		called = true
+	}))
+
+	req := httptest.NewRequest("PUT", "/nodes/1", nil)
+	req.Header.Set("If-Match", "bogus")
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if rr.Code != http.StatusBadRequest || called {
+		t.Errorf("expected an invalid header to be rejected, got %v", rr.Code)
+	}
+
+	req = httptest.NewRequest("GET", "/nodes/1/edit", nil)
+	req.Header.Set("If-Match", "bogus")
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req)
+	if !called {
+		t.Error("expected GET requests to pass through")
+	}
+}
+
+func TestEditorHandler_RevisionConflict(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	tour := tourService.GetCurrentTour(ctx)
+	save := IfMatch(http.HandlerFunc(handler.HandleNodeSave))
+
+	send := func(ifMatch, narrative string) *httptest.ResponseRecorder {
+		form := url.Values{
+			"id":                {"2"},
+			"short_description": {"Tower"},
+			"narrative":         {narrative},
+			"finish":            {"on"},
+			"location.lat":      {"45.1"},
+			"location.lon":      {"20.1"},
+		}
+		req := httptest.NewRequest("PUT", "/nodes/2", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.Header.Set("If-Match", ifMatch)
+		req.SetPathValue("id", "2")
+		rr := httptest.NewRecorder()
+		save.ServeHTTP(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	rr := send(`"0"`, "Climb the stairs")
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if rr.Header().Get("ETag") != `"1"` {
+		t.Errorf("expected ETag \"1\", got %q", rr.Header().Get("ETag"))
+	}
+
+	// The page still shows revision 0
+	rr = send(`"0"`, "Take the lift")
+	if rr.Code != http.StatusConflict {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusConflict, rr.Body)
+	}
+	if rr.Header().Get("ETag") != `"1"` || rr.Header().Get("HX-Retarget") != "#conflict" {
+		t.Errorf("unexpected conflict headers: %v", rr.Header())
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, "diff-removed") || !strings.Contains(body, "Take the lift") {
+		t.Errorf("expected the conflict to show the change, got %s", body)
+	}
+	if got := tour.GetNode(2).Narrative.String(); got != "Climb the stairs" {
+		t.Errorf("conflicting change was saved: %q", got)
+	}
+
+	// Saving it on top of the current revision
+	rr = send(`"1"`, "Take the lift")
+	if rr.Code != http.StatusOK || rr.Header().Get("ETag") != `"2"` {
+		t.Fatalf("expected the change to be saved as revision 2, got %v %q", rr.Code, rr.Header().Get("ETag"))
+	}
+}
+
+func TestEditorHandler_ConcurrentNodeSaves(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+
+	const saves = 10
+	var wg, readers sync.WaitGroup
+	done := make(chan struct{})
+	for i := 0; i < saves; i++ {
+		wg.Add(1)
+		go func(id string) {
+			defer wg.Done()
+			form := url.Values{
+				"id":                {id},
+				"short_description": {"Stop " + id},
+				"narrative":         {"Another stop"},
+				"location.lat":      {"45.2"},
+				"location.lon":      {"20.2"},
+			}
+			req := httptest.NewRequest("PUT", "/nodes/"+id, strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.SetPathValue("id", id)
+			rr := httptest.NewRecorder()
+			handler.HandleNodeSave(rr, req.WithContext(ctx))
+			if rr.Code != http.StatusOK {
+				t.Errorf("saving node %s: got %v: %s", id, rr.Code, rr.Body)
+			}
+		}(strconv.Itoa(10 + i))
+	}
+
+	// Reads render the open tour while the saves replace it
+	reads := map[string]http.HandlerFunc{
+		"/nodes":    handler.HandleNodesList,
+		"/edges":    handler.HandleEdgesList,
+		"/edges/0":  handler.HandleEdgeEditor,
+		"/preview":  handler.HandleTourPreview,
+		"/export":   handler.HandleTourExport,
+		"/validate": handler.HandleTourValidate,
+		"/":         handler.ServeHTTP,
+	}
+	for path, read := range reads {
+		readers.Add(1)
+		go func(path string, read http.HandlerFunc) {
+			defer readers.Done()
+			for {
+				select {
+				case <-done:
+					return
+				default:
+				}
+				req := httptest.NewRequest("GET", path, nil)
+				req.SetPathValue("index", "0")
+				rr := httptest.NewRecorder()
+				read(rr, req.WithContext(ctx))
+				if rr.Code >= http.StatusInternalServerError {
+					t.Errorf("reading %s: got %v: %s", path, rr.Code, rr.Body)
+				}
+			}
+		}(path, read)
+	}
+	wg.Wait()
+	close(done)
+	readers.Wait()
+
+	tour := tourService.GetCurrentTour(ctx)
+	if len(tour.Nodes) != 2+saves || tour.Record.Revision != saves {
+		t.Errorf("expected %d nodes at revision %d, got %d at %d", 2+saves, saves, len(tour.Nodes), tour.Record.Revision)
+	}
+}
@@ -0,0 +1,145 @@
+// internal/handlers/dashboard_handler.go
+package handlers
+
+import (
+	"errors"
+	"html/template"
+	"log"
+	"net/http"
+	"path/filepath"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/middleware"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+)
+
+// DashboardHandler lists the user's tours and creates, opens, duplicates
+// and archives them.
+type DashboardHandler struct {
+	templates   *template.Template
+	tourService *services.TourService
+}
+
+type dashboardData struct {
+	Title    string
+	Tours    []services.TourSummary
+	Archived bool // listing the archived tours
+}
+
+func NewDashboardHandler(templateDir string, tourService *services.TourService) *DashboardHandler {
+	templates, err := template.ParseFiles(
+		filepath.Join(templateDir, "layout.html"),
+		filepath.Join(templateDir, "dashboard", "index.html"),
+	)
+	if err != nil {
+		log.Printf("ERR: error parsing templates: %v", err)
+		return nil
+	}
+
+	return &DashboardHandler{
+		templates:   templates,
+		tourService: tourService,
+	}
+}
+
+// userID returns the authenticated user, or "" without authentication.
+func userID(r *http.Request) string {
+	return middleware.UserID(r.Context())
+}
+
+// ServeHTTP renders the dashboard, listing the archived tours with
+// ?archived=1.
+func (h *DashboardHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	archived := r.URL.Query().Get("archived") != ""
+	tours, err := h.tourService.ListTours(r.Context(), userID(r), archived)
+	if err != nil {
+		log.Printf("ERR: error listing tours: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := dashboardData{Title: "Tours", Tours: tours, Archived: archived}
+	if err := h.templates.ExecuteTemplate(w, "layout.html", data); err != nil {
+		log.Printf("ERR: error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleTourCreate creates an empty tour and opens it in the editor.
+func (h *DashboardHandler) HandleTourCreate(w http.ResponseWriter, r *http.Request) {
+	if _, err := h.tourService.CreateTour(r.Context(), userID(r)); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// HandleTourOpen makes the tour {id} the one being edited.
+func (h *DashboardHandler) HandleTourOpen(w http.ResponseWriter, r *http.Request) {
+	_, err := h.tourService.OpenTour(r.Context(), userID(r), r.PathValue("id"))
+	if errors.Is(err, repository.ErrNotFound) {
+		http.Error(w, "Tour not found", http.StatusNotFound)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	http.Redirect(w, r, "/", http.StatusSeeOther)
+}
+
+// HandleTourDuplicate copies the tour {id} and re-renders the tour list.
+func (h *DashboardHandler) HandleTourDuplicate(w http.ResponseWriter, r *http.Request) {
+	_, err := h.tourService.DuplicateTour(r.Context(), userID(r), r.PathValue("id"))
+	if !h.checkTourError(w, err) {
+		return
+	}
+	h.renderTourList(w, r, false)
+}
+
+// HandleTourArchive archives the tour {id} and re-renders the list of
+// active tours.
+func (h *DashboardHandler) HandleTourArchive(w http.ResponseWriter, r *http.Request) {
+	err := h.tourService.SetArchived(r.Context(), userID(r), r.PathValue("id"), true)
+	if !h.checkTourError(w, err) {
+		return
+	}
+	h.renderTourList(w, r, false)
+}
+
+// HandleTourRestore restores the archived tour {id} and re-renders the
+// list of archived tours.
+func (h *DashboardHandler) HandleTourRestore(w http.ResponseWriter, r *http.Request) {
+	err := h.tourService.SetArchived(r.Context(), userID(r), r.PathValue("id"), false)
+	if !h.checkTourError(w, err) {
+		return
+	}
+	h.renderTourList(w, r, true)
+}
+
+// checkTourError responds to err and reports whether there was none.
+func (h *DashboardHandler) checkTourError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, repository.ErrNotFound):
+		http.Error(w, "Tour not found", http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	return false
+}
+
+func (h *DashboardHandler) renderTourList(w http.ResponseWriter, r *http.Request, archived bool) {
+	tours, err := h.tourService.ListTours(r.Context(), userID(r), archived)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := dashboardData{Tours: tours, Archived: archived}
+	if err := h.templates.ExecuteTemplate(w, "tour-list", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,83 @@
+// internal/handlers/dashboard_handler_test.go
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestDashboardHandler(t *testing.T) {
+	_, tourService, ctx := newTestEditor(t)
+	if err := tourService.SaveTour(ctx, tourService.GetCurrentTour(ctx)); err != nil {
+		t.Fatal(err)
+	}
+
+	handler := NewDashboardHandler("../../templates", tourService)
+	if handler == nil {
+		t.Fatal("Failed to create dashboard handler")
+	}
+
+	// List
+	req := httptest.NewRequest("GET", "/tours", nil)
+	rr := httptest.NewRecorder()
+	handler.ServeHTTP(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("dashboard returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "Test Tour") || !strings.Contains(body, "/tours/test_tour/open") {
+		t.Errorf("expected the tour to be listed, got %s", body)
+	}
+
+	// Duplicate
+	req = httptest.NewRequest("POST", "/tours/test_tour/duplicate", nil)
+	req.SetPathValue("id", "test_tour")
+	rr = httptest.NewRecorder()
+	handler.HandleTourDuplicate(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "test_tour-copy") {
+		t.Fatalf("expected the copy in the tour list, got %v: %s", rr.Code, rr.Body)
+	}
+
+	// Archive
+	req = httptest.NewRequest("POST", "/tours/test_tour-copy/archive", nil)
+	req.SetPathValue("id", "test_tour-copy")
+	rr = httptest.NewRecorder()
+	handler.HandleTourArchive(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK || strings.Contains(rr.Body.String(), "test_tour-copy") {
+		t.Errorf("expected the archived tour to leave the list, got %v: %s", rr.Code, rr.Body)
+	}
+
+	// Create and open
+	req = httptest.NewRequest("POST", "/tours", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleTourCreate(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusSeeOther || rr.Header().Get("Location") != "/" {
+		t.Fatalf("expected a redirect to the editor, got %v %q", rr.Code, rr.Header().Get("Location"))
+	}
+	if current := tourService.GetCurrentTour(ctx); current.ID == "test_tour" || current.Record.Owner != "admin" {
+		t.Errorf("expected the new tour to be open, got %s owned by %q", current.ID, current.Record.Owner)
+	}
+
+	req = httptest.NewRequest("POST", "/tours/test_tour/open", nil)
+	req.SetPathValue("id", "test_tour")
+	rr = httptest.NewRecorder()
+	handler.HandleTourOpen(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusSeeOther || tourService.GetCurrentTour(ctx).ID != "test_tour" {
+		t.Errorf("expected test_tour to be opened, got %v", rr.Code)
+	}
+
+	req = httptest.NewRequest("POST", "/tours/missing/open", nil)
+	req.SetPathValue("id", "missing")
+	rr = httptest.NewRecorder()
+	handler.HandleTourOpen(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusNotFound {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusNotFound)
+	}
+}
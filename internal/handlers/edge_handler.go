@@ -0,0 +1,256 @@
+// internal/handlers/edge_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/binder"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+type edgeEditorData struct {
+	Tour      *models.Tour
+	Index     int // -1 for an edge that has not been saved yet
+	Edge      *models.Edge
+	Condition conditionEditorData
+	MediaRows []mediaRowData
+}
+
+type nodeEdgesData struct {
+	Tour     *models.Tour
+	NodeID   int
+	Outgoing []models.IndexedEdge
+	Incoming []models.IndexedEdge
+}
+
+func (h *EditorHandler) HandleEdgesList(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	if node := r.URL.Query().Get("node"); node != "" {
+		nodeID, err := strconv.Atoi(node)
+		if err != nil {
+			http.Error(w, "Invalid node ID", http.StatusBadRequest)
+			return
+		}
+
+		view := h.tourService.SnapshotTour(tour)
+		data := nodeEdgesData{
+			Tour:     view,
+			NodeID:   nodeID,
+			Outgoing: view.OutgoingEdges(nodeID),
+			Incoming: view.IncomingEdges(nodeID),
+		}
+		if err := h.templates.ExecuteTemplate(w, "node-edges", data); err != nil {
+			log.Printf("Error executing template: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "edges-list", h.tourService.SnapshotTour(tour)); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+func (h *EditorHandler) HandleEdgeEditor(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	view := h.tourService.SnapshotTour(tour)
+	index := -1
+	var edge *models.Edge
+	if r.PathValue("index") != "" {
+		var err error
+		if index, err = strconv.Atoi(r.PathValue("index")); err != nil {
+			http.Error(w, "Invalid edge index", http.StatusBadRequest)
+			return
+		}
+		if edge = view.GetEdge(index); edge == nil {
+			http.Error(w, "Edge not found", http.StatusNotFound)
+			return
+		}
+	} else {
+		from, _ := strconv.Atoi(r.URL.Query().Get("from"))
+		edge = models.NewEdge(from, 0)
+	}
+
+	h.renderEdgeEditor(w, view, index, edge)
+}
+
+func (h *EditorHandler) HandleEdgeSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost && r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	// POST creates a new edge, PUT updates the edge at the given index
+	index := -1
+	edge := models.NewEdge(0, 0)
+	if r.Method == http.MethodPut {
+		var err error
+		if index, err = strconv.Atoi(r.PathValue("index")); err != nil {
+			http.Error(w, "Invalid edge index", http.StatusBadRequest)
+			return
+		}
+		var existing *models.Edge
+		h.tourService.ViewTour(tour, func(tour *models.Tour) {
+			if found := tour.GetEdge(index); found != nil {
+				copied := *found
+				existing = &copied
+			}
+		})
+		if existing == nil {
+			http.Error(w, "Edge not found", http.StatusNotFound)
+			return
+		}
+		edge = existing
+	}
+
+	if err := h.updateEdgeFromForm(edge, r); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tourService.SaveEdge(r.Context(), tour, index, edge); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	h.setRevision(w, tour)
+	w.Header().Set("HX-Trigger", "edgeListChanged")
+
+	// A freshly created edge is reopened in the editor so further changes
+	// are saved in place rather than appended again
+	if index < 0 {
+		view := h.tourService.SnapshotTour(tour)
+		index = len(view.Edges) - 1
+		h.renderEdgeEditor(w, view, index, view.GetEdge(index))
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Edge saved successfully",
+		"type":    "success",
+	})
+}
+
+func (h *EditorHandler) HandleEdgeDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		http.Error(w, "Invalid edge index", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tourService.DeleteEdge(r.Context(), tour, index); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	h.setRevision(w, tour)
+	w.Header().Set("HX-Trigger", "edgeListChanged")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *EditorHandler) HandleEdgeMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	index, err := strconv.Atoi(r.PathValue("index"))
+	if err != nil {
+		http.Error(w, "Invalid edge index", http.StatusBadRequest)
+		return
+	}
+
+	var offset int
+	switch r.FormValue("direction") {
+	case "up":
+		offset = -1
+	case "down":
+		offset = 1
+	default:
+		http.Error(w, "Invalid direction", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tourService.MoveEdge(r.Context(), tour, index, offset); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	h.setRevision(w, tour)
+	w.Header().Set("HX-Trigger", "edgeListChanged")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *EditorHandler) renderEdgeEditor(w http.ResponseWriter, tour *models.Tour, index int, edge *models.Edge) {
+	data := edgeEditorData{
+		Tour:      tour,
+		Index:     index,
+		Edge:      edge,
+		Condition: conditionEditorData{Prefix: "condition", Condition: edge.Condition},
+		MediaRows: newMediaRows("media_files", edge.MediaFiles),
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "edge-editor", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// updateEdgeFromForm binds the edge editor form onto edge. Media rows are
+// matched to the existing files by ID, so their translations are kept.
+func (h *EditorHandler) updateEdgeFromForm(edge *models.Edge, r *http.Request) error {
+	if err := binder.Bind(r.Form, edge); err != nil {
+		return err
+	}
+	normalizeCondition(edge.Condition)
+	return nil
+}
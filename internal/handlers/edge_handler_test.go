@@ -0,0 +1,203 @@
+// internal/handlers/edge_handler_test.go
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/middleware"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+)
+
+func newTestEditor(t *testing.T) (*EditorHandler, *services.TourService, context.Context) {
+	tourService := services.NewTourService(repository.NewMemory())
+	handler := NewEditorHandler("../../templates", tourService, nil)
+	if handler == nil {
+		t.Fatal("Failed to create editor handler")
+	}
+
+	ctx := context.WithValue(context.Background(), middleware.UserIDKey, "admin")
+	tour := &models.Tour{
+		ID:          "test_tour",
+		Name:        models.NewText("Test Tour"),
+		Description: models.NewText("A test tour"),
+		StartDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:     time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+		Version:     "1.0",
+		HeroImage:   "http://example.com/image.jpg",
+		Author: models.Author{
+			Name:        "Test Author",
+			ProfileLink: "http://example.com/author",
+		},
+		Price: 1000,
+		Nodes: []models.Node{
+			{ID: 1, Location: models.Location{Lat: 45.0, Lon: 20.0}, ShortDesc: models.NewText("Gate"), Narrative: models.NewText("First"), Start: true},
+			{ID: 2, Location: models.Location{Lat: 45.1, Lon: 20.1}, ShortDesc: models.NewText("Tower"), Narrative: models.NewText("Second"), Finish: true},
+		},
+		Edges: []models.Edge{},
+	}
+	if err := tourService.SaveTourToSession(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+
+	return handler, tourService, ctx
+}
+
+func TestEditorHandler_EdgeLifecycle(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+
+	// Create
+	form := url.Values{
+		"from":                     {"1"},
+		"to":                       {"2"},
+		"instructions":             {"Follow the wall"},
+		"condition.type":           {"q&a"},
+		"condition.question":       {"What colour is the door?"},
+		"condition.correct_answer": {"Red"},
+	}
+	req := httptest.NewRequest("POST", "/edges", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.HandleEdgeSave(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("create returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if rr.Header().Get("HX-Trigger") != "edgeListChanged" {
+		t.Errorf("expected edgeListChanged trigger, got %q", rr.Header().Get("HX-Trigger"))
+	}
+
+	tour := tourService.GetCurrentTour(ctx)
+	if len(tour.Edges) != 1 {
+		t.Fatalf("expected 1 edge, got %d", len(tour.Edges))
+	}
+	if tour.Edges[0].Condition == nil || tour.Edges[0].Condition.Question.String() != "What colour is the door?" {
+		t.Errorf("edge condition not saved: %+v", tour.Edges[0].Condition)
+	}
+
+	// Per-node listing
+	req = httptest.NewRequest("GET", "/edges?node=2", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleEdgesList(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("list returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if !strings.Contains(rr.Body.String(), "Gate") {
+		t.Errorf("expected incoming edge from Gate in listing, got %s", rr.Body)
+	}
+
+	// Update
+	form.Set("silent", "on")
+	form.Del("condition.type")
+	form.Del("condition.question")
+	form.Del("condition.correct_answer")
+	req = httptest.NewRequest("PUT", "/edges/0", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("index", "0")
+	rr = httptest.NewRecorder()
+	handler.HandleEdgeSave(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if !tour.Edges[0].Silent || tour.Edges[0].Condition != nil {
+		t.Errorf("edge not updated: %+v", tour.Edges[0])
+	}
+
+	// Delete
+	req = httptest.NewRequest("DELETE", "/edges/0", nil)
+	req.SetPathValue("index", "0")
+	rr = httptest.NewRecorder()
+	handler.HandleEdgeDelete(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("delete returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if len(tour.Edges) != 0 {
+		t.Errorf("expected no edges after delete, got %d", len(tour.Edges))
+	}
+}
+
+func TestEditorHandler_EdgeSaveRejectsUnknownNode(t *testing.T) {
+	handler, _, ctx := newTestEditor(t)
+
+	form := url.Values{"from": {"1"}, "to": {"9"}}
+	req := httptest.NewRequest("POST", "/edges", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	rr := httptest.NewRecorder()
+	handler.HandleEdgeSave(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestEditorHandler_EdgeSaveMediaRows(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+
+	tour := tourService.GetCurrentTour(ctx)
+	narrative := models.NewText("Look up")
+	narrative.Set("de", "Nach oben schauen")
+	tour.Edges = []models.Edge{{
+		From: 1,
+		To:   2,
+		MediaFiles: []models.MediaFile{
+			{ID: "media-a", Type: "image", URI: "http://example.com/a.jpg", Narrative: narrative},
+			{ID: "media-b", Type: "image", URI: "http://example.com/b.jpg"},
+		},
+	}}
+
+	save := func(form url.Values) *httptest.ResponseRecorder {
+		form.Set("from", "1")
+		form.Set("to", "2")
+		req := httptest.NewRequest("PUT", "/edges/0", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("index", "0")
+		rr := httptest.NewRecorder()
+		handler.HandleEdgeSave(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	// media-b was removed in the browser and a row added with a sparse index
+	rr := save(url.Values{
+		"media_files[0].id":               {"media-a"},
+		"media_files[0].type":             {"image"},
+		"media_files[0].uri":              {"http://example.com/a.jpg"},
+		"media_files[0].narrative":        {"Look up!"},
+		"media_files[1712345].id":         {"media-c"},
+		"media_files[1712345].type":       {"audio"},
+		"media_files[1712345].uri":        {"http://example.com/c.ogg"},
+		"media_files[1712345].send_delay": {"4"},
+	})
+	if rr.Code != http.StatusOK {
+		t.Fatalf("save returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	files := tour.Edges[0].MediaFiles
+	if len(files) != 2 || files[0].ID != "media-a" || files[1].ID != "media-c" || files[1].SendDelay != 4 {
+		t.Fatalf("unexpected media files: %+v", files)
+	}
+	if files[0].Narrative.Value != "Look up!" || files[0].Narrative.In("de") != "Nach oben schauen" {
+		t.Errorf("narrative translation not kept: %+v", files[0].Narrative)
+	}
+
+	rr = save(url.Values{
+		"media_files[0].id":         {"media-a"},
+		"media_files[0].uri":        {"http://example.com/a.jpg"},
+		"media_files[0].send_delay": {"soon"},
+	})
+	if rr.Code != http.StatusBadRequest || !strings.Contains(rr.Body.String(), "media_files[0].send_delay") {
+		t.Errorf("expected field error for send_delay, got %v: %s", rr.Code, rr.Body)
+	}
+	if len(tour.Edges[0].MediaFiles) != 2 {
+		t.Errorf("edge changed despite invalid input: %+v", tour.Edges[0].MediaFiles)
+	}
+}
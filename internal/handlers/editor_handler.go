@@ -2,36 +2,91 @@
 package handlers
 
 import (
-	"fmt"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
 	"path/filepath"
-	"strconv"
+	"sort"
+	"strings"
 
+	"github.com/ceesaxp/tour-guide-editor/internal/binder"
 	"github.com/ceesaxp/tour-guide-editor/internal/models"
 	"github.com/ceesaxp/tour-guide-editor/internal/services"
 )
 
 type EditorHandler struct {
 	templates    *template.Template
+	history      *template.Template // history page, see HandleTourHistory
 	tourService  *services.TourService
 	mediaService *services.MediaService
 }
 
 type TemplateData struct {
-	Title string
-	Tour  *models.Tour
-	Node  *models.Node
-	Error string
+	Title    string
+	Tour     *models.Tour
+	Node     *models.Node
+	Error    string
+	Lang     string // language being edited; empty for the default language
+	Draft    bool   // the tour does not validate yet but can be saved
+	ETag     string // revision of the tour the page shows, see IfMatch
+	Media    nodeMediaData
+	Messages []messageFieldsData
+
+	EntryCondition conditionEditorData
+	ExitCondition  conditionEditorData
+}
+
+// messageFieldsData renders the inputs for a milestone or farewell message.
+type messageFieldsData struct {
+	ID           string
+	Label        string
+	Prefix       string
+	Text         string
+	ErrorID      string // element showing errors of the text
+	MediaRows    []mediaRowData
+	MediaErrorID string // element showing errors of the media rows
+}
+
+func newMessageFields(id, label, prefix string, msg *models.Message) messageFieldsData {
+	data := messageFieldsData{
+		ID:           id,
+		Label:        label,
+		Prefix:       prefix,
+		ErrorID:      fieldErrorID(tourForm, prefix+".text"),
+		MediaErrorID: fieldErrorID(tourForm, prefix+".media_files"),
+	}
+	if msg != nil {
+		data.Text = msg.Text.Value
+		data.MediaRows = newMediaRows(prefix+".media_files", msg.MediaFiles)
+	}
+	return data
 }
 
 func NewEditorHandler(templateDir string, tourService *services.TourService, mediaService *services.MediaService) *EditorHandler {
 	templates, err := template.ParseFiles(
 		filepath.Join(templateDir, "layout.html"),
 		filepath.Join(templateDir, "editor", "condition.html"),
+		filepath.Join(templateDir, "editor", "conflict.html"),
+		filepath.Join(templateDir, "editor", "edge.html"),
+		filepath.Join(templateDir, "editor", "history.html"),
+		filepath.Join(templateDir, "editor", "import.html"),
 		filepath.Join(templateDir, "editor", "index.html"),
+		filepath.Join(templateDir, "editor", "media.html"),
 		filepath.Join(templateDir, "editor", "node.html"),
+		filepath.Join(templateDir, "editor", "preview.html"),
+		filepath.Join(templateDir, "editor", "translation.html"),
+		filepath.Join(templateDir, "editor", "validation.html"),
+	)
+	if err != nil {
+		log.Printf("ERR: error parsing templates: %v", err)
+		return nil
+	}
+	history, err := template.ParseFiles(
+		filepath.Join(templateDir, "layout.html"),
+		filepath.Join(templateDir, "editor", "conflict.html"),
+		filepath.Join(templateDir, "editor", "history.html"),
+		filepath.Join(templateDir, "history", "index.html"),
 	)
 	if err != nil {
 		log.Printf("ERR: error parsing templates: %v", err)
@@ -40,6 +95,7 @@ func NewEditorHandler(templateDir string, tourService *services.TourService, med
 
 	return &EditorHandler{
 		templates:    templates,
+		history:      history,
 		tourService:  tourService,
 		mediaService: mediaService,
 	}
@@ -48,8 +104,16 @@ func NewEditorHandler(templateDir string, tourService *services.TourService, med
 func (h *EditorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	// Get current tour from session or create new one
 	tour := h.tourService.GetCurrentTour(r.Context())
+	var err error
 	if tour == nil {
-		tour = models.NewTour()
+		tour, err = h.tourService.NewTour(r.Context(), userID(r))
+	} else {
+		err = h.tourService.RefreshTour(r.Context(), tour)
+	}
+	if err != nil {
+		log.Printf("ERR: error loading tour: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
 	}
 
 	// Save tour to session
@@ -59,9 +123,18 @@ func (h *EditorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	view := h.tourService.SnapshotTour(tour)
 	data := TemplateData{
 		Title: "Tour Editor",
-		Tour:  tour,
+		Tour:  view,
+		Draft: h.tourService.IsDraft(tour),
+		ETag:  etag(view.Record.Revision),
+		Messages: []messageFieldsData{
+			newMessageFields("milestone-25", "25% Milestone", "milestones.at_25", view.Milestones.At25),
+			newMessageFields("milestone-50", "50% Milestone", "milestones.at_50", view.Milestones.At50),
+			newMessageFields("milestone-75", "75% Milestone", "milestones.at_75", view.Milestones.At75),
+			newMessageFields("farewell", "Farewell", "farewell", view.Farewell),
+		},
 	}
 
 	if err := h.templates.ExecuteTemplate(w, "layout.html", data); err != nil {
@@ -70,87 +143,136 @@ func (h *EditorHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
-// Helper functions for node updates
-func (h *EditorHandler) updateNodeFromForm(node *models.Node, r *http.Request) error {
-	// Basic information
-	if id, err := strconv.Atoi(r.FormValue("id")); err == nil {
-		node.ID = id
-	}
-	node.ShortDesc = r.FormValue("short_description")
-	node.Narrative = r.FormValue("narrative")
-
-	// Location
-	if lat, err := strconv.ParseFloat(r.FormValue("location.lat"), 64); err == nil {
-		node.Location.Lat = lat
-	}
-	if lon, err := strconv.ParseFloat(r.FormValue("location.lon"), 64); err == nil {
-		node.Location.Lon = lon
-	}
+// fieldErrorsData lists the fields a form save was rejected for. The
+// messages are also swapped out of band into the elements next to the
+// inputs, one per element.
+type fieldErrorsData struct {
+	Summary string
+	Errors  []fieldErrorData
+	Targets []fieldErrorData
+}
 
-	// Media files
-	node.MediaFiles = nil
-	for i := 0; ; i++ {
-		uri := r.FormValue(fmt.Sprintf("media_files[%d].uri", i))
-		if uri == "" {
-			break
-		}
+type fieldErrorData struct {
+	Field   string // form field name, e.g. "location.lat"
+	ID      string // ID of the element showing the message
+	Message string
+}
 
-		delay, _ := strconv.Atoi(r.FormValue(fmt.Sprintf("media_files[%d].send_delay", i)))
-		mediaType := r.FormValue(fmt.Sprintf("media_files[%d].type", i))
+// Forms whose fields renderFieldErrors reports, as prefixes of the element
+// IDs. The node editor is shown on the tour's page, so their IDs must not
+// clash.
+const (
+	tourForm = ""
+	nodeForm = "node"
+)
 
-		node.MediaFiles = append(node.MediaFiles, models.MediaFile{
-			Type:      mediaType,
-			URI:       uri,
-			SendDelay: delay,
-		})
+// fieldErrorID returns the element ID that shows the errors of a field of
+// form: "location.lat" of the node form is shown in
+// "error-node-location-lat". The rows of a list share the element of the
+// list, so "farewell.media_files[0].uri" is shown in
+// "error-farewell-media_files"; rows added in the browser are not numbered
+// like the list they are saved to.
+func fieldErrorID(form, field string) string {
+	field, _, _ = strings.Cut(field, "[")
+	if form != "" {
+		field = form + "." + field
 	}
+	return "error-" + strings.ReplaceAll(field, ".", "-")
+}
 
-	// Update conditions
-	if err := h.updateConditionFromForm(node.EntryCondition, "entry_condition", r); err != nil {
-		return err
+// renderFieldErrors responds to a rejected form with a message per field,
+// in the language the browser asks for. root is the struct that failed
+// validation. It reports false if err does not concern form fields, leaving
+// the response to the caller.
+func (h *EditorHandler) renderFieldErrors(w http.ResponseWriter, r *http.Request, form string, err error, root interface{}) bool {
+	langs := acceptLanguages(r)
+
+	fields := h.tourService.FieldErrors(err, root, langs...)
+	var bindErrs binder.Errors
+	if errors.As(err, &bindErrs) {
+		fields = bindErrs
 	}
-	if err := h.updateConditionFromForm(node.ExitCondition, "exit_condition", r); err != nil {
-		return err
+	if len(fields) == 0 {
+		return false
 	}
 
-	return nil
-}
+	data := fieldErrorsData{Summary: h.tourService.ValidationMessage("summary", langs...)}
+	for field, msg := range fields {
+		data.Errors = append(data.Errors, fieldErrorData{Field: field, ID: fieldErrorID(form, field), Message: msg})
+	}
+	sort.Slice(data.Errors, func(i, j int) bool { return data.Errors[i].Field < data.Errors[j].Field })
 
-func (h *EditorHandler) updateConditionFromForm(condition *models.Condition, prefix string, r *http.Request) error {
-	condType := r.FormValue(prefix + ".type")
-	if condType == "" {
-		return nil
+	targets := make(map[string]int)
+	for _, e := range data.Errors {
+		msg := e.Message
+		if _, row, ok := strings.Cut(e.Field, "]."); ok {
+			msg = row + ": " + msg // the row's field, e.g. "uri: …"
+		}
+		if i, ok := targets[e.ID]; ok {
+			data.Targets[i].Message += "; " + msg
+			continue
+		}
+		targets[e.ID] = len(data.Targets)
+		data.Targets = append(data.Targets, fieldErrorData{ID: e.ID, Message: msg})
 	}
 
-	if condition == nil {
-		condition = &models.Condition{}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(http.StatusUnprocessableEntity)
+	if err := h.templates.ExecuteTemplate(w, "field-errors", data); err != nil {
+		log.Printf("Error executing template: %v", err)
 	}
+	return true
+}
 
-	condition.Type = condType
-	condition.Question = r.FormValue(prefix + ".question")
-	condition.CorrectAnswer = r.FormValue(prefix + ".correct_answer")
-	condition.Strict = r.FormValue(prefix+".strict") == "on"
-	condition.MediaLink = r.FormValue(prefix + ".media_link")
+// renderIDError responds to an ID that cannot be saved with the message
+// key, see ValidationMessage, on the id field of form.
+func (h *EditorHandler) renderIDError(w http.ResponseWriter, r *http.Request, form, key string) {
+	msg := h.tourService.ValidationMessage(key, acceptLanguages(r)...)
+	h.renderFieldErrors(w, r, form, binder.Errors{"id": msg}, nil)
+}
 
-	// Options
-	condition.Options = nil
-	for i := 0; ; i++ {
-		option := r.FormValue(fmt.Sprintf("%s.options[%d]", prefix, i))
-		if option == "" {
-			break
+// acceptLanguages lists the languages of the Accept-Language header in
+// the locale format of the message catalogue, each followed by its base
+// language: "de-CH" gives "de_CH", "de".
+func acceptLanguages(r *http.Request) []string {
+	var langs []string
+	for _, part := range strings.Split(r.Header.Get("Accept-Language"), ",") {
+		tag, _, _ := strings.Cut(part, ";")
+		if tag = strings.TrimSpace(tag); tag == "" || tag == "*" {
+			continue
 		}
-		condition.Options = append(condition.Options, option)
-	}
-
-	// Hints
-	condition.Hints = nil
-	for i := 0; ; i++ {
-		hint := r.FormValue(fmt.Sprintf("%s.hints[%d]", prefix, i))
-		if hint == "" {
-			break
+		langs = append(langs, strings.ReplaceAll(tag, "-", "_"))
+		if base, _, ok := strings.Cut(tag, "-"); ok {
+			langs = append(langs, base)
 		}
-		condition.Hints = append(condition.Hints, hint)
 	}
+	return langs
+}
+
+// Helper functions for node updates
 
+// updateNodeFromForm binds the node editor form onto node. The form edits
+// the default language; translations are kept as they are. A condition that
+// is not in the form has been removed.
+func (h *EditorHandler) updateNodeFromForm(node *models.Node, r *http.Request) error {
+	if err := binder.Bind(r.Form, node); err != nil {
+		return err
+	}
+	normalizeCondition(node.EntryCondition)
+	normalizeCondition(node.ExitCondition)
 	return nil
 }
+
+// normalizeCondition drops the options and media link of condition types
+// that do not use them, left behind when the type was switched.
+func normalizeCondition(condition *models.Condition) {
+	if condition == nil {
+		return
+	}
+	if condition.Type != "quiz" {
+		condition.Options = nil
+	}
+	if condition.Type != "puzzle" {
+		condition.MediaLink = ""
+	}
+}
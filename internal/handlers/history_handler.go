@@ -0,0 +1,192 @@
+// internal/handlers/history_handler.go
+package handlers
+
+import (
+	"context"
+	"errors"
+	"log"
+	"net/http"
+	"strconv"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+	"gopkg.in/yaml.v3"
+)
+
+type historyData struct {
+	Title     string
+	Tour      *models.Tour
+	ETag      string
+	Current   int // revision the tour is at
+	Revisions []services.Revision
+	Undo      string // change Undo would revert
+	Redo      string // change Redo would make again
+}
+
+type revisionData struct {
+	Number int
+	YAML   string
+	Diff   []services.DiffLine
+}
+
+// HandleTourHistory renders the history page of the current tour, listing
+// its revisions newest first.
+func (h *EditorHandler) HandleTourHistory(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Redirect(w, r, "/tours", http.StatusSeeOther)
+		return
+	}
+	if err := h.tourService.RefreshTour(r.Context(), tour); err != nil {
+		log.Printf("ERR: error refreshing tour: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+		return
+	}
+
+	data := historyData{Title: "History", Revisions: h.tourService.History(tour)}
+	data.Undo, data.Redo = h.tourService.UndoRedo(tour)
+	h.tourService.ViewTour(tour, func(tour *models.Tour) {
+		copied := *tour
+		data.Tour = &copied
+		data.Current = tour.Record.Revision
+		data.ETag = etag(tour.Record.Revision)
+	})
+	if err := h.history.ExecuteTemplate(w, "layout.html", data); err != nil {
+		log.Printf("ERR: error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleRevisionView renders the YAML of revision {rev} of the current
+// tour.
+func (h *EditorHandler) HandleRevisionView(w http.ResponseWriter, r *http.Request) {
+	tour, number, ok := h.revisionRequest(w, r)
+	if !ok {
+		return
+	}
+	revision, err := h.tourService.GetRevision(tour, number)
+	if !checkRevisionError(w, err) {
+		return
+	}
+	text, err := yaml.Marshal(revision)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+	h.renderRevision(w, "revision-view", revisionData{Number: number, YAML: string(text)})
+}
+
+// HandleRevisionDiff compares revision {rev} of the current tour with its
+// current state.
+func (h *EditorHandler) HandleRevisionDiff(w http.ResponseWriter, r *http.Request) {
+	tour, number, ok := h.revisionRequest(w, r)
+	if !ok {
+		return
+	}
+	diff, err := h.tourService.DiffRevision(tour, number)
+	if !checkRevisionError(w, err) {
+		return
+	}
+	h.renderRevision(w, "revision-diff", revisionData{Number: number, Diff: diff})
+}
+
+// HandleRevisionRestore saves revision {rev} of the current tour as its
+// next revision and returns to the editor.
+func (h *EditorHandler) HandleRevisionRestore(w http.ResponseWriter, r *http.Request) {
+	tour, number, ok := h.revisionRequest(w, r)
+	if !ok {
+		return
+	}
+	err := h.tourService.RestoreRevision(r.Context(), tour, number)
+	if h.renderConflict(w, err) || !checkRevisionError(w, err) {
+		return
+	}
+	h.setRevision(w, tour)
+	w.Header().Set("HX-Redirect", "/")
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleUndo reverts the last change to the current tour and reloads the
+// page.
+func (h *EditorHandler) HandleUndo(w http.ResponseWriter, r *http.Request) {
+	h.undoRedo(w, r, h.tourService.Undo)
+}
+
+// HandleRedo makes the last undone change to the current tour again and
+// reloads the page.
+func (h *EditorHandler) HandleRedo(w http.ResponseWriter, r *http.Request) {
+	h.undoRedo(w, r, h.tourService.Redo)
+}
+
+func (h *EditorHandler) undoRedo(w http.ResponseWriter, r *http.Request, step func(ctx context.Context, tour *models.Tour) error) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	err := step(r.Context(), tour)
+	if h.renderConflict(w, err) {
+		return
+	}
+	if errors.Is(err, services.ErrNothingToUndo) || errors.Is(err, services.ErrNothingToRedo) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.setRevision(w, tour)
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusOK)
+}
+
+// HandleUndoRedo renders the undo and redo buttons of the current tour.
+func (h *EditorHandler) HandleUndoRedo(w http.ResponseWriter, r *http.Request) {
+	var data historyData
+	if tour := h.tourService.GetCurrentTour(r.Context()); tour != nil {
+		data.Undo, data.Redo = h.tourService.UndoRedo(tour)
+	}
+	if err := h.templates.ExecuteTemplate(w, "undo-redo", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// revisionRequest returns the current tour and the revision {rev} of the
+// request, or responds with an error.
+func (h *EditorHandler) revisionRequest(w http.ResponseWriter, r *http.Request) (*models.Tour, int, bool) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	number, err := strconv.Atoi(r.PathValue("rev"))
+	if err != nil {
+		http.Error(w, "Invalid revision", http.StatusBadRequest)
+		return nil, 0, false
+	}
+	return tour, number, true
+}
+
+// checkRevisionError responds to err and reports whether there was none.
+func checkRevisionError(w http.ResponseWriter, err error) bool {
+	switch {
+	case err == nil:
+		return true
+	case errors.Is(err, services.ErrRevisionNotFound):
+		http.Error(w, "Revision not found", http.StatusNotFound)
+	default:
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+	return false
+}
+
+func (h *EditorHandler) renderRevision(w http.ResponseWriter, name string, data revisionData) {
+	if err := h.templates.ExecuteTemplate(w, name, data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,79 @@
+// internal/handlers/history_handler_test.go
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+func TestEditorHandler_History(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	tour := tourService.GetCurrentTour(ctx)
+	for _, id := range []int{10, 11} {
+		node := &models.Node{ID: id, Location: models.Location{Lat: 45.2, Lon: 20.2}, ShortDesc: models.NewText("Bridge"), Narrative: models.NewText("Third")}
+		if err := tourService.SaveNode(ctx, tour, node); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	get := func(path, rev string, serve http.HandlerFunc) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("GET", path, nil)
+		req.SetPathValue("rev", rev)
+		rr := httptest.NewRecorder()
+		serve(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	// History page
+	rr := get("/tour/history", "", handler.HandleTourHistory)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("history returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "Saved node 11") || !strings.Contains(body, `hx-post="/tour/history/1/restore"`) {
+		t.Errorf("expected the revisions to be listed, got %s", body)
+	}
+
+	// Viewing and comparing a revision
+	rr = get("/tour/history/1", "1", handler.HandleRevisionView)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "Bridge") {
+		t.Errorf("expected the YAML of revision 1, got %v: %s", rr.Code, rr.Body)
+	}
+	rr = get("/tour/history/1/diff", "1", handler.HandleRevisionDiff)
+	if rr.Code != http.StatusOK || !strings.Contains(rr.Body.String(), "diff-added") {
+		t.Errorf("expected node 11 to be added since revision 1, got %v: %s", rr.Code, rr.Body)
+	}
+	if rr = get("/tour/history/9", "9", handler.HandleRevisionView); rr.Code != http.StatusNotFound {
+		t.Errorf("expected unknown revisions not to be found, got %v", rr.Code)
+	}
+
+	// Undo
+	req := httptest.NewRequest("POST", "/tour/undo", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleUndo(rr, req.WithContext(ctx))
+	if rr.Code != http.StatusOK || rr.Header().Get("HX-Refresh") != "true" {
+		t.Fatalf("undo returned %v: %s", rr.Code, rr.Body)
+	}
+	if tour.GetNode(11) != nil {
+		t.Error("expected node 11 undone")
+	}
+	rr = get("/tour/undo-redo", "", handler.HandleUndoRedo)
+	if !strings.Contains(rr.Body.String(), `title="Redo: Saved node 11"`) {
+		t.Errorf("expected the redo button to be enabled, got %s", rr.Body)
+	}
+
+	// Restore
+	req = httptest.NewRequest("POST", "/tour/history/2/restore", nil)
+	req.SetPathValue("rev", "2")
+	rr = httptest.NewRecorder()
+	handler.HandleRevisionRestore(rr, req.WithContext(ctx))
+	if rr.Code != http.StatusOK || rr.Header().Get("HX-Redirect") != "/" {
+		t.Fatalf("restore returned %v: %s", rr.Code, rr.Body)
+	}
+	if tour.GetNode(11) == nil || rr.Header().Get("ETag") != `"4"` {
+		t.Errorf("expected revision 2 restored as revision 4, got ETag %s", rr.Header().Get("ETag"))
+	}
+}
@@ -0,0 +1,106 @@
+// internal/handlers/import_handler.go
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"sort"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+)
+
+type importReportData struct {
+	Report *services.ImportReport
+	Errors []fieldErrorData // validation errors of the uploaded tour
+	Error  string           // why the file could not be read or applied
+}
+
+func (h *EditorHandler) newImportReportData(r *http.Request, report *services.ImportReport, err error) importReportData {
+	data := importReportData{Report: report}
+	if report == nil {
+		if err != nil {
+			data.Error = err.Error()
+		}
+		return data
+	}
+
+	invalid := report.Invalid
+	if err != nil {
+		invalid = err
+	}
+	for field, msg := range h.tourService.FieldErrors(invalid, report.Tour, acceptLanguages(r)...) {
+		data.Errors = append(data.Errors, fieldErrorData{Field: field, Message: msg})
+	}
+	sort.Slice(data.Errors, func(i, j int) bool { return data.Errors[i].Field < data.Errors[j].Field })
+	if err != nil && len(data.Errors) == 0 {
+		data.Error = err.Error()
+	}
+	return data
+}
+
+// HandleTourImportCheck reads an uploaded tour file and reports its
+// problems and conflicts with the current tour, offering the ways it can be
+// imported. Nothing is changed yet.
+func (h *EditorHandler) HandleTourImportCheck(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, _, err := r.FormFile("tour_file")
+	if err != nil {
+		http.Error(w, "Invalid file upload", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	report, err := h.tourService.CheckImport(h.tourService.GetCurrentTour(r.Context()), file)
+	h.renderImportReport(w, http.StatusOK, h.newImportReportData(r, report, err))
+}
+
+// HandleTourImport replaces the current tour with the uploaded one, or
+// merges its nodes and edges into it, and reloads the editor. A tour that
+// does not validate is only loaded when "lenient" is set.
+func (h *EditorHandler) HandleTourImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	file, _, err := r.FormFile("tour_file")
+	if err != nil {
+		http.Error(w, "Invalid file upload", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	opts := services.ImportOptions{
+		Mode:      r.FormValue("mode"),
+		Conflicts: r.FormValue("conflicts"),
+		Lenient:   r.FormValue("lenient") == "on",
+	}
+	_, report, err := h.tourService.ApplyImport(r.Context(), h.tourService.GetCurrentTour(r.Context()), file, opts)
+	if h.renderConflict(w, err) {
+		return
+	}
+	if err != nil {
+		status := http.StatusUnprocessableEntity
+		if report != nil && !errors.Is(err, services.ErrImportInvalid) {
+			status = http.StatusBadRequest
+		}
+		h.renderImportReport(w, status, h.newImportReportData(r, report, err))
+		return
+	}
+
+	w.Header().Set("HX-Refresh", "true")
+	w.WriteHeader(http.StatusOK)
+}
+
+func (h *EditorHandler) renderImportReport(w http.ResponseWriter, status int, data importReportData) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.WriteHeader(status)
+	if err := h.templates.ExecuteTemplate(w, "import-report", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+	}
+}
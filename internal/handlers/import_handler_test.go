@@ -0,0 +1,100 @@
+// internal/handlers/import_handler_test.go
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+const importYAML = `
+id: "imported_tour"
+name: "Imported Tour"
+description: "An imported tour"
+start_date: "2024-01-01T00:00:00Z"
+end_date: "2024-12-31T23:59:59Z"
+version: "2.0"
+hero_image: "http://example.com/imported.jpg"
+author:
+  name: "Other Author"
+  profile_link: "http://example.com/other"
+price: 500
+nodes:
+  - id: 2
+    location: {lat: 46.0, lon: 21.0}
+    short_description: ""
+    narrative: "Over the river"
+    start: true
+edges: []
+`
+
+func newImportRequest(t *testing.T, path string, fields map[string]string) *http.Request {
+	t.Helper()
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("tour_file", "tour.yaml")
+	part.Write([]byte(importYAML))
+	for name, value := range fields {
+		writer.WriteField(name, value)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest("POST", path, &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	return req
+}
+
+func TestEditorHandler_TourImport(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	current := tourService.GetCurrentTour(ctx)
+
+	// Check reports errors and conflicts without changing anything
+	rr := httptest.NewRecorder()
+	handler.HandleTourImportCheck(rr, newImportRequest(t, "/tour/import/check", nil).WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("check returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	body := rr.Body.String()
+	for _, want := range []string{"imported_tour", "nodes[0].short_description", "Nodes 2", `name="lenient"`} {
+		if !strings.Contains(body, want) {
+			t.Errorf("report is missing %q: %s", want, body)
+		}
+	}
+
+	// Strict import of an invalid tour is refused
+	rr = httptest.NewRecorder()
+	handler.HandleTourImport(rr, newImportRequest(t, "/tour/import", map[string]string{"mode": "replace"}).WithContext(ctx))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("import returned wrong status code: got %v want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if tourService.GetCurrentTour(ctx) != current {
+		t.Error("invalid tour replaced the current one")
+	}
+
+	// Lenient import loads it as a draft
+	rr = httptest.NewRecorder()
+	handler.HandleTourImport(rr, newImportRequest(t, "/tour/import", map[string]string{"mode": "replace", "lenient": "on"}).WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("lenient import returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if rr.Header().Get("HX-Refresh") != "true" {
+		t.Error("expected the editor to be reloaded")
+	}
+	draft := tourService.GetCurrentTour(ctx)
+	if draft.ID != "imported_tour" || !tourService.IsDraft(draft) {
+		t.Errorf("expected imported draft, got %s", draft.ID)
+	}
+
+	req := httptest.NewRequest("GET", "/", nil)
+	rr = httptest.NewRecorder()
+	handler.ServeHTTP(rr, req.WithContext(ctx))
+	if !strings.Contains(rr.Body.String(), "draft-notice") {
+		t.Error("expected the editor to mark the tour as a draft")
+	}
+}
@@ -0,0 +1,48 @@
+// internal/handlers/media_files_handler.go
+package handlers
+
+import (
+	"errors"
+	"io"
+	"log"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/storage"
+)
+
+// MediaFiles serves the files of store under storage.FilesURL, for the
+// filesystem media backend. Like /static/ the files are public: tours link
+// them.
+func MediaFiles(store storage.MediaStore) http.Handler {
+	return http.StripPrefix(storage.FilesURL+"/", http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		key := strings.TrimPrefix(r.URL.Path, "/")
+		body, obj, err := store.Get(r.Context(), key)
+		if errors.Is(err, storage.ErrNotFound) || errors.Is(err, storage.ErrInvalidKey) {
+			http.NotFound(w, r)
+			return
+		}
+		if err != nil {
+			log.Printf("ERR: error reading media file %s: %v", key, err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+			return
+		}
+		defer body.Close()
+
+		if obj.ContentType != "" {
+			w.Header().Set("Content-Type", obj.ContentType)
+		}
+		if content, ok := body.(io.ReadSeeker); ok {
+			http.ServeContent(w, r, key, obj.Modified, content)
+			return
+		}
+		w.Header().Set("Content-Length", strconv.FormatInt(obj.Size, 10))
+		if !obj.Modified.IsZero() {
+			w.Header().Set("Last-Modified", obj.Modified.UTC().Format(http.TimeFormat))
+		}
+		if r.Method != http.MethodHead {
+			io.Copy(w, body)
+		}
+	}))
+}
@@ -0,0 +1,54 @@
+// internal/handlers/media_files_handler_test.go
+package handlers
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/storage"
+)
+
+func TestMediaFiles(t *testing.T) {
+	store, err := storage.NewFilesystem(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := store.Put(context.Background(), "2024/05/01/a.png", strings.NewReader("png data"), "image/png"); err != nil {
+		t.Fatal(err)
+	}
+	handler := MediaFiles(store)
+
+	tests := []struct {
+		name     string
+		path     string
+		wantCode int
+		wantBody string
+	}{
+		{"file", "/media/files/2024/05/01/a.png", http.StatusOK, "png data"},
+		{"missing file", "/media/files/2024/05/01/b.png", http.StatusNotFound, ""},
+		{"directory", "/media/files/2024/", http.StatusNotFound, ""},
+		{"outside the store", "/media/files/../secret", http.StatusNotFound, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			req := httptest.NewRequest("GET", "/", nil)
+			req.URL.Path = tt.path
+			rr := httptest.NewRecorder()
+			handler.ServeHTTP(rr, req)
+
+			if rr.Code != tt.wantCode {
+				t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, tt.wantCode)
+			}
+			if tt.wantBody != "" && rr.Body.String() != tt.wantBody {
+				t.Errorf("unexpected body %q", rr.Body)
+			}
+			if tt.wantCode == http.StatusOK && rr.Header().Get("Content-Type") != "image/png" {
+				t.Errorf("unexpected content type %q", rr.Header().Get("Content-Type"))
+			}
+		})
+	}
+}
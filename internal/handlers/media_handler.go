@@ -3,8 +3,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"fmt"
+	"log"
 	"net/http"
+	"strconv"
+	"strings"
+	"time"
 
+	"github.com/ceesaxp/tour-guide-editor/internal/binder"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
 	"github.com/ceesaxp/tour-guide-editor/internal/services"
 )
 
@@ -110,3 +117,308 @@ func (h *EditorHandler) HandleMediaValidation(w http.ResponseWriter, r *http.Req
 
 	w.WriteHeader(http.StatusOK)
 }
+
+type mediaRowData struct {
+	Prefix string
+	Index  int
+	Media  models.MediaFile
+}
+
+func newMediaRows(prefix string, files []models.MediaFile) []mediaRowData {
+	rows := make([]mediaRowData, len(files))
+	for i, media := range files {
+		rows[i] = mediaRowData{Prefix: prefix, Index: i, Media: media}
+	}
+	return rows
+}
+
+// HandleMediaRow returns an empty media file row whose inputs are named
+// "<prefix>[<i>].<field>".
+func (h *EditorHandler) HandleMediaRow(w http.ResponseWriter, r *http.Request) {
+	prefix := r.URL.Query().Get("prefix")
+	if prefix == "" {
+		http.Error(w, "Missing prefix", http.StatusBadRequest)
+		return
+	}
+	index, _ := strconv.Atoi(r.URL.Query().Get("i"))
+
+	data := mediaRowData{
+		Prefix: prefix,
+		Index:  index,
+		Media:  models.NewMediaFile("image"),
+	}
+	if err := h.templates.ExecuteTemplate(w, "media-row", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// nodeMediaData renders a node's media list. Unlike edge and message media,
+// which are saved with their form, node media files are managed through
+// their own endpoints.
+type nodeMediaData struct {
+	NodeID                 int
+	Files                  []models.MediaFile
+	Durations              map[string]string // formatted durations of the audio files by ID
+	AudioNarrative         string            // URL of the node's audio narrative, if any
+	AudioNarrativeDuration string            // formatted; empty if unknown
+}
+
+func newNodeMediaData(node *models.Node) nodeMediaData {
+	data := nodeMediaData{
+		NodeID:                 node.ID,
+		Files:                  node.MediaFiles,
+		Durations:              make(map[string]string),
+		AudioNarrative:         node.AudioNarrative,
+		AudioNarrativeDuration: formatSeconds(node.AudioNarrativeDuration),
+	}
+	for _, file := range node.MediaFiles {
+		if d := formatSeconds(file.Duration); d != "" {
+			data.Durations[file.ID] = d
+		}
+	}
+	return data
+}
+
+// formatDuration formats d as m:ss, or h:mm:ss from an hour, rounded to the
+// second.
+func formatDuration(d time.Duration) string {
+	s := int(d.Round(time.Second) / time.Second)
+	if s >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", s/3600, s/60%60, s%60)
+	}
+	return fmt.Sprintf("%d:%02d", s/60, s%60)
+}
+
+// formatSeconds is formatDuration for a duration stored in seconds, or ""
+// if it is unknown.
+func formatSeconds(seconds float64) string {
+	if seconds <= 0 {
+		return ""
+	}
+	return formatDuration(time.Duration(seconds * float64(time.Second)))
+}
+
+// HandleNodeMediaNew returns the form for adding a media file to the node,
+// either by uploading a file or by URL.
+func (h *EditorHandler) HandleNodeMediaNew(w http.ResponseWriter, r *http.Request) {
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+
+	if err := h.templates.ExecuteTemplate(w, "node-media-new", nodeMediaData{NodeID: nodeID}); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleNodeMediaAdd processes an uploaded file, or the file at the posted
+// url, and appends it to the node's media.
+func (h *EditorHandler) HandleNodeMediaAdd(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+	if !h.nodeExists(tour, nodeID) {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+
+	var processed *services.ProcessedMedia
+	file, header, err := r.FormFile("file")
+	switch {
+	case err == nil:
+		defer file.Close()
+		processed, err = h.mediaService.ProcessAndUpload(file, header)
+	case r.FormValue("url") != "":
+		processed, err = h.mediaService.ProcessURL(r.FormValue("url"))
+	default:
+		http.Error(w, "Upload a file or enter a URL", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	media := models.NewMediaFile(mediaTypeOf(processed.MimeType))
+	media.URI = processed.URL
+	media.Duration = models.Seconds(processed.Duration)
+	media.SampleRate, media.Codec = processed.SampleRate, processed.Codec
+	media.SendDelay, _ = strconv.Atoi(r.FormValue("send_delay"))
+	media.Narrative.Set("", r.FormValue("narrative"))
+
+	if err := h.tourService.SaveMediaFile(r.Context(), tour, nodeID, &media); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	h.renderNodeMedia(w, tour, nodeID)
+}
+
+// HandleNodeMediaSave updates the node's media file {mediaID} from the form
+// fields type, uri, send_delay, narrative and cache, and re-renders the
+// node's media list.
+func (h *EditorHandler) HandleNodeMediaSave(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+	var file models.MediaFile
+	var nodeFound, fileFound bool
+	h.tourService.ViewTour(tour, func(tour *models.Tour) {
+		node := tour.GetNode(nodeID)
+		if nodeFound = node != nil; !nodeFound {
+			return
+		}
+		if existing := node.GetMediaFile(r.PathValue("mediaID")); existing != nil {
+			file, fileFound = *existing, true
+		}
+	})
+	if !nodeFound {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+	if !fileFound {
+		http.Error(w, "Media file not found", http.StatusNotFound)
+		return
+	}
+
+	id, uri := file.ID, file.URI
+	if err := binder.Bind(r.Form, &file); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	file.ID = id // the path names the file; a posted id does not rename it
+	if file.URI != uri {
+		// Probed from the old file; unknown for a linked one
+		file.Duration, file.SampleRate, file.Codec = 0, 0, ""
+	}
+
+	if err := h.tourService.SaveMediaFile(r.Context(), tour, nodeID, &file); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
+		return
+	}
+
+	h.renderNodeMedia(w, tour, nodeID)
+}
+
+func (h *EditorHandler) HandleNodeMediaMove(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+	if !h.nodeExists(tour, nodeID) {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+
+	var offset int
+	switch r.FormValue("direction") {
+	case "up":
+		offset = -1
+	case "down":
+		offset = 1
+	default:
+		http.Error(w, "Invalid direction", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.tourService.MoveMediaFile(r.Context(), tour, nodeID, r.PathValue("mediaID"), offset); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		}
+		return
+	}
+
+	h.renderNodeMedia(w, tour, nodeID)
+}
+
+func (h *EditorHandler) HandleNodeMediaDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+	if !h.nodeExists(tour, nodeID) {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+
+	if err := h.tourService.DeleteMediaFile(r.Context(), tour, nodeID, r.PathValue("mediaID")); err != nil {
+		if !h.renderConflict(w, err) {
+			http.Error(w, err.Error(), http.StatusNotFound)
+		}
+		return
+	}
+
+	h.renderNodeMedia(w, tour, nodeID)
+}
+
+// renderNodeMedia re-renders the media list of the tour's node nodeID after
+// a change to it.
+func (h *EditorHandler) renderNodeMedia(w http.ResponseWriter, tour *models.Tour, nodeID int) {
+	var data nodeMediaData
+	h.tourService.ViewTour(tour, func(tour *models.Tour) {
+		w.Header().Set("ETag", etag(tour.Record.Revision))
+		if node := tour.GetNode(nodeID); node != nil {
+			data = newNodeMediaData(node)
+		}
+	})
+
+	w.Header().Set("HX-Trigger", "tourChanged")
+	if err := h.templates.ExecuteTemplate(w, "node-media", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// mediaTypeOf maps a MIME type to a MediaFile type.
+func mediaTypeOf(mimeType string) string {
+	switch {
+	case strings.HasPrefix(mimeType, "audio/"):
+		return "audio"
+	case strings.HasPrefix(mimeType, "video/"):
+		return "video"
+	default:
+		return "image"
+	}
+}
@@ -11,12 +11,16 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
 	"os"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/ceesaxp/tour-guide-editor/internal/mocks"
 	"github.com/ceesaxp/tour-guide-editor/internal/services"
+	"github.com/ceesaxp/tour-guide-editor/internal/storage"
 )
 
 func TestMediaHandler_Upload(t *testing.T) {
@@ -32,7 +36,6 @@ func TestMediaHandler_Upload(t *testing.T) {
         AllowedFormats: []string{"image/", "audio/", "video/"},
         ImageMaxWidth:  800,
         ImageMaxHeight: 600,
-        S3Bucket:      "test-bucket",
     }
 
     mockS3 := &mocks.MockS3Client{
@@ -41,7 +44,7 @@ func TestMediaHandler_Upload(t *testing.T) {
         },
     }
 
-    mediaService := services.NewMediaService(config, mockS3)
+    mediaService := services.NewMediaService(config, storage.NewS3Store(mockS3, "test-bucket", "https://test-bucket.s3.amazonaws.com"))
     handler := NewMediaHandler(mediaService)
 
     tests := []struct {
@@ -125,7 +128,7 @@ func TestMediaHandler_ValidateURL(t *testing.T) {
 		AllowedFormats: []string{"image/", "audio/", "video/"},
 	}
 
-	mediaService := services.NewMediaService(config, mockS3)
+	mediaService := services.NewMediaService(config, storage.NewS3Store(mockS3, "test-bucket", "https://test-bucket.s3.amazonaws.com"))
 	handler := NewMediaHandler(mediaService)
 
 	tests := []struct {
@@ -196,3 +199,131 @@ func createTestImage(t *testing.T) []byte {
 
 	return buf.Bytes()
 }
+
+func TestEditorHandler_NodeMedia(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	handler.mediaService = services.NewMediaService(services.MediaConfig{
+		MaxFileSize:    1024 * 1024,
+		AllowedFormats: []string{"image/", "audio/", "video/"},
+		ImageMaxWidth:  800,
+		ImageMaxHeight: 600,
+	}, storage.NewS3Store(&mocks.MockS3Client{
+		PutObjectFunc: func(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+			return &s3.PutObjectOutput{}, nil
+		},
+	}, "test-bucket", "https://test-bucket.s3.amazonaws.com"))
+	tour := tourService.GetCurrentTour(ctx)
+
+	imageData := createTestImage(t)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "image/jpeg")
+		w.Write(imageData)
+	}))
+	defer server.Close()
+
+	// Add by upload
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "gate.jpg")
+	part.Write(imageData)
+	writer.WriteField("send_delay", "2")
+	writer.Close()
+
+	req := httptest.NewRequest("POST", "/nodes/1/media", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.SetPathValue("id", "1")
+	rr := httptest.NewRecorder()
+	handler.HandleNodeMediaAdd(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("upload returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+
+	// Add by URL
+	form := url.Values{"url": {server.URL + "/tower.jpg"}}
+	req = httptest.NewRequest("POST", "/nodes/1/media", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", "1")
+	rr = httptest.NewRecorder()
+	handler.HandleNodeMediaAdd(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("add by URL returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	node := tour.GetNode(1)
+	if len(node.MediaFiles) != 2 || node.MediaFiles[0].Type != "image" || node.MediaFiles[0].SendDelay != 2 {
+		t.Fatalf("media files not added: %+v", node.MediaFiles)
+	}
+	first, second := node.MediaFiles[0].ID, node.MediaFiles[1].ID
+
+	// Update; a posted id does not rename the file
+	form = url.Values{"id": {first}, "type": {"audio"}, "uri": {"http://example.com/a.ogg"}, "send_delay": {"3"}}
+	req = httptest.NewRequest("PUT", "/nodes/1/media/"+second, strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", "1")
+	req.SetPathValue("mediaID", second)
+	rr = httptest.NewRecorder()
+	handler.HandleNodeMediaSave(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("update returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if file := tour.GetNode(1).GetMediaFile(second); file.Type != "audio" || file.SendDelay != 3 {
+		t.Errorf("media file not updated: %+v", file)
+	}
+	if node := tour.GetNode(1); len(node.MediaFiles) != 2 || node.MediaFiles[0].ID != first || node.MediaFiles[0].Type != "image" {
+		t.Errorf("posted id changed the media files: %+v", node.MediaFiles)
+	}
+
+	// Move
+	form = url.Values{"direction": {"up"}}
+	req = httptest.NewRequest("POST", "/nodes/1/media/"+second+"/move", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", "1")
+	req.SetPathValue("mediaID", second)
+	rr = httptest.NewRecorder()
+	handler.HandleNodeMediaMove(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("move returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if node := tour.GetNode(1); node.MediaFiles[0].ID != second {
+		t.Errorf("expected %s first after move, got %+v", second, node.MediaFiles)
+	}
+
+	// Delete
+	req = httptest.NewRequest("DELETE", "/nodes/1/media/"+first, nil)
+	req.SetPathValue("id", "1")
+	req.SetPathValue("mediaID", first)
+	rr = httptest.NewRecorder()
+	handler.HandleNodeMediaDelete(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("delete returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if node := tour.GetNode(1); len(node.MediaFiles) != 1 || node.MediaFiles[0].ID != second {
+		t.Errorf("expected only %s to remain, got %+v", second, node.MediaFiles)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "/nodes/1/media/"+second) || strings.Contains(body, first) {
+		t.Errorf("expected re-rendered media list, got %s", body)
+	}
+}
+
+func TestFormatDuration(t *testing.T) {
+	tests := []struct {
+		d    time.Duration
+		want string
+	}{
+		{0, "0:00"},
+		{1499 * time.Millisecond, "0:01"},
+		{61500 * time.Millisecond, "1:02"},
+		{59*time.Minute + 59*time.Second, "59:59"},
+		{time.Hour + 2*time.Minute + 3*time.Second, "1:02:03"},
+	}
+
+	for _, tt := range tests {
+		if got := formatDuration(tt.d); got != tt.want {
+			t.Errorf("formatDuration(%v) = %q, want %q", tt.d, got, tt.want)
+		}
+	}
+}
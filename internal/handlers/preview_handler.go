@@ -0,0 +1,81 @@
+// internal/handlers/preview_handler.go
+package handlers
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+)
+
+type yamlPreviewData struct {
+	*services.TourPreview
+	Errors int // findings that prevent export
+}
+
+// HandleTourPreview renders the current tour as highlighted YAML, marking
+// the sections that fail validation.
+func (h *EditorHandler) HandleTourPreview(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	preview, err := h.tourService.PreviewTour(h.tourService.SnapshotTour(tour), acceptLanguages(r)...)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	data := yamlPreviewData{TourPreview: preview}
+	for _, issue := range preview.Issues {
+		if issue.Severity == services.SeverityError {
+			data.Errors++
+		}
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "yaml-preview", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// HandleTourExport downloads the current tour as YAML once it validates,
+// flattened to a single language with ?lang=.
+func (h *EditorHandler) HandleTourExport(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	view := h.tourService.SnapshotTour(tour)
+	filename := view.ID
+	if view.Version != "" {
+		filename += "-" + view.Version
+	}
+
+	var data []byte
+	var err error
+	if lang := r.URL.Query().Get("lang"); lang != "" {
+		data, err = h.tourService.ExportTourLanguage(view, lang)
+		filename += "." + lang
+	} else {
+		data, err = h.tourService.ExportTour(view)
+	}
+	if errors.Is(err, services.ErrLanguageNotDeclared) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusUnprocessableEntity)
+		return
+	}
+
+	w.Header().Set("Content-Type", "application/yaml")
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", filename+".yaml"))
+	w.Write(data)
+}
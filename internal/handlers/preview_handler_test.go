@@ -0,0 +1,73 @@
+// internal/handlers/preview_handler_test.go
+package handlers
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+func TestEditorHandler_TourPreviewAndExport(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	tour := tourService.GetCurrentTour(ctx)
+	tour.Edges = []models.Edge{{From: 1, To: 2}}
+
+	// Valid tour
+	req := httptest.NewRequest("GET", "/tour/preview", nil)
+	rr := httptest.NewRecorder()
+	handler.HandleTourPreview(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("preview returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	body := rr.Body.String()
+	if !strings.Contains(body, `<span class="yaml-key">short_description</span>`) || !strings.Contains(body, `href="/tour/export"`) {
+		t.Errorf("expected highlighted YAML with an export link, got %s", body)
+	}
+
+	req = httptest.NewRequest("GET", "/tour/export", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleTourExport(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("export returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename="test_tour-1.0.yaml"` {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+	if !strings.Contains(rr.Body.String(), "id: test_tour") {
+		t.Errorf("unexpected export %s", rr.Body)
+	}
+
+	// Only the tour's languages can be exported
+	req = httptest.NewRequest("GET", "/tour/export?lang=%22%0Dxx", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleTourExport(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusBadRequest || rr.Header().Get("Content-Disposition") != "" {
+		t.Errorf("export of an undeclared language: got %v with %q", rr.Code, rr.Header().Get("Content-Disposition"))
+	}
+
+	// Invalid tour
+	tour.GetNode(2).ShortDesc = models.NewText("")
+
+	req = httptest.NewRequest("GET", "/tour/preview", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleTourPreview(rr, req.WithContext(ctx))
+
+	body = rr.Body.String()
+	if !strings.Contains(body, `class="yaml-line error"`) || strings.Contains(body, `href="/tour/export"`) {
+		t.Errorf("expected the invalid node marked and export disabled, got %s", body)
+	}
+
+	req = httptest.NewRequest("GET", "/tour/export", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleTourExport(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Errorf("export returned wrong status code: got %v want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+}
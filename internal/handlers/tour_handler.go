@@ -3,12 +3,15 @@ package handlers
 
 import (
 	"encoding/json"
+	"errors"
 	"html/template"
 	"log"
 	"net/http"
 	"strconv"
+	"strings"
 	"time"
 
+	"github.com/ceesaxp/tour-guide-editor/internal/binder"
 	"github.com/ceesaxp/tour-guide-editor/internal/models"
 	"github.com/ceesaxp/tour-guide-editor/internal/services"
 )
@@ -89,35 +92,29 @@ func (h *EditorHandler) HandleTourMetadata(w http.ResponseWriter, r *http.Reques
 		return
 	}
 
-	// Update tour metadata
-	tour.ID = r.FormValue("id")
-	tour.Name = r.FormValue("name")
-	tour.Description = r.FormValue("description")
-
-	if price, err := strconv.Atoi(r.FormValue("price")); err == nil {
-		tour.Price = price
-	}
-
-	// Parse dates
-	if startDate, err := parseDate(r.FormValue("start_date")); err == nil {
-		tour.StartDate = startDate
-	}
-	if endDate, err := parseDate(r.FormValue("end_date")); err == nil {
-		tour.EndDate = endDate
+	// Update, validate and save; drafts are saved despite errors elsewhere
+	// in the tour
+	err := h.tourService.EditTour(r.Context(), tour, "Saved tour details", func(tour *models.Tour) error {
+		return updateTourFromForm(tour, r)
+	})
+	if errors.Is(err, services.ErrTourIDFixed) {
+		h.renderIDError(w, r, tourForm, "id_fixed")
+		return
 	}
-
-	// Validate and save
-	if err := h.tourService.ValidateTour(tour); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+	if errors.Is(err, services.ErrTourIDTaken) {
+		h.renderIDError(w, r, tourForm, "id_taken")
 		return
 	}
-
-	if err := h.tourService.SaveTour(r.Context(), tour); err != nil {
-		http.Error(w, err.Error(), http.StatusInternalServerError)
+	if err != nil {
+		if !h.renderConflict(w, err) && !h.renderFieldErrors(w, r, tourForm, err, tour) {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+		}
 		return
 	}
 
 	// Return success toast message
+	h.setRevision(w, tour)
+	w.Header().Set("HX-Trigger", "tourChanged")
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
 		"message": "Tour metadata updated successfully",
@@ -125,6 +122,81 @@ func (h *EditorHandler) HandleTourMetadata(w http.ResponseWriter, r *http.Reques
 	})
 }
 
+// updateTourFromForm binds the metadata form onto the tour's details,
+// settings and messages. The form edits the default language; translations
+// are kept as they are. A message without text or media is removed.
+func updateTourFromForm(tour *models.Tour, r *http.Request) error {
+	// Translation languages are entered comma-separated
+	if _, ok := r.Form["settings.languages"]; ok {
+		r.Form["settings.languages"] = parseLanguages(r.Form.Get("settings.languages"))
+	}
+	if err := binder.Bind(r.Form, tour); err != nil {
+		return err
+	}
+
+	for _, msg := range []**models.Message{&tour.Milestones.At25, &tour.Milestones.At50, &tour.Milestones.At75, &tour.Farewell} {
+		if *msg != nil && (*msg).Text.Value == "" && len((*msg).MediaFiles) == 0 {
+			*msg = nil
+		}
+	}
+	return nil
+}
+
+type validationReportData struct {
+	Error  string
+	Issues services.GraphIssues
+}
+
+// HandleTourValidate runs the field and graph validators against the current
+// tour and renders the findings.
+func (h *EditorHandler) HandleTourValidate(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	view := h.tourService.SnapshotTour(tour)
+	data := validationReportData{
+		Issues: append(h.tourService.ValidateGraph(view), h.tourService.MissingTranslations(view)...),
+	}
+	if err := h.tourService.ValidateTour(view); err != nil {
+		data.Error = err.Error()
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "validation-report", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// audioTimeData is the total audio time of a tour. Unknown counts the
+// audio files whose duration was not probed, which Total leaves out.
+type audioTimeData struct {
+	Total   string
+	Unknown int
+}
+
+// HandleTourAudioTime renders the total playing time of the current tour's
+// audio narratives and audio files, or nothing if it has no audio.
+func (h *EditorHandler) HandleTourAudioTime(w http.ResponseWriter, r *http.Request) {
+	var data audioTimeData
+	if tour := h.tourService.GetCurrentTour(r.Context()); tour != nil {
+		var total time.Duration
+		h.tourService.ViewTour(tour, func(tour *models.Tour) {
+			total, data.Unknown = tour.AudioDuration()
+		})
+		if total > 0 || data.Unknown > 0 {
+			data.Total = formatDuration(total)
+		}
+	}
+
+	if err := h.templates.ExecuteTemplate(w, "audio-time", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
 func (h *EditorHandler) HandleNodesList(w http.ResponseWriter, r *http.Request) {
 	tour := h.tourService.GetCurrentTour(r.Context())
 	if tour == nil {
@@ -132,7 +204,7 @@ func (h *EditorHandler) HandleNodesList(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	if err := h.templates.ExecuteTemplate(w, "nodes-list", tour.Nodes); err != nil {
+	if err := h.templates.ExecuteTemplate(w, "nodes-list", h.tourService.SnapshotTour(tour).Nodes); err != nil {
 		log.Printf("Error executing template: %v", err)
 		http.Error(w, "Internal server error", http.StatusInternalServerError)
 	}
@@ -147,11 +219,13 @@ func (h *EditorHandler) HandleNodeEditor(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
+	view := h.tourService.SnapshotTour(tour)
 	var node *models.Node
 	if nodeID > 0 {
-		node = tour.GetNode(nodeID)
+		node = view.GetNode(nodeID)
 	} else {
 		node = models.NewNode()
+		node.ID = h.tourService.AllocateNodeID(tour)
 	}
 
 	if node == nil {
@@ -159,11 +233,17 @@ func (h *EditorHandler) HandleNodeEditor(w http.ResponseWriter, r *http.Request)
 		return
 	}
 
-	data := TemplateData{
-		Node: node,
+	// Other languages get a translation form next to the default text
+	if lang := r.URL.Query().Get("lang"); lang != "" && nodeID > 0 {
+		if !view.Settings.HasLanguage(lang) {
+			http.Error(w, "Language is not declared in the tour settings", http.StatusBadRequest)
+			return
+		}
+		h.renderNodeTranslationEditor(w, view, node, lang)
+		return
 	}
 
-	h.templates.ExecuteTemplate(w, "node-editor", data)
+	h.templates.ExecuteTemplate(w, "node-editor", newNodeEditorData(view, node))
 }
 
 func (h *EditorHandler) HandleNodeSave(w http.ResponseWriter, r *http.Request) {
@@ -184,33 +264,65 @@ func (h *EditorHandler) HandleNodeSave(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
+	// The path holds the node's current ID; the form may renumber it
 	nodeID, _ := strconv.Atoi(r.PathValue("id"))
-	node := tour.GetNode(nodeID)
-	if node == nil {
-		node = models.NewNode()
-		node.ID = nodeID
-	}
+	node := models.NewNode()
+	node.ID = nodeID
+	h.tourService.ViewTour(tour, func(tour *models.Tour) {
+		if existing := tour.GetNode(nodeID); existing != nil {
+			copied := *existing
+			node = &copied
+		}
+	})
 
 	// Update node data
 	if err := h.updateNodeFromForm(node, r); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if !h.renderFieldErrors(w, r, nodeForm, err, node) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
 		return
 	}
 
 	// Validate and save
 	if err := h.tourService.ValidateNode(node); err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		if !h.renderFieldErrors(w, r, nodeForm, err, node) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+		}
 		return
 	}
 
-	if err := h.tourService.SaveNode(r.Context(), tour, node); err != nil {
+	err := h.tourService.UpdateNode(r.Context(), tour, nodeID, node)
+	if h.renderConflict(w, err) {
+		return
+	}
+	if errors.Is(err, services.ErrNodeIDTaken) {
+		h.renderIDError(w, r, nodeForm, "id_taken")
+		return
+	}
+	if err != nil {
 		http.Error(w, err.Error(), http.StatusInternalServerError)
 		return
 	}
 
 	// Trigger node list update
+	h.setRevision(w, tour)
 	w.Header().Set("HX-Trigger", "nodeListChanged")
 
+	// A renumbered node is re-rendered so the form saves to its new ID
+	if node.ID != nodeID {
+		w.Header().Set("HX-Retarget", "#node-editor")
+		w.Header().Set("HX-Reswap", "innerHTML")
+		var data TemplateData
+		h.tourService.ViewTour(tour, func(tour *models.Tour) {
+			data = newNodeEditorData(tour, tour.GetNode(node.ID))
+		})
+		if err := h.templates.ExecuteTemplate(w, "node-editor", data); err != nil {
+			log.Printf("Error executing template: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+
 	// Return success toast message
 	w.Header().Set("Content-Type", "application/json")
 	json.NewEncoder(w).Encode(map[string]string{
@@ -219,8 +331,94 @@ func (h *EditorHandler) HandleNodeSave(w http.ResponseWriter, r *http.Request) {
 	})
 }
 
+func newNodeEditorData(tour *models.Tour, node *models.Node) TemplateData {
+	return TemplateData{
+		Tour:           tour,
+		Node:           node,
+		Media:          newNodeMediaData(node),
+		EntryCondition: conditionEditorData{Prefix: "entry_condition", Condition: node.EntryCondition},
+		ExitCondition:  conditionEditorData{Prefix: "exit_condition", Condition: node.ExitCondition},
+	}
+}
+
+type startReplacementData struct {
+	Node  *models.Node
+	Nodes []models.Node
+}
+
+// HandleNodeDelete removes a node and its edges. Deleting the start node
+// requires a replacement_start node ID; without one the author is asked to
+// pick a replacement.
+func (h *EditorHandler) HandleNodeDelete(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodDelete {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+	view := h.tourService.SnapshotTour(tour)
+	node := view.GetNode(nodeID)
+	if node == nil {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+
+	var err error
+	if replacement := r.FormValue("replacement_start"); replacement != "" {
+		replacementID, convErr := strconv.Atoi(replacement)
+		if convErr != nil {
+			http.Error(w, "Invalid replacement start node", http.StatusBadRequest)
+			return
+		}
+		err = h.tourService.DeleteStartNode(r.Context(), tour, nodeID, replacementID)
+	} else {
+		err = h.tourService.DeleteNode(r.Context(), tour, nodeID)
+	}
+	if h.renderConflict(w, err) {
+		return
+	}
+	if errors.Is(err, services.ErrInvalidStartNode) {
+		http.Error(w, "Invalid replacement start node", http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, services.ErrDeleteStartNode) {
+		data := startReplacementData{Node: node}
+		for _, n := range view.Nodes {
+			if n.ID != nodeID {
+				data.Nodes = append(data.Nodes, n)
+			}
+		}
+		if err := h.templates.ExecuteTemplate(w, "start-node-replacement", data); err != nil {
+			log.Printf("Error executing template: %v", err)
+			http.Error(w, "Internal server error", http.StatusInternalServerError)
+		}
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+		return
+	}
+
+	h.setRevision(w, tour)
+	w.Header().Set("HX-Trigger", "nodeListChanged")
+	w.WriteHeader(http.StatusOK)
+}
+
 // Helper functions
 
-func parseDate(dateStr string) (time.Time, error) {
-	return time.Parse("2006-01-02", dateStr)
+// parseLanguages splits a comma separated list of language tags.
+func parseLanguages(value string) []string {
+	var langs []string
+	for _, lang := range strings.Split(value, ",") {
+		if lang = strings.TrimSpace(lang); lang != "" {
+			langs = append(langs, lang)
+		}
+	}
+	return langs
 }
@@ -7,14 +7,19 @@ import (
 	"mime/multipart"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
 	"github.com/ceesaxp/tour-guide-editor/internal/services"
 )
 
 func TestTourHandler_Upload(t *testing.T) {
-	tourService := services.NewTourService()
+	tourService := services.NewTourService(repository.NewMemory())
 	handler := NewTourHandler(tourService, nil)
 
 	tests := []struct {
@@ -84,7 +89,7 @@ invalid_yaml: [
 }
 
 func TestTourHandler_ValidateNode(t *testing.T) {
-	tourService := services.NewTourService()
+	tourService := services.NewTourService(repository.NewMemory())
 	handler := NewTourHandler(tourService, nil)
 
 	tests := []struct {
@@ -101,10 +106,11 @@ func TestTourHandler_ValidateNode(t *testing.T) {
 					Lat: 45.0,
 					Lon: 20.0,
 				},
-				ShortDesc: "Test Node",
-				Narrative: "Test narrative",
+				ShortDesc: models.NewText("Test Node"),
+				Narrative: models.NewText("Test narrative"),
 				MediaFiles: []models.MediaFile{
 					{
+						ID:        "media-1",
 						Type:      "image",
 						URI:       "http://example.com/image.jpg",
 						SendDelay: 0,
@@ -158,3 +164,282 @@ func TestTourHandler_ValidateNode(t *testing.T) {
 		})
 	}
 }
+
+func TestEditorHandler_NodeSaveRenumbers(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	tour := tourService.GetCurrentTour(ctx)
+	tour.Edges = []models.Edge{{From: 1, To: 2}}
+
+	save := func(id string, form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("PUT", "/nodes/"+id, strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		req.SetPathValue("id", id)
+		rr := httptest.NewRecorder()
+		handler.HandleNodeSave(rr, req.WithContext(ctx))
+		return rr
+	}
+
+	form := url.Values{
+		"id":                {"2"},
+		"short_description": {"Tower"},
+		"narrative":         {"Second"},
+		"finish":            {"on"},
+		"location.lat":      {"45.1"},
+		"location.lon":      {"20.1"},
+	}
+
+	// Taken ID
+	form.Set("id", "1")
+	rr := save("2", form)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(rr.Body.String(), `id="error-node-id"`) || !strings.Contains(rr.Body.String(), "already in use") {
+		t.Errorf("taken ID not shown on the id field: %s", rr.Body.String())
+	}
+	if tour.GetNode(2) == nil {
+		t.Fatal("node was renumbered despite the conflict")
+	}
+
+	// Free ID
+	form.Set("id", "7")
+	rr = save("2", form)
+	if rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if rr.Header().Get("HX-Retarget") != "#node-editor" || !strings.Contains(rr.Body.String(), `hx-put="/nodes/7"`) {
+		t.Errorf("expected the editor to be re-rendered for node 7, got %s", rr.Body)
+	}
+	if tour.GetNode(7) == nil || tour.Edges[0].To != 7 {
+		t.Errorf("node or edge not renumbered: %+v %+v", tour.Nodes, tour.Edges)
+	}
+
+	// New nodes get a fresh ID
+	req := httptest.NewRequest("GET", "/nodes/new", nil)
+	rr = httptest.NewRecorder()
+	handler.HandleNodeEditor(rr, req.WithContext(ctx))
+	if !strings.Contains(rr.Body.String(), `hx-put="/nodes/8"`) {
+		t.Errorf("expected new node to be allocated ID 8, got %s", rr.Body)
+	}
+}
+
+func TestEditorHandler_NodeSaveFieldErrors(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+
+	tests := []struct {
+		name     string
+		language string
+		want     []string
+	}{
+		{
+			name: "english",
+			want: []string{
+				`<span id="error-node-narrative" class="field-error" hx-swap-oob="true">This field is required</span>`,
+				`<span id="error-node-location-lat" class="field-error" hx-swap-oob="true">Enter a latitude between -90 and 90</span>`,
+				"Please correct the highlighted fields",
+			},
+		},
+		{
+			name:     "german",
+			language: "de-CH,de;q=0.9,en;q=0.8",
+			want: []string{
+				`<span id="error-node-narrative" class="field-error" hx-swap-oob="true">Dieses Feld ist erforderlich</span>`,
+				"Bitte korrigieren Sie die markierten Felder",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			form := url.Values{
+				"id":                {"1"},
+				"short_description": {"Gate"},
+				"narrative":         {""},
+				"location.lat":      {"95"},
+				"location.lon":      {"20"},
+			}
+			req := httptest.NewRequest("PUT", "/nodes/1", strings.NewReader(form.Encode()))
+			req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+			req.Header.Set("Accept-Language", tt.language)
+			req.SetPathValue("id", "1")
+			rr := httptest.NewRecorder()
+			handler.HandleNodeSave(rr, req.WithContext(ctx))
+
+			if rr.Code != http.StatusUnprocessableEntity {
+				t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusUnprocessableEntity, rr.Body)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(rr.Body.String(), want) {
+					t.Errorf("expected %q in response:\n%s", want, rr.Body)
+				}
+			}
+		})
+	}
+
+	if narrative := tourService.GetCurrentTour(ctx).GetNode(1).Narrative.String(); narrative != "First" {
+		t.Errorf("invalid node was saved: narrative %q", narrative)
+	}
+
+	// Unparseable input is reported the same way
+	form := url.Values{"id": {"1"}, "location.lon": {"east"}}
+	req := httptest.NewRequest("PUT", "/nodes/1", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", "1")
+	rr := httptest.NewRecorder()
+	handler.HandleNodeSave(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusUnprocessableEntity || !strings.Contains(rr.Body.String(), `id="error-node-location-lon"`) {
+		t.Errorf("expected field error for location.lon, got %v: %s", rr.Code, rr.Body)
+	}
+}
+
+func TestEditorHandler_TourMetadata(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	tour := tourService.GetCurrentTour(ctx)
+
+	post := func(form url.Values) *httptest.ResponseRecorder {
+		req := httptest.NewRequest("POST", "/tour/metadata", strings.NewReader(form.Encode()))
+		req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+		rr := httptest.NewRecorder()
+		handler.HandleTourMetadata(rr, req.WithContext(ctx))
+		return rr
+	}
+	form := url.Values{
+		"id":                          {"test_tour"},
+		"name":                        {"Old Town"},
+		"description":                 {"A walk"},
+		"start_date":                  {"2024-05-01"},
+		"end_date":                    {"2024-09-30"},
+		"price":                       {"1500"},
+		"hero_image":                  {"http://example.com/hero.jpg"},
+		"settings.media_caching":      {"prefetch_all"},
+		"settings.preferred_language": {"en"},
+		"settings.languages":          {"de, fr"},
+		"milestones.at_50.text":       {"Halfway"},
+		"farewell.text":               {""},
+	}
+
+	if rr := post(form); rr.Code != http.StatusOK {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if tour.Name.String() != "Old Town" || tour.Price != 1500 || !tour.StartDate.Equal(time.Date(2024, 5, 1, 0, 0, 0, 0, time.UTC)) {
+		t.Errorf("details not saved: %q, %d, %v", tour.Name, tour.Price, tour.StartDate)
+	}
+	if !reflect.DeepEqual(tour.Settings.Languages, []string{"de", "fr"}) || tour.Settings.MediaCaching != "prefetch_all" {
+		t.Errorf("settings not saved: %+v", tour.Settings)
+	}
+	if tour.Milestones.At50 == nil || tour.Milestones.At50.Text.String() != "Halfway" || tour.Farewell != nil {
+		t.Errorf("expected only the 50%% milestone, got %+v and %+v", tour.Milestones, tour.Farewell)
+	}
+	if len(tour.Nodes) != 2 {
+		t.Errorf("nodes changed by the metadata form: %+v", tour.Nodes)
+	}
+
+	// Malformed numbers and dates are field errors, not ignored
+	form.Set("price", "12.50")
+	form.Set("start_date", "May 1st")
+	rr := post(form)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("handler returned wrong status code: got %v want %v: %s", rr.Code, http.StatusUnprocessableEntity, rr.Body)
+	}
+	for _, id := range []string{`id="error-price"`, `id="error-start_date"`} {
+		if !strings.Contains(rr.Body.String(), id) {
+			t.Errorf("expected %s in response:\n%s", id, rr.Body)
+		}
+	}
+	if tour.Price != 1500 {
+		t.Errorf("malformed price was saved: %d", tour.Price)
+	}
+
+	// A rejected change does not linger in the open tour
+	form.Set("price", "1500")
+	form.Set("start_date", "2024-05-01")
+	form.Set("name", "")
+	if rr := post(form); rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if tour.Name.String() != "Old Town" {
+		t.Errorf("rejected name was kept: %q", tour.Name)
+	}
+	form.Set("name", "Old Town")
+
+	// A stored tour cannot be moved to another ID
+	form.Set("id", "other_tour")
+	rr = post(form)
+	if rr.Code != http.StatusUnprocessableEntity {
+		t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusUnprocessableEntity)
+	}
+	if !strings.Contains(rr.Body.String(), `id="error-id"`) || !strings.Contains(rr.Body.String(), "cannot be changed") {
+		t.Errorf("fixed ID not shown on the id field: %s", rr.Body.String())
+	}
+	if tour.ID != "test_tour" {
+		t.Errorf("tour ID changed to %s", tour.ID)
+	}
+	form.Set("id", "test_tour")
+
+	// Errors of message media rows are shown below the message's media
+	form.Set("farewell.text", "Bye")
+	form.Set("farewell.media_files[1718000000000].id", "media-new")
+	form.Set("farewell.media_files[1718000000000].type", "image")
+	form.Set("farewell.media_files[1718000000000].uri", "not a url")
+	rr = post(form)
+	want := `<span id="error-farewell-media_files" class="field-error" hx-swap-oob="true">uri: `
+	if rr.Code != http.StatusUnprocessableEntity || !strings.Contains(rr.Body.String(), want) {
+		t.Errorf("expected %q, got %v:\n%s", want, rr.Code, rr.Body)
+	}
+}
+
+func TestFieldErrorID(t *testing.T) {
+	tests := []struct {
+		form, field, want string
+	}{
+		{tourForm, "id", "error-id"},
+		{nodeForm, "id", "error-node-id"},
+		{nodeForm, "location.lat", "error-node-location-lat"},
+		{nodeForm, "entry_condition.options[2]", "error-node-entry_condition-options"},
+		{tourForm, "farewell.media_files[0].uri", "error-farewell-media_files"},
+	}
+
+	for _, tt := range tests {
+		if got := fieldErrorID(tt.form, tt.field); got != tt.want {
+			t.Errorf("fieldErrorID(%q, %q) = %q, want %q", tt.form, tt.field, got, tt.want)
+		}
+	}
+}
+
+func TestEditorHandler_TourAudioTime(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	tour := tourService.GetCurrentTour(ctx)
+
+	audioTime := func() string {
+		rr := httptest.NewRecorder()
+		handler.HandleTourAudioTime(rr, httptest.NewRequest("GET", "/tour/audio-time", nil).WithContext(ctx))
+		if rr.Code != http.StatusOK {
+			t.Fatalf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+		}
+		return strings.Join(strings.Fields(rr.Body.String()), " ")
+	}
+
+	if body := audioTime(); strings.Contains(body, "Audio") {
+		t.Errorf("expected nothing for a tour without audio, got %q", body)
+	}
+
+	if err := tourService.SetAudioNarrative(ctx, tour, 1, "http://example.com/1.ogg", 61500*time.Millisecond); err != nil {
+		t.Fatal(err)
+	}
+	if err := tourService.SetAudioNarrative(ctx, tour, 2, "http://example.com/2.ogg", 59*time.Second); err != nil {
+		t.Fatal(err)
+	}
+	if body := audioTime(); !strings.Contains(body, "Audio 2:01 </span>") {
+		t.Errorf("expected the total audio time, got %q", body)
+	}
+
+	file := models.NewMediaFile("audio")
+	file.URI = "http://example.com/linked.mp3"
+	if err := tourService.SaveMediaFile(ctx, tour, 1, &file); err != nil {
+		t.Fatal(err)
+	}
+	if body := audioTime(); !strings.Contains(body, "Audio 2:01+") || !strings.Contains(body, "without 1 file(s)") {
+		t.Errorf("expected audio of unknown duration to be marked, got %q", body)
+	}
+}
@@ -0,0 +1,212 @@
+// internal/handlers/translation_handler.go
+package handlers
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"net/http"
+	"path/filepath"
+	"strconv"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/services"
+)
+
+var (
+	errNodeNotFound        = errors.New("node not found")
+	errLanguageNotDeclared = errors.New("language is not declared in the tour settings")
+)
+
+type translationFieldData struct {
+	Path   string
+	Source string // default language text, shown for reference
+	Value  string
+}
+
+type nodeTranslationData struct {
+	Tour   *models.Tour
+	Node   *models.Node
+	Lang   string
+	Fields []translationFieldData
+}
+
+func newNodeTranslationData(tour *models.Tour, node *models.Node, lang string) nodeTranslationData {
+	data := nodeTranslationData{Tour: tour, Node: node, Lang: lang}
+	for _, field := range node.TextFields() {
+		data.Fields = append(data.Fields, translationFieldData{
+			Path:   field.Path,
+			Source: field.Text.Value,
+			Value:  field.Text.In(lang),
+		})
+	}
+	return data
+}
+
+// HandleNodeTranslations stores the node's text in one of the tour's
+// languages. Form fields are named after the node-relative text paths, e.g.
+// "narrative" or "entry_condition.hints[0]"; an empty value removes the
+// translation.
+func (h *EditorHandler) HandleNodeTranslations(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPut {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	if err := r.ParseForm(); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	nodeID, _ := strconv.Atoi(r.PathValue("id"))
+	lang := r.PathValue("lang")
+	change := fmt.Sprintf("Translated node %d into %s", nodeID, lang)
+	err := h.tourService.EditTour(r.Context(), tour, change, func(tour *models.Tour) error {
+		node := tour.GetNode(nodeID)
+		if node == nil {
+			return errNodeNotFound
+		}
+		if !tour.Settings.HasLanguage(lang) {
+			return errLanguageNotDeclared
+		}
+		for _, field := range node.TextFields() {
+			if _, ok := r.Form[field.Path]; ok {
+				field.Text.Set(lang, r.FormValue(field.Path))
+			}
+		}
+		return nil
+	})
+	if h.renderConflict(w, err) {
+		return
+	}
+	if errors.Is(err, errNodeNotFound) {
+		http.Error(w, "Node not found", http.StatusNotFound)
+		return
+	}
+	if errors.Is(err, errLanguageNotDeclared) {
+		http.Error(w, "Language is not declared in the tour settings", http.StatusBadRequest)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	h.setRevision(w, tour)
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(map[string]string{
+		"message": "Translation saved successfully",
+		"type":    "success",
+	})
+}
+
+func (h *EditorHandler) renderNodeTranslationEditor(w http.ResponseWriter, tour *models.Tour, node *models.Node, lang string) {
+	data := newNodeTranslationData(tour, node, lang)
+	if err := h.templates.ExecuteTemplate(w, "node-translation-editor", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
+
+// translationFileTypes maps exchange formats to their file extension and
+// content type.
+var translationFileTypes = map[string]struct{ ext, contentType string }{
+	services.TranslationFormatXLIFF: {".xlf", "application/xliff+xml"},
+	services.TranslationFormatPO:    {".po", "text/x-gettext-translation"},
+}
+
+// HandleTranslationsExport downloads the tour's text for translation into
+// {lang}, as XLIFF 2.0 (?format=xliff, the default) or gettext PO
+// (?format=po).
+func (h *EditorHandler) HandleTranslationsExport(w http.ResponseWriter, r *http.Request) {
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	format := r.URL.Query().Get("format")
+	if format == "" {
+		format = services.TranslationFormatXLIFF
+	}
+	fileType, ok := translationFileTypes[format]
+	if !ok {
+		http.Error(w, "Unknown translation format", http.StatusBadRequest)
+		return
+	}
+
+	lang := r.PathValue("lang")
+	view := h.tourService.SnapshotTour(tour)
+	data, err := h.tourService.ExportTranslations(view, lang, format)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	w.Header().Set("Content-Type", fileType.contentType)
+	w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", view.ID+"."+lang+fileType.ext))
+	w.Write(data)
+}
+
+type translationReportData struct {
+	Lang   string
+	Report *services.TranslationReport
+}
+
+// HandleTranslationsImport merges an uploaded XLIFF or PO file into the
+// tour's {lang} translations and renders what was applied and what was not.
+// The format is taken from the file extension.
+func (h *EditorHandler) HandleTranslationsImport(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	tour := h.tourService.GetCurrentTour(r.Context())
+	if tour == nil {
+		http.Error(w, "No active tour", http.StatusBadRequest)
+		return
+	}
+
+	file, header, err := r.FormFile("file")
+	if err != nil {
+		http.Error(w, "Invalid file upload", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	var format string
+	switch filepath.Ext(header.Filename) {
+	case ".xlf", ".xliff":
+		format = services.TranslationFormatXLIFF
+	case ".po":
+		format = services.TranslationFormatPO
+	default:
+		http.Error(w, "Unsupported translation file; upload a .xlf or .po file", http.StatusBadRequest)
+		return
+	}
+
+	lang := r.PathValue("lang")
+	report, err := h.tourService.ImportTranslations(r.Context(), tour, lang, format, file)
+	if h.renderConflict(w, err) {
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	h.setRevision(w, tour)
+
+	data := translationReportData{Lang: lang, Report: report}
+	if err := h.templates.ExecuteTemplate(w, "translation-report", data); err != nil {
+		log.Printf("Error executing template: %v", err)
+		http.Error(w, "Internal server error", http.StatusInternalServerError)
+	}
+}
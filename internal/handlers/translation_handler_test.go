@@ -0,0 +1,110 @@
+// internal/handlers/translation_handler_test.go
+package handlers
+
+import (
+	"bytes"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestEditorHandler_NodeTranslations(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	tour := tourService.GetCurrentTour(ctx)
+	tour.Settings.PreferredLanguage = "en"
+	tour.Settings.Languages = []string{"de"}
+
+	// Translation editor shows the default text for reference
+	req := httptest.NewRequest("GET", "/nodes/1/edit?lang=de", nil)
+	req.SetPathValue("id", "1")
+	rr := httptest.NewRecorder()
+	handler.HandleNodeEditor(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("editor returned wrong status code: got %v want %v", rr.Code, http.StatusOK)
+	}
+	if body := rr.Body.String(); !strings.Contains(body, "/nodes/1/translations/de") || !strings.Contains(body, "First") {
+		t.Errorf("expected translation form with source text, got %s", body)
+	}
+
+	// Save
+	form := url.Values{"narrative": {"Erste"}, "short_description": {"Tor"}}
+	req = httptest.NewRequest("PUT", "/nodes/1/translations/de", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", "1")
+	req.SetPathValue("lang", "de")
+	rr = httptest.NewRecorder()
+	handler.HandleNodeTranslations(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("save returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	node := tour.GetNode(1)
+	if node.Narrative.In("de") != "Erste" || node.ShortDesc.In("de") != "Tor" {
+		t.Errorf("translation not saved: %+v %+v", node.ShortDesc, node.Narrative)
+	}
+	if node.Narrative.Value != "First" {
+		t.Errorf("default text changed to %q", node.Narrative.Value)
+	}
+
+	// Undeclared language
+	req = httptest.NewRequest("PUT", "/nodes/1/translations/fr", strings.NewReader(form.Encode()))
+	req.Header.Set("Content-Type", "application/x-www-form-urlencoded")
+	req.SetPathValue("id", "1")
+	req.SetPathValue("lang", "fr")
+	rr = httptest.NewRecorder()
+	handler.HandleNodeTranslations(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusBadRequest {
+		t.Errorf("handler returned wrong status code: got %v want %v", rr.Code, http.StatusBadRequest)
+	}
+}
+
+func TestEditorHandler_TranslationsRoundTrip(t *testing.T) {
+	handler, tourService, ctx := newTestEditor(t)
+	tour := tourService.GetCurrentTour(ctx)
+	tour.Settings.Languages = []string{"de"}
+
+	// Export
+	req := httptest.NewRequest("GET", "/tour/translations/de?format=po", nil)
+	req.SetPathValue("lang", "de")
+	rr := httptest.NewRecorder()
+	handler.HandleTranslationsExport(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("export returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if got := rr.Header().Get("Content-Disposition"); got != `attachment; filename="test_tour.de.po"` {
+		t.Errorf("unexpected Content-Disposition %q", got)
+	}
+
+	// Fill in one translation and import it back
+	po := strings.Replace(rr.Body.String(),
+		"msgctxt \"nodes[id=1].narrative\"\nmsgid \"First\"\nmsgstr \"\"",
+		"msgctxt \"nodes[id=1].narrative\"\nmsgid \"First\"\nmsgstr \"Erste\"", 1)
+
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	part, _ := writer.CreateFormFile("file", "test_tour.de.po")
+	part.Write([]byte(po))
+	writer.Close()
+
+	req = httptest.NewRequest("POST", "/tour/translations/de", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.SetPathValue("lang", "de")
+	rr = httptest.NewRecorder()
+	handler.HandleTranslationsImport(rr, req.WithContext(ctx))
+
+	if rr.Code != http.StatusOK {
+		t.Fatalf("import returned wrong status code: got %v want %v: %s", rr.Code, http.StatusOK, rr.Body)
+	}
+	if !strings.Contains(rr.Body.String(), "1 de translations imported") {
+		t.Errorf("unexpected report %s", rr.Body)
+	}
+	if got := tour.GetNode(1).Narrative.In("de"); got != "Erste" {
+		t.Errorf("expected imported translation, got %q", got)
+	}
+}
@@ -11,10 +11,19 @@ import (
 	"github.com/golang-jwt/jwt/v5"
 )
 
+// contextKey is the type of the request context keys set by this package,
+// so they cannot collide with keys of other packages.
 type contextKey string
 
+// UserIDKey holds the subject of the authenticated user's token, a string.
 const UserIDKey contextKey = "userID"
 
+// UserID returns the authenticated user of ctx, or "" if there is none.
+func UserID(ctx context.Context) string {
+	id, _ := ctx.Value(UserIDKey).(string)
+	return id
+}
+
 func RequireAuth(secretKey string) func(http.Handler) http.Handler {
 	return func(next http.Handler) http.Handler {
 		return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
@@ -61,11 +70,17 @@ func RequireAuth(secretKey string) func(http.Handler) http.Handler {
 				http.Redirect(w, r, "/login", http.StatusSeeOther)
 				return
 			}
+			subject, err := claims.GetSubject()
+			if err != nil || subject == "" {
+				log.Printf("Token without subject")
+				http.Redirect(w, r, "/login", http.StatusSeeOther)
+				return
+			}
 
 			// Log claims for debugging
 			log.Printf("Token claims: %+v", claims)
 
-			ctx := context.WithValue(r.Context(), UserIDKey, claims["sub"])
+			ctx := context.WithValue(r.Context(), UserIDKey, subject)
 			next.ServeHTTP(w, r.WithContext(ctx))
 		})
 	}
@@ -0,0 +1,111 @@
+// internal/mocks/s3_fake.go
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// FakeS3 is an in-memory S3 that keeps objects per bucket, for testing code
+// against S3 without a server. Listings are paginated after MaxKeys
+// objects, 1000 by default, like S3's.
+type FakeS3 struct {
+	mu      sync.Mutex
+	objects map[string]map[string][]byte // bucket -> key -> content
+}
+
+func NewFakeS3() *FakeS3 {
+	return &FakeS3{objects: make(map[string]map[string][]byte)}
+}
+
+func (f *FakeS3) GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.objects[aws.ToString(params.Bucket)][aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NoSuchKey{Message: aws.String("The specified key does not exist.")}
+	}
+	return &s3.GetObjectOutput{
+		Body:          io.NopCloser(bytes.NewReader(data)),
+		ContentLength: aws.Int64(int64(len(data))),
+	}, nil
+}
+
+func (f *FakeS3) HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	data, ok := f.objects[aws.ToString(params.Bucket)][aws.ToString(params.Key)]
+	if !ok {
+		return nil, &types.NotFound{Message: aws.String("Not Found")}
+	}
+	return &s3.HeadObjectOutput{ContentLength: aws.Int64(int64(len(data)))}, nil
+}
+
+func (f *FakeS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	var data []byte
+	if params.Body != nil {
+		var err error
+		if data, err = io.ReadAll(params.Body); err != nil {
+			return nil, err
+		}
+	}
+
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	bucket := aws.ToString(params.Bucket)
+	if f.objects[bucket] == nil {
+		f.objects[bucket] = make(map[string][]byte)
+	}
+	f.objects[bucket][aws.ToString(params.Key)] = data
+	return &s3.PutObjectOutput{}, nil
+}
+
+func (f *FakeS3) DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	delete(f.objects[aws.ToString(params.Bucket)], aws.ToString(params.Key))
+	return &s3.DeleteObjectOutput{}, nil
+}
+
+func (f *FakeS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	f.mu.Lock()
+	defer f.mu.Unlock()
+
+	var keys []string
+	for key := range f.objects[aws.ToString(params.Bucket)] {
+		if strings.HasPrefix(key, aws.ToString(params.Prefix)) && key > aws.ToString(params.ContinuationToken) {
+			keys = append(keys, key)
+		}
+	}
+	sort.Strings(keys)
+
+	limit := int(aws.ToInt32(params.MaxKeys))
+	if limit <= 0 {
+		limit = 1000
+	}
+	out := &s3.ListObjectsV2Output{IsTruncated: aws.Bool(len(keys) > limit)}
+	if len(keys) > limit {
+		keys = keys[:limit]
+		out.NextContinuationToken = aws.String(keys[limit-1])
+	}
+	for _, key := range keys {
+		out.Contents = append(out.Contents, types.Object{
+			Key:  aws.String(key),
+			Size: aws.Int64(int64(len(f.objects[aws.ToString(params.Bucket)][key]))),
+		})
+	}
+	out.KeyCount = aws.Int32(int32(len(out.Contents)))
+	return out, nil
+}
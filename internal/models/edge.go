@@ -0,0 +1,46 @@
+// internal/models/edge.go
+package models
+
+// IndexedEdge pairs an edge with its position in Tour.Edges. Edges have no
+// identifier of their own, so the editor addresses them by index.
+type IndexedEdge struct {
+	Index int
+	Edge  Edge
+}
+
+func NewEdge(from, to int) *Edge {
+	return &Edge{
+		From:       from,
+		To:         to,
+		MediaFiles: make([]MediaFile, 0),
+	}
+}
+
+func (t *Tour) GetEdge(index int) *Edge {
+	if index < 0 || index >= len(t.Edges) {
+		return nil
+	}
+	return &t.Edges[index]
+}
+
+// OutgoingEdges returns the edges leaving the given node, in tour order.
+func (t *Tour) OutgoingEdges(nodeID int) []IndexedEdge {
+	var edges []IndexedEdge
+	for i, edge := range t.Edges {
+		if edge.From == nodeID {
+			edges = append(edges, IndexedEdge{Index: i, Edge: edge})
+		}
+	}
+	return edges
+}
+
+// IncomingEdges returns the edges arriving at the given node, in tour order.
+func (t *Tour) IncomingEdges(nodeID int) []IndexedEdge {
+	var edges []IndexedEdge
+	for i, edge := range t.Edges {
+		if edge.To == nodeID {
+			edges = append(edges, IndexedEdge{Index: i, Edge: edge})
+		}
+	}
+	return edges
+}
@@ -0,0 +1,104 @@
+// internal/models/media.go
+package models
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"time"
+)
+
+// NewMediaFile returns a media file of the given type with a freshly
+// generated ID.
+func NewMediaFile(mediaType string) MediaFile {
+	return MediaFile{ID: generateMediaID(), Type: mediaType}
+}
+
+func generateMediaID() string {
+	b := make([]byte, 6)
+	rand.Read(b)
+	return "media-" + hex.EncodeToString(b)
+}
+
+// AllMediaFiles returns every media file of the tour: those of the
+// milestone and farewell messages, nodes and edges, in document order.
+func (t *Tour) AllMediaFiles() []*MediaFile {
+	var files []*MediaFile
+	add := func(list []MediaFile) {
+		for i := range list {
+			files = append(files, &list[i])
+		}
+	}
+	for _, msg := range []*Message{t.Milestones.At25, t.Milestones.At50, t.Milestones.At75, t.Farewell} {
+		if msg != nil {
+			add(msg.MediaFiles)
+		}
+	}
+	for i := range t.Nodes {
+		add(t.Nodes[i].MediaFiles)
+	}
+	for i := range t.Edges {
+		add(t.Edges[i].MediaFiles)
+	}
+	return files
+}
+
+// AssignMediaIDs gives every media file without an ID a fresh one, so tours
+// written before media files had IDs can still be loaded.
+func (t *Tour) AssignMediaIDs() {
+	for _, file := range t.AllMediaFiles() {
+		if file.ID == "" {
+			file.ID = generateMediaID()
+		}
+	}
+}
+
+// GetMediaFile returns the node's media file with the given ID.
+func (n *Node) GetMediaFile(id string) *MediaFile {
+	for i := range n.MediaFiles {
+		if n.MediaFiles[i].ID == id {
+			return &n.MediaFiles[i]
+		}
+	}
+	return nil
+}
+
+// ReassignDuplicateMediaIDs gives a fresh ID to every media file whose ID
+// an earlier file of the tour already uses, e.g. after merging two tours.
+func (t *Tour) ReassignDuplicateMediaIDs() {
+	seen := make(map[string]bool)
+	for _, file := range t.AllMediaFiles() {
+		if file.ID != "" && seen[file.ID] {
+			file.ID = generateMediaID()
+		}
+		seen[file.ID] = true
+	}
+}
+
+// Seconds converts a duration to the seconds media durations are stored in.
+func Seconds(d time.Duration) float64 {
+	return d.Round(time.Millisecond).Seconds()
+}
+
+// AudioDuration returns the total playing time of the tour's audio: the
+// audio narratives of its nodes and its audio media files. unknown counts
+// the audio whose duration was not probed, which total leaves out.
+func (t *Tour) AudioDuration() (total time.Duration, unknown int) {
+	add := func(seconds float64) {
+		if seconds <= 0 {
+			unknown++
+			return
+		}
+		total += time.Duration(seconds * float64(time.Second))
+	}
+	for _, node := range t.Nodes {
+		if node.AudioNarrative != "" {
+			add(node.AudioNarrativeDuration)
+		}
+	}
+	for _, file := range t.AllMediaFiles() {
+		if file.Type == "audio" {
+			add(file.Duration)
+		}
+	}
+	return total.Round(time.Millisecond), unknown
+}
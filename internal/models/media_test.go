@@ -0,0 +1,95 @@
+// internal/models/media_test.go
+package models
+
+import (
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestTour_MediaIDs(t *testing.T) {
+	validate := setupValidator(t)
+
+	tour := &Tour{
+		Nodes: []Node{
+			{ID: 1, MediaFiles: []MediaFile{{URI: "http://example.com/a.jpg"}, NewMediaFile("image")}},
+		},
+		Edges: []Edge{
+			{From: 1, To: 2, MediaFiles: []MediaFile{{URI: "http://example.com/b.jpg"}}},
+		},
+		Farewell: &Message{Text: NewText("Bye"), MediaFiles: []MediaFile{{URI: "http://example.com/c.jpg"}}},
+	}
+
+	kept := tour.Nodes[0].MediaFiles[1].ID
+	tour.AssignMediaIDs()
+
+	seen := make(map[string]bool)
+	for _, file := range tour.AllMediaFiles() {
+		if !strings.HasPrefix(file.ID, "media-") || seen[file.ID] {
+			t.Errorf("Expected unique generated ID, got %q", file.ID)
+		}
+		seen[file.ID] = true
+	}
+	if len(seen) != 4 {
+		t.Errorf("Expected 4 media files, got %d", len(seen))
+	}
+	if tour.Nodes[0].MediaFiles[1].ID != kept {
+		t.Errorf("Existing ID %q was replaced by %q", kept, tour.Nodes[0].MediaFiles[1].ID)
+	}
+	if tour.Nodes[0].GetMediaFile(kept) == nil {
+		t.Errorf("GetMediaFile(%q) = nil", kept)
+	}
+
+	tour.Edges[0].MediaFiles[0].ID = kept
+	err := validate.Struct(tour)
+	if err == nil || !strings.Contains(err.Error(), "unique_media_id") {
+		t.Errorf("Expected unique_media_id error, got %v", err)
+	}
+}
+
+func TestTour_AudioDuration(t *testing.T) {
+	tests := []struct {
+		name        string
+		tour        *Tour
+		wantTotal   time.Duration
+		wantUnknown int
+	}{
+		{
+			name: "no audio",
+			tour: &Tour{Nodes: []Node{{ID: 1, MediaFiles: []MediaFile{{Type: "image", Duration: 5}}}}},
+		},
+		{
+			name: "narratives and audio files",
+			tour: &Tour{
+				Nodes: []Node{
+					{ID: 1, AudioNarrative: "http://example.com/1.ogg", AudioNarrativeDuration: 61.5},
+					{ID: 2, AudioNarrativeDuration: 30}, // narrative removed
+					{ID: 3, MediaFiles: []MediaFile{{Type: "audio", Duration: 10.25}}},
+				},
+				Edges:    []Edge{{From: 1, To: 3, MediaFiles: []MediaFile{{Type: "audio", Duration: 8}}}},
+				Farewell: &Message{MediaFiles: []MediaFile{{Type: "audio", Duration: 0.25}}},
+			},
+			wantTotal: 80 * time.Second,
+		},
+		{
+			name: "unprobed audio",
+			tour: &Tour{
+				Nodes: []Node{
+					{ID: 1, AudioNarrative: "http://example.com/1.ogg"},
+					{ID: 2, MediaFiles: []MediaFile{{Type: "audio", Duration: 12}, {Type: "audio"}}},
+				},
+			},
+			wantTotal:   12 * time.Second,
+			wantUnknown: 2,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			total, unknown := tt.tour.AudioDuration()
+			if total != tt.wantTotal || unknown != tt.wantUnknown {
+				t.Errorf("AudioDuration() = %v, %d; want %v, %d", total, unknown, tt.wantTotal, tt.wantUnknown)
+			}
+		})
+	}
+}
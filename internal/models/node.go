@@ -1,29 +1,15 @@
 package models
 
-import (
-	"time"
-)
-
+// NewNode returns an empty node without entry or exit conditions. Its ID is
+// left zero; TourService.AllocateNodeID hands out IDs that are unique in a
+// tour.
 func NewNode() *Node {
 	return &Node{
-		ID: int(time.Now().UnixNano() % 10000), // Simple ID generation for demo
 		Location: Location{
 			Lat: 0,
 			Lon: 0,
 		},
 		MediaFiles: make([]MediaFile, 0),
-		EntryCondition: &Condition{
-			Type:    "q&a",
-			Strict:  false,
-			Options: make([]string, 0),
-			Hints:   make([]string, 0),
-		},
-		ExitCondition: &Condition{
-			Type:    "q&a",
-			Strict:  false,
-			Options: make([]string, 0),
-			Hints:   make([]string, 0),
-		},
 	}
 }
 
@@ -35,3 +21,32 @@ func (t *Tour) GetNode(id int) *Node {
 	}
 	return nil
 }
+
+// StartNodeID returns the node designated as the start of the tour. If
+// several nodes are marked, the first one wins.
+func (t *Tour) StartNodeID() (int, bool) {
+	for _, node := range t.Nodes {
+		if node.Start {
+			return node.ID, true
+		}
+	}
+	return 0, false
+}
+
+// IsFinishNode reports whether the tour may end at the given node.
+func (t *Tour) IsFinishNode(id int) bool {
+	node := t.GetNode(id)
+	return node != nil && node.Finish
+}
+
+// SetStartNode marks the given node as the start and clears the flag on all
+// other nodes.
+func (t *Tour) SetStartNode(id int) bool {
+	if t.GetNode(id) == nil {
+		return false
+	}
+	for i := range t.Nodes {
+		t.Nodes[i].Start = t.Nodes[i].ID == id
+	}
+	return true
+}
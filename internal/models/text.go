@@ -0,0 +1,248 @@
+// internal/models/text.go
+package models
+
+import (
+	"encoding/json"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Text is a localizable string. Value holds the text in the tour's default
+// language; Translations maps further BCP-47 language tags to their text.
+//
+// A Text without translations is written as a plain string, so tours that
+// are not translated keep the single-language schema. Translated text is
+// written as a mapping:
+//
+//	narrative:
+//	  text: Welcome to the old town
+//	  translations:
+//	    de: Willkommen in der Altstadt
+type Text struct {
+	Value        string
+	Translations map[string]string
+}
+
+type textDocument struct {
+	Text         string            `yaml:"text" json:"text"`
+	Translations map[string]string `yaml:"translations,omitempty" json:"translations,omitempty"`
+}
+
+func NewText(value string) Text {
+	return Text{Value: value}
+}
+
+func (t Text) String() string {
+	return t.Value
+}
+
+func (t Text) IsEmpty() bool {
+	return t.Value == "" && len(t.Translations) == 0
+}
+
+// In returns the translation for lang, or the default value when lang is
+// empty. It does not fall back to the default language.
+func (t Text) In(lang string) string {
+	if lang == "" {
+		return t.Value
+	}
+	return t.Translations[lang]
+}
+
+// Get returns the translation for lang, falling back to the default value.
+func (t Text) Get(lang string) string {
+	if value, ok := t.Translations[lang]; ok && value != "" {
+		return value
+	}
+	return t.Value
+}
+
+func (t Text) Has(lang string) bool {
+	return t.Translations[lang] != ""
+}
+
+// Set stores value for lang. An empty lang sets the default value; an empty
+// value removes the translation.
+func (t *Text) Set(lang, value string) {
+	if lang == "" {
+		t.Value = value
+		return
+	}
+	if value == "" {
+		delete(t.Translations, lang)
+		if len(t.Translations) == 0 {
+			t.Translations = nil
+		}
+		return
+	}
+	if t.Translations == nil {
+		t.Translations = make(map[string]string)
+	}
+	t.Translations[lang] = value
+}
+
+func (t Text) MarshalYAML() (interface{}, error) {
+	if len(t.Translations) == 0 {
+		return t.Value, nil
+	}
+	return textDocument{Text: t.Value, Translations: t.Translations}, nil
+}
+
+func (t *Text) UnmarshalYAML(value *yaml.Node) error {
+	switch value.Kind {
+	case yaml.ScalarNode:
+		t.Translations = nil
+		return value.Decode(&t.Value)
+	case yaml.MappingNode:
+		var doc textDocument
+		if err := value.Decode(&doc); err != nil {
+			return err
+		}
+		t.Value = doc.Text
+		t.Translations = doc.Translations
+		return nil
+	default:
+		return fmt.Errorf("line %d: text must be a string or a mapping", value.Line)
+	}
+}
+
+func (t Text) MarshalJSON() ([]byte, error) {
+	if len(t.Translations) == 0 {
+		return json.Marshal(t.Value)
+	}
+	return json.Marshal(textDocument{Text: t.Value, Translations: t.Translations})
+}
+
+func (t *Text) UnmarshalJSON(data []byte) error {
+	if len(data) > 0 && data[0] == '"' {
+		t.Translations = nil
+		return json.Unmarshal(data, &t.Value)
+	}
+	var doc textDocument
+	if err := json.Unmarshal(data, &doc); err != nil {
+		return err
+	}
+	t.Value = doc.Text
+	t.Translations = doc.Translations
+	return nil
+}
+
+// UnmarshalText sets the default value from a form field, keeping the
+// translations.
+func (t *Text) UnmarshalText(data []byte) error {
+	t.Set("", string(data))
+	return nil
+}
+
+// TextField is a localizable field together with its YAML path, for example
+// "nodes[2].narrative".
+type TextField struct {
+	Path string
+	Text *Text
+}
+
+// TextFields returns the node's localizable fields that have a default
+// value, with paths relative to the node.
+func (n *Node) TextFields() []TextField {
+	return n.textFields(false)
+}
+
+func (n *Node) textFields(byKey bool) []TextField {
+	var fields []TextField
+	fields = appendText(fields, "short_description", &n.ShortDesc)
+	fields = appendText(fields, "narrative", &n.Narrative)
+	fields = appendMediaTexts(fields, "media_files", n.MediaFiles, byKey)
+	fields = appendConditionTexts(fields, "entry_condition", n.EntryCondition)
+	fields = appendConditionTexts(fields, "exit_condition", n.ExitCondition)
+	return fields
+}
+
+// TextFields returns every localizable field of the tour that has a default
+// value, in document order.
+func (t *Tour) TextFields() []TextField {
+	return t.textFields(false)
+}
+
+// KeyedTextFields returns the fields of TextFields with paths that stay the
+// same when nodes, edges or media files are added, removed or reordered:
+// nodes and media files are picked by ID and edges by the nodes they join,
+// as in "nodes[id=3].media_files[id=media-1a2b].narrative" or
+// "edges[from=1,to=3].instructions".
+func (t *Tour) KeyedTextFields() []TextField {
+	return t.textFields(true)
+}
+
+func (t *Tour) textFields(byKey bool) []TextField {
+	var fields []TextField
+	fields = appendText(fields, "name", &t.Name)
+	fields = appendText(fields, "description", &t.Description)
+	fields = appendMessageTexts(fields, "milestones.at_25", t.Milestones.At25, byKey)
+	fields = appendMessageTexts(fields, "milestones.at_50", t.Milestones.At50, byKey)
+	fields = appendMessageTexts(fields, "milestones.at_75", t.Milestones.At75, byKey)
+	fields = appendMessageTexts(fields, "farewell", t.Farewell, byKey)
+	for i := range t.Nodes {
+		prefix := fmt.Sprintf("nodes[%d].", i)
+		if byKey {
+			prefix = fmt.Sprintf("nodes[id=%d].", t.Nodes[i].ID)
+		}
+		for _, field := range t.Nodes[i].textFields(byKey) {
+			fields = append(fields, TextField{Path: prefix + field.Path, Text: field.Text})
+		}
+	}
+	for i := range t.Edges {
+		prefix := fmt.Sprintf("edges[%d].", i)
+		if byKey {
+			prefix = fmt.Sprintf("edges[from=%d,to=%d].", t.Edges[i].From, t.Edges[i].To)
+		}
+		fields = appendMediaTexts(fields, prefix+"media_files", t.Edges[i].MediaFiles, byKey)
+		fields = appendConditionTexts(fields, prefix+"condition", t.Edges[i].Condition)
+		fields = appendText(fields, prefix+"instructions", &t.Edges[i].Instructions)
+	}
+	return fields
+}
+
+func appendText(fields []TextField, path string, text *Text) []TextField {
+	if text.Value == "" {
+		return fields
+	}
+	return append(fields, TextField{Path: path, Text: text})
+}
+
+func appendTexts(fields []TextField, prefix string, texts []Text) []TextField {
+	for i := range texts {
+		fields = appendText(fields, fmt.Sprintf("%s[%d]", prefix, i), &texts[i])
+	}
+	return fields
+}
+
+// appendMediaTexts appends the narratives of files, picked by position or,
+// if byKey is set, by ID where they have one.
+func appendMediaTexts(fields []TextField, prefix string, files []MediaFile, byKey bool) []TextField {
+	for i := range files {
+		path := fmt.Sprintf("%s[%d].narrative", prefix, i)
+		if byKey && files[i].ID != "" {
+			path = fmt.Sprintf("%s[id=%s].narrative", prefix, files[i].ID)
+		}
+		fields = appendText(fields, path, &files[i].Narrative)
+	}
+	return fields
+}
+
+func appendMessageTexts(fields []TextField, prefix string, m *Message, byKey bool) []TextField {
+	if m == nil {
+		return fields
+	}
+	fields = appendText(fields, prefix+".text", &m.Text)
+	return appendMediaTexts(fields, prefix+".media_files", m.MediaFiles, byKey)
+}
+
+func appendConditionTexts(fields []TextField, prefix string, c *Condition) []TextField {
+	if c == nil {
+		return fields
+	}
+	fields = appendText(fields, prefix+".question", &c.Question)
+	fields = appendText(fields, prefix+".correct_answer", &c.CorrectAnswer)
+	fields = appendTexts(fields, prefix+".hints", c.Hints)
+	return appendTexts(fields, prefix+".options", c.Options)
+}
@@ -0,0 +1,106 @@
+// internal/models/text_test.go
+package models
+
+import (
+	"strings"
+	"testing"
+
+	"gopkg.in/yaml.v3"
+)
+
+func TestText_YAML(t *testing.T) {
+	tests := []struct {
+		name string
+		text Text
+		want string
+	}{
+		{
+			name: "plain string",
+			text: NewText("Welcome"),
+			want: "Welcome\n",
+		},
+		{
+			name: "with translations",
+			text: Text{Value: "Welcome", Translations: map[string]string{"de": "Willkommen"}},
+			want: "text: Welcome\ntranslations:\n    de: Willkommen\n",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			data, err := yaml.Marshal(tt.text)
+			if err != nil {
+				t.Fatalf("Marshal() error = %v", err)
+			}
+			if string(data) != tt.want {
+				t.Errorf("Marshal() = %q, want %q", data, tt.want)
+			}
+
+			var parsed Text
+			if err := yaml.Unmarshal(data, &parsed); err != nil {
+				t.Fatalf("Unmarshal() error = %v", err)
+			}
+			if parsed.Value != tt.text.Value || len(parsed.Translations) != len(tt.text.Translations) {
+				t.Errorf("Unmarshal() = %+v, want %+v", parsed, tt.text)
+			}
+			for lang, value := range tt.text.Translations {
+				if parsed.In(lang) != value {
+					t.Errorf("In(%q) = %q, want %q", lang, parsed.In(lang), value)
+				}
+			}
+		})
+	}
+
+	var invalid Text
+	if err := yaml.Unmarshal([]byte("[a, b]"), &invalid); err == nil {
+		t.Error("Expected error unmarshalling a sequence, got nil")
+	}
+}
+
+func TestText_SetAndGet(t *testing.T) {
+	text := NewText("Hello")
+	text.Set("fr", "Bonjour")
+
+	if got := text.Get("fr"); got != "Bonjour" {
+		t.Errorf("Get(fr) = %q, want Bonjour", got)
+	}
+	if got := text.Get("de"); got != "Hello" {
+		t.Errorf("Get(de) = %q, want fallback Hello", got)
+	}
+	if text.Has("de") {
+		t.Error("Has(de) = true, want false")
+	}
+
+	text.Set("", "Hi")
+	text.Set("fr", "")
+	if text.Value != "Hi" || text.Translations != nil {
+		t.Errorf("Expected default text only, got %+v", text)
+	}
+}
+
+func TestTour_TextFields(t *testing.T) {
+	node := NewNode()
+	node.ShortDesc = NewText("Gate")
+	node.Narrative = NewText("The old gate")
+	node.EntryCondition = &Condition{
+		Type:     "q&a",
+		Question: NewText("Which year?"),
+		Hints:    []Text{NewText("Look up")},
+	}
+
+	tour := &Tour{
+		Nodes: []Node{*node},
+		Edges: []Edge{{From: 1, To: 2, Instructions: NewText("Turn left")}},
+	}
+
+	var paths []string
+	for _, field := range tour.TextFields() {
+		paths = append(paths, field.Path)
+	}
+
+	want := "nodes[0].short_description nodes[0].narrative nodes[0].entry_condition.question " +
+		"nodes[0].entry_condition.hints[0] edges[0].instructions"
+	if got := strings.Join(paths, " "); got != want {
+		t.Errorf("TextFields() paths = %q, want %q", got, want)
+	}
+}
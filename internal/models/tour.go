@@ -2,21 +2,92 @@
 package models
 
 import (
+	"crypto/rand"
+	"encoding/hex"
 	"time"
 )
 
 type Tour struct {
 	ID          string    `yaml:"id" validate:"required"`
-	Name        string    `yaml:"name" validate:"required"`
-	Description string    `yaml:"description" validate:"required"`
+	Name        Text      `yaml:"name" validate:"required"`
+	Description Text      `yaml:"description" validate:"required"`
 	StartDate   time.Time `yaml:"start_date" validate:"required"`
 	EndDate     time.Time `yaml:"end_date" validate:"required,gtfield=StartDate"`
 	Version     string    `yaml:"version" validate:"required"`
 	HeroImage   string    `yaml:"hero_image" validate:"required,url"`
 	Author      Author    `yaml:"author" validate:"required"`
 	Price       int       `yaml:"price" validate:"required,min=0"`
-	Nodes       []Node    `yaml:"nodes" validate:"required,dive"`
-	Edges       []Edge    `yaml:"edges" validate:"required,dive"`
+	Nodes       []Node    `yaml:"nodes" validate:"required,dive" form:"-"` // edited through the node endpoints
+	Edges       []Edge    `yaml:"edges" validate:"required,dive" form:"-"` // edited through the edge endpoints
+
+	Settings   Settings   `yaml:"settings,omitempty"`
+	Milestones Milestones `yaml:"milestones,omitempty"`
+	Farewell   *Message   `yaml:"farewell,omitempty" validate:"omitempty"`
+
+	Record TourRecord `yaml:"-"` // kept by the editor, not exported
+}
+
+// TourRecord is what the editor keeps about a tour besides its content. It
+// is stored with the tour but is not part of the tour format.
+type TourRecord struct {
+	Owner    string    `yaml:"owner,omitempty"` // user who created the tour; empty for shared tours
+	Modified time.Time `yaml:"modified"`
+	Archived bool      `yaml:"archived,omitempty"`
+	Revision int       `yaml:"revision"` // number of saved changes
+}
+
+// Stored reports whether the editor has stored the tour, which sets its
+// modification time. A stored tour keeps its ID.
+func (t *Tour) Stored() bool {
+	return !t.Record.Modified.IsZero()
+}
+
+// Media caching modes for Settings.MediaCaching.
+const (
+	CacheNone         = "none"          // fetch media when it is sent
+	CachePrefetchAll  = "prefetch_all"  // download all media when the tour starts
+	CachePrefetchNext = "prefetch_next" // download the next node's media in advance
+)
+
+// Per-file overrides for MediaFile.Cache. An empty value inherits the tour
+// setting.
+const (
+	MediaCachePrefetch = "prefetch"
+	MediaCacheNever    = "never"
+)
+
+// Settings holds tour-wide client behaviour. PreferredLanguage is the
+// language the tour is written in; Languages lists the additional languages
+// its text is translated into.
+type Settings struct {
+	MediaCaching      string   `yaml:"media_caching,omitempty" validate:"omitempty,oneof=none prefetch_all prefetch_next"`
+	PreferredLanguage string   `yaml:"preferred_language,omitempty" validate:"omitempty,bcp47_language_tag"`
+	Languages         []string `yaml:"languages,omitempty" validate:"dive,bcp47_language_tag"`
+}
+
+// HasLanguage reports whether lang is one of the translation languages.
+func (s Settings) HasLanguage(lang string) bool {
+	for _, l := range s.Languages {
+		if l == lang {
+			return true
+		}
+	}
+	return false
+}
+
+// Message is a text sent to the visitor outside of a node, optionally
+// accompanied by media.
+type Message struct {
+	Text       Text        `yaml:"text" validate:"required"`
+	MediaFiles []MediaFile `yaml:"media_files,omitempty" validate:"dive"`
+}
+
+// Milestones are optional messages sent when the visitor has completed a
+// quarter, half and three quarters of the tour.
+type Milestones struct {
+	At25 *Message `yaml:"at_25,omitempty" validate:"omitempty"`
+	At50 *Message `yaml:"at_50,omitempty" validate:"omitempty"`
+	At75 *Message `yaml:"at_75,omitempty" validate:"omitempty"`
 }
 
 type Author struct {
@@ -25,14 +96,17 @@ type Author struct {
 }
 
 type Node struct {
-	ID             int         `yaml:"id" validate:"required"`
-	Location       Location    `yaml:"location" validate:"required"`
-	ShortDesc      string      `yaml:"short_description" validate:"required"`
-	Narrative      string      `yaml:"narrative" validate:"required"`
-	AudioNarrative string      `yaml:"audio_narrative" validate:"omitempty,url"`
-	MediaFiles     []MediaFile `yaml:"media_files" validate:"dive"`
-	EntryCondition *Condition  `yaml:"entry_condition" validate:"omitempty"`
-	ExitCondition  *Condition  `yaml:"exit_condition" validate:"omitempty"`
+	ID                     int         `yaml:"id" validate:"required"`
+	Location               Location    `yaml:"location" validate:"required"`
+	ShortDesc              Text        `yaml:"short_description" validate:"required"`
+	Narrative              Text        `yaml:"narrative" validate:"required"`
+	AudioNarrative         string      `yaml:"audio_narrative" validate:"omitempty,url" form:"-"`
+	AudioNarrativeDuration float64     `yaml:"audio_narrative_duration,omitempty" validate:"min=0" form:"-"` // seconds, probed on upload
+	MediaFiles             []MediaFile `yaml:"media_files" validate:"dive" form:"-"`                         // edited through the node media endpoints
+	EntryCondition         *Condition  `yaml:"entry_condition" validate:"omitempty"`
+	ExitCondition          *Condition  `yaml:"exit_condition" validate:"omitempty"`
+	Start                  bool        `yaml:"start,omitempty"`  // the tour begins here; exactly one per tour
+	Finish                 bool        `yaml:"finish,omitempty"` // the tour may end here
 }
 
 type Location struct {
@@ -41,20 +115,27 @@ type Location struct {
 }
 
 type MediaFile struct {
+	ID        string `yaml:"id" validate:"required" form:"id,key"` // unique across the tour
 	Type      string `yaml:"type" validate:"required,oneof=image audio video"`
-	URI       string `yaml:"uri" validate:"required,url"`
+	URI       string `yaml:"uri" validate:"required,url" form:"uri,required"`
 	SendDelay int    `yaml:"send_delay" validate:"min=0"`
-	Narrative string `yaml:"narrative" validate:"omitempty"`
+	Narrative Text   `yaml:"narrative" validate:"omitempty"`
+	Cache     string `yaml:"cache,omitempty" validate:"omitempty,oneof=prefetch never"`
+
+	// Probed from uploaded audio; zero if unknown
+	Duration   float64 `yaml:"duration,omitempty" validate:"min=0" form:"-"`    // seconds
+	SampleRate int     `yaml:"sample_rate,omitempty" validate:"min=0" form:"-"` // Hz
+	Codec      string  `yaml:"codec,omitempty" form:"-"`
 }
 
 type Condition struct {
-	Type          string   `yaml:"type" validate:"required,oneof=quiz q&a puzzle"`
-	Strict        bool     `yaml:"strict"`
-	Question      string   `yaml:"question" validate:"required"`
-	CorrectAnswer string   `yaml:"correct_answer" validate:"required"`
-	Hints         []string `yaml:"hints" validate:"omitempty"`
-	Options       []string `yaml:"options" validate:"required_if=Type quiz,min=2"`
-	MediaLink     string   `yaml:"media_link" validate:"omitempty,required_if=Type puzzle,url"`
+	Type          string `yaml:"type" validate:"required,oneof=quiz q&a puzzle"`
+	Strict        bool   `yaml:"strict"`
+	Question      Text   `yaml:"question" validate:"required"`
+	CorrectAnswer Text   `yaml:"correct_answer" validate:"required"`
+	Hints         []Text `yaml:"hints" validate:"omitempty"`
+	Options       []Text `yaml:"options" validate:"required_if=Type quiz"`
+	MediaLink     string `yaml:"media_link" validate:"omitempty,required_if=Type puzzle,url"`
 }
 
 type Edge struct {
@@ -62,8 +143,9 @@ type Edge struct {
 	To           int         `yaml:"to" validate:"required,nefield=From"`
 	MediaFiles   []MediaFile `yaml:"media_files" validate:"dive"`
 	Condition    *Condition  `yaml:"condition" validate:"omitempty"`
-	Instructions string      `yaml:"instructions" validate:"omitempty"`
+	Instructions Text        `yaml:"instructions" validate:"omitempty"`
 	Silent       bool        `yaml:"silent"`
+	Loop         bool        `yaml:"loop,omitempty"` // intentionally returns to an earlier node
 }
 
 func NewTour() *Tour {
@@ -72,11 +154,18 @@ func NewTour() *Tour {
 		Version:   "1.0",
 		StartDate: time.Now(),
 		EndDate:   time.Now().AddDate(0, 3, 0), // Default 3 months duration
-		Nodes:     make([]Node, 0),
-		Edges:     make([]Edge, 0),
+		Settings: Settings{
+			MediaCaching: CachePrefetchNext,
+		},
+		Nodes: make([]Node, 0),
+		Edges: make([]Edge, 0),
 	}
 }
 
+// generateID returns the creation time with a random suffix, so tours
+// created in the same second get different IDs.
 func generateID() string {
-	return "tour-" + time.Now().Format("20060102150405")
+	b := make([]byte, 3)
+	rand.Read(b)
+	return "tour-" + time.Now().Format("20060102150405") + "-" + hex.EncodeToString(b)
 }
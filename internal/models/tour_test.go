@@ -15,6 +15,7 @@ func setupValidator(t *testing.T) *validator.Validate {
 	if err != nil {
 		t.Fatalf("Failed to register custom validations: %v", err)
 	}
+	RegisterValidation(validate)
 	return validate
 }
 
@@ -30,8 +31,8 @@ func TestTourValidation(t *testing.T) {
 			name: "valid tour",
 			tour: Tour{
 				ID:          "test_tour",
-				Name:        "Test Tour",
-				Description: "A test tour",
+				Name:        NewText("Test Tour"),
+				Description: NewText("A test tour"),
 				StartDate:   time.Now(),
 				EndDate:     time.Now().AddDate(0, 1, 0),
 				Version:     "1.0",
@@ -50,8 +51,8 @@ func TestTourValidation(t *testing.T) {
 			name: "invalid dates",
 			tour: Tour{
 				ID:          "test_tour",
-				Name:        "Test Tour",
-				Description: "A test tour",
+				Name:        NewText("Test Tour"),
+				Description: NewText("A test tour"),
 				StartDate:   time.Now(),
 				EndDate:     time.Now().AddDate(0, -1, 0), // End date before start date
 				Version:     "1.0",
@@ -70,8 +71,8 @@ func TestTourValidation(t *testing.T) {
 			name: "invalid price",
 			tour: Tour{
 				ID:          "test_tour",
-				Name:        "Test Tour",
-				Description: "A test tour",
+				Name:        NewText("Test Tour"),
+				Description: NewText("A test tour"),
 				StartDate:   time.Now(),
 				EndDate:     time.Now().AddDate(0, 1, 0),
 				Version:     "1.0",
@@ -86,6 +87,77 @@ func TestTourValidation(t *testing.T) {
 			},
 			wantErr: true,
 		},
+		{
+			name: "valid settings",
+			tour: Tour{
+				ID:          "test_tour",
+				Name:        NewText("Test Tour"),
+				Description: NewText("A test tour"),
+				StartDate:   time.Now(),
+				EndDate:     time.Now().AddDate(0, 1, 0),
+				Version:     "1.0",
+				HeroImage:   "http://example.com/image.jpg",
+				Author: Author{
+					Name:        "Test Author",
+					ProfileLink: "http://example.com/author",
+				},
+				Price: 1000,
+				Settings: Settings{
+					MediaCaching:      CachePrefetchAll,
+					PreferredLanguage: "pt-BR",
+				},
+				Nodes: []Node{},
+				Edges: []Edge{},
+			},
+			wantErr: false,
+		},
+		{
+			name: "invalid preferred language",
+			tour: Tour{
+				ID:          "test_tour",
+				Name:        NewText("Test Tour"),
+				Description: NewText("A test tour"),
+				StartDate:   time.Now(),
+				EndDate:     time.Now().AddDate(0, 1, 0),
+				Version:     "1.0",
+				HeroImage:   "http://example.com/image.jpg",
+				Author: Author{
+					Name:        "Test Author",
+					ProfileLink: "http://example.com/author",
+				},
+				Price: 1000,
+				Settings: Settings{
+					MediaCaching:      CacheNone,
+					PreferredLanguage: "english please", // Not a BCP-47 tag
+				},
+				Nodes: []Node{},
+				Edges: []Edge{},
+			},
+			wantErr: true,
+		},
+		{
+			name: "invalid caching mode",
+			tour: Tour{
+				ID:          "test_tour",
+				Name:        NewText("Test Tour"),
+				Description: NewText("A test tour"),
+				StartDate:   time.Now(),
+				EndDate:     time.Now().AddDate(0, 1, 0),
+				Version:     "1.0",
+				HeroImage:   "http://example.com/image.jpg",
+				Author: Author{
+					Name:        "Test Author",
+					ProfileLink: "http://example.com/author",
+				},
+				Price: 1000,
+				Settings: Settings{
+					MediaCaching: "sometimes",
+				},
+				Nodes: []Node{},
+				Edges: []Edge{},
+			},
+			wantErr: true,
+		},
 	}
 
 	for _, tt := range tests {
@@ -114,11 +186,12 @@ func TestNodeValidation(t *testing.T) {
 					Lat: 45.0,
 					Lon: 20.0,
 				},
-				ShortDesc:      "Test Node",
-				Narrative:      "Test narrative",
+				ShortDesc:      NewText("Test Node"),
+				Narrative:      NewText("Test narrative"),
 				AudioNarrative: "http://example.com/audio.ogg",
 				MediaFiles: []MediaFile{
 					{
+						ID:        "media-1",
 						Type:      "image",
 						URI:       "http://example.com/image.jpg",
 						SendDelay: 0,
@@ -127,9 +200,9 @@ func TestNodeValidation(t *testing.T) {
 				EntryCondition: &Condition{
 					Type:          "quiz",
 					Strict:        true,
-					Question:      "Test question?",
-					CorrectAnswer: "Answer",
-					Options:       []string{"Answer", "Wrong", "Wrong2"},
+					Question:      NewText("Test question?"),
+					CorrectAnswer: NewText("Answer"),
+					Options:       []Text{NewText("Answer"), NewText("Wrong"), NewText("Wrong2")},
 				},
 			},
 			wantErr: false,
@@ -142,8 +215,8 @@ func TestNodeValidation(t *testing.T) {
 					Lat: 91.0,  // Invalid latitude
 					Lon: 180.1, // Invalid longitude
 				},
-				ShortDesc: "Test Node",
-				Narrative: "Test narrative",
+				ShortDesc: NewText("Test Node"),
+				Narrative: NewText("Test narrative"),
 			},
 			wantErr: true,
 		},
@@ -155,13 +228,13 @@ func TestNodeValidation(t *testing.T) {
 					Lat: 45.0,
 					Lon: 20.0,
 				},
-				ShortDesc: "Test Node",
-				Narrative: "Test narrative",
+				ShortDesc: NewText("Test Node"),
+				Narrative: NewText("Test narrative"),
 				EntryCondition: &Condition{
 					Type:          "quiz",
-					Question:      "Test question?",
-					CorrectAnswer: "Answer",
-					Options:       []string{"Answer"}, // Need at least 2 options
+					Question:      NewText("Test question?"),
+					CorrectAnswer: NewText("Answer"),
+					Options:       []Text{NewText("Answer")}, // Need at least 2 options
 				},
 			},
 			wantErr: true,
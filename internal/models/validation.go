@@ -0,0 +1,38 @@
+// internal/models/validation.go
+package models
+
+import (
+	"reflect"
+
+	"github.com/go-playground/validator/v10"
+)
+
+// RegisterValidation teaches v the model rules that struct tags cannot
+// express: Text fields are validated by their default value, so tags such
+// as "required" apply to the default language text, and media file IDs must
+// be unique across a tour.
+func RegisterValidation(v *validator.Validate) {
+	v.RegisterCustomTypeFunc(func(field reflect.Value) interface{} {
+		if text, ok := field.Interface().(Text); ok {
+			return text.Value
+		}
+		return nil
+	}, Text{})
+
+	v.RegisterStructValidation(validateTour, Tour{})
+}
+
+func validateTour(sl validator.StructLevel) {
+	tour := sl.Current().Interface().(Tour)
+
+	seen := make(map[string]bool)
+	for _, file := range tour.AllMediaFiles() {
+		if file.ID == "" {
+			continue // reported by the required tag
+		}
+		if seen[file.ID] {
+			sl.ReportError(file.ID, "MediaFiles", "MediaFiles", "unique_media_id", file.ID)
+		}
+		seen[file.ID] = true
+	}
+}
@@ -0,0 +1,142 @@
+// internal/repository/filesystem.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+// Filesystem stores each tour as <dir>/<id>.yaml. Writes go to a temporary
+// file in the same directory that is renamed over the tour, so a crash
+// never leaves a partly written tour behind.
+type Filesystem struct {
+	dir string
+}
+
+// NewFilesystem stores tours in dir, creating it if needed.
+func NewFilesystem(dir string) (*Filesystem, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating tour directory: %w", err)
+	}
+	return &Filesystem{dir: dir}, nil
+}
+
+func (f *Filesystem) path(id string) string {
+	return filepath.Join(f.dir, id+extension)
+}
+
+func (f *Filesystem) Get(ctx context.Context, id string) (*models.Tour, error) {
+	data, err := f.read(id)
+	if err != nil {
+		return nil, err
+	}
+	return decode(id, data)
+}
+
+// Summaries returns the summaries of the stored tours in ID order.
+func (f *Filesystem) Summaries(ctx context.Context) ([]Summary, error) {
+	ids, err := f.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]Summary, 0, len(ids))
+	for _, id := range ids {
+		data, err := f.read(id)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted since it was listed
+		}
+		if err != nil {
+			return nil, err
+		}
+		summary, err := decodeSummary(id, data)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// read returns the stored document of the tour id.
+func (f *Filesystem) read(id string) ([]byte, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	data, err := os.ReadFile(f.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading tour %s: %w", id, err)
+	}
+	return data, nil
+}
+
+func (f *Filesystem) Save(ctx context.Context, tour *models.Tour) error {
+	data, err := encode(tour)
+	if err != nil {
+		return err
+	}
+
+	tmp, err := os.CreateTemp(f.dir, "."+tour.ID+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing tour %s: %w", tour.ID, err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing tour %s: %w", tour.ID, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing tour %s: %w", tour.ID, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing tour %s: %w", tour.ID, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("writing tour %s: %w", tour.ID, err)
+	}
+	if err := os.Rename(tmp.Name(), f.path(tour.ID)); err != nil {
+		return fmt.Errorf("writing tour %s: %w", tour.ID, err)
+	}
+	return nil
+}
+
+func (f *Filesystem) Delete(ctx context.Context, id string) error {
+	if err := checkID(id); err != nil {
+		return err
+	}
+	err := os.Remove(f.path(id))
+	if errors.Is(err, fs.ErrNotExist) {
+		return ErrNotFound
+	}
+	return err
+}
+
+func (f *Filesystem) List(ctx context.Context) ([]string, error) {
+	entries, err := os.ReadDir(f.dir)
+	if err != nil {
+		return nil, fmt.Errorf("listing tours: %w", err)
+	}
+
+	var ids []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || strings.HasPrefix(name, ".") || !strings.HasSuffix(name, extension) {
+			continue
+		}
+		ids = append(ids, strings.TrimSuffix(name, extension))
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,86 @@
+// internal/repository/memory.go
+package repository
+
+import (
+	"context"
+	"sort"
+	"sync"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+// Memory keeps tours in memory, for tests and development. Tours are stored
+// encoded, so changes to a saved or loaded tour do not leak into the store.
+type Memory struct {
+	mu    sync.RWMutex
+	tours map[string][]byte
+}
+
+func NewMemory() *Memory {
+	return &Memory{tours: make(map[string][]byte)}
+}
+
+func (m *Memory) Get(ctx context.Context, id string) (*models.Tour, error) {
+	m.mu.RLock()
+	data, ok := m.tours[id]
+	m.mu.RUnlock()
+	if !ok {
+		return nil, ErrNotFound
+	}
+	return decode(id, data)
+}
+
+func (m *Memory) Save(ctx context.Context, tour *models.Tour) error {
+	data, err := encode(tour)
+	if err != nil {
+		return err
+	}
+	m.mu.Lock()
+	m.tours[tour.ID] = data
+	m.mu.Unlock()
+	return nil
+}
+
+func (m *Memory) Delete(ctx context.Context, id string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.tours[id]; !ok {
+		return ErrNotFound
+	}
+	delete(m.tours, id)
+	return nil
+}
+
+// Summaries returns the summaries of the stored tours in ID order.
+func (m *Memory) Summaries(ctx context.Context) ([]Summary, error) {
+	ids, err := m.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]Summary, 0, len(ids))
+	for _, id := range ids {
+		m.mu.RLock()
+		data, ok := m.tours[id]
+		m.mu.RUnlock()
+		if !ok {
+			continue // deleted since it was listed
+		}
+		summary, err := decodeSummary(id, data)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+func (m *Memory) List(ctx context.Context) ([]string, error) {
+	m.mu.RLock()
+	ids := make([]string, 0, len(m.tours))
+	for id := range m.tours {
+		ids = append(ids, id)
+	}
+	m.mu.RUnlock()
+	sort.Strings(ids)
+	return ids, nil
+}
@@ -0,0 +1,92 @@
+// internal/repository/repository.go
+
+// Package repository stores tours as YAML documents, one per tour ID, in
+// memory, on the filesystem or in an S3 bucket. Each document is the tour
+// followed by the editor's record of it (owner, modification time,
+// archived flag) under the key "editor".
+package repository
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// ErrNotFound is returned when no tour is stored under an ID.
+var ErrNotFound = errors.New("tour not found")
+
+// ErrInvalidID is returned for tour IDs that cannot name a stored document,
+// such as an empty ID or one containing a path separator.
+var ErrInvalidID = errors.New("invalid tour ID")
+
+const extension = ".yaml"
+
+// document is the stored form of a tour: the tour itself with the editor's
+// record of it under "editor", which tour readers ignore.
+type document struct {
+	models.Tour `yaml:",inline"`
+	Editor      models.TourRecord `yaml:"editor"`
+}
+
+// Summary describes a stored tour without its content, for listing tours.
+type Summary struct {
+	ID      string
+	Name    string // in the tour's default language
+	Version string
+	Nodes   int
+	Record  models.TourRecord
+}
+
+// summaryDocument is the part of a document a Summary is read from. Nodes
+// are counted, not decoded.
+type summaryDocument struct {
+	Name    models.Text       `yaml:"name"`
+	Version string            `yaml:"version"`
+	Nodes   []struct{}        `yaml:"nodes"`
+	Editor  models.TourRecord `yaml:"editor"`
+}
+
+func checkID(id string) error {
+	if id == "" || id == "." || id == ".." || strings.ContainsAny(id, `/\`) || strings.HasPrefix(id, ".") {
+		return fmt.Errorf("%w: %q", ErrInvalidID, id)
+	}
+	return nil
+}
+
+func encode(tour *models.Tour) ([]byte, error) {
+	if err := checkID(tour.ID); err != nil {
+		return nil, err
+	}
+	data, err := yaml.Marshal(document{Tour: *tour, Editor: tour.Record})
+	if err != nil {
+		return nil, fmt.Errorf("encoding tour %s: %w", tour.ID, err)
+	}
+	return data, nil
+}
+
+func decode(id string, data []byte) (*models.Tour, error) {
+	var doc document
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("decoding tour %s: %w", id, err)
+	}
+	tour := doc.Tour
+	tour.Record = doc.Editor
+	return &tour, nil
+}
+
+func decodeSummary(id string, data []byte) (Summary, error) {
+	var doc summaryDocument
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return Summary{}, fmt.Errorf("decoding tour %s: %w", id, err)
+	}
+	return Summary{
+		ID:      id,
+		Name:    doc.Name.Value,
+		Version: doc.Version,
+		Nodes:   len(doc.Nodes),
+		Record:  doc.Editor,
+	}, nil
+}
@@ -0,0 +1,164 @@
+// internal/repository/repository_test.go
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ceesaxp/tour-guide-editor/internal/mocks"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+type repository interface {
+	Get(ctx context.Context, id string) (*models.Tour, error)
+	Save(ctx context.Context, tour *models.Tour) error
+	Delete(ctx context.Context, id string) error
+	List(ctx context.Context) ([]string, error)
+	Summaries(ctx context.Context) ([]Summary, error)
+}
+
+func newTour(id string) *models.Tour {
+	tour := models.NewTour()
+	tour.ID = id
+	tour.Name = models.NewText("Tour " + id)
+	tour.Nodes = []models.Node{
+		{ID: 1, ShortDesc: models.NewText("Gate"), Narrative: models.NewText("First"), Start: true},
+	}
+	return tour
+}
+
+// smallPages makes the fake S3 return one object per page.
+type smallPages struct{ *mocks.FakeS3 }
+
+func (p smallPages) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	one := int32(1)
+	params.MaxKeys = &one
+	return p.FakeS3.ListObjectsV2(ctx, params, optFns...)
+}
+
+func TestRepositories(t *testing.T) {
+	tests := []struct {
+		name string
+		new  func(t *testing.T) repository
+	}{
+		{"memory", func(t *testing.T) repository { return NewMemory() }},
+		{"filesystem", func(t *testing.T) repository {
+			repo, err := NewFilesystem(filepath.Join(t.TempDir(), "tours"))
+			if err != nil {
+				t.Fatal(err)
+			}
+			return repo
+		}},
+		{"s3", func(t *testing.T) repository { return NewS3(smallPages{mocks.NewFakeS3()}, "tours") }},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			repo := tt.new(t)
+
+			if _, err := repo.Get(ctx, "missing"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("Get() of a missing tour: expected ErrNotFound, got %v", err)
+			}
+
+			for _, id := range []string{"b", "a", "c"} {
+				if err := repo.Save(ctx, newTour(id)); err != nil {
+					t.Fatalf("Save(%s) error = %v", id, err)
+				}
+			}
+
+			tour := newTour("a")
+			tour.Name = models.NewText("Renamed")
+			tour.Record.Owner = "alice"
+			if err := repo.Save(ctx, tour); err != nil {
+				t.Fatal(err)
+			}
+			tour.Name = models.NewText("Changed after saving")
+
+			got, err := repo.Get(ctx, "a")
+			if err != nil {
+				t.Fatalf("Get() error = %v", err)
+			}
+			if got.Name.Value != "Renamed" || len(got.Nodes) != 1 || got.Nodes[0].ShortDesc.Value != "Gate" {
+				t.Errorf("unexpected tour %+v", got)
+			}
+
+			ids, err := repo.List(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if want := []string{"a", "b", "c"}; !reflect.DeepEqual(ids, want) {
+				t.Errorf("List() = %v, want %v", ids, want)
+			}
+
+			summaries, err := repo.Summaries(ctx)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(summaries) != 3 {
+				t.Fatalf("Summaries() = %+v, want 3 tours", summaries)
+			}
+			if got := summaries[0]; got.ID != "a" || got.Name != "Renamed" || got.Version != "1.0" || got.Nodes != 1 || got.Record.Owner != "alice" {
+				t.Errorf("unexpected summary %+v", got)
+			}
+
+			if err := repo.Delete(ctx, "b"); err != nil {
+				t.Fatalf("Delete() error = %v", err)
+			}
+			if err := repo.Delete(ctx, "b"); !errors.Is(err, ErrNotFound) {
+				t.Errorf("second Delete(): expected ErrNotFound, got %v", err)
+			}
+			if ids, _ := repo.List(ctx); !reflect.DeepEqual(ids, []string{"a", "c"}) {
+				t.Errorf("List() after delete = %v", ids)
+			}
+
+			for _, id := range []string{"", "../escape", "nested/tour", ".hidden"} {
+				if err := repo.Save(ctx, newTour(id)); !errors.Is(err, ErrInvalidID) {
+					t.Errorf("Save(%q): expected ErrInvalidID, got %v", id, err)
+				}
+			}
+		})
+	}
+}
+
+func TestFilesystem_AtomicSave(t *testing.T) {
+	dir := t.TempDir()
+	repo, err := NewFilesystem(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+
+	for i := 0; i < 3; i++ {
+		tour := newTour("city")
+		tour.Version = fmt.Sprint(i)
+		if err := repo.Save(ctx, tour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	entries, err := os.ReadDir(dir)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(entries) != 1 || entries[0].Name() != "city.yaml" {
+		t.Errorf("expected only city.yaml, found %v", entries)
+	}
+
+	// A temporary file left by an interrupted save is not a tour
+	if err := os.WriteFile(filepath.Join(dir, ".city-123.tmp"), []byte("partial"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if ids, _ := repo.List(ctx); !reflect.DeepEqual(ids, []string{"city"}) {
+		t.Errorf("List() = %v", ids)
+	}
+	if got, _ := repo.Get(ctx, "city"); got == nil || got.Version != "2" {
+		t.Errorf("expected the last saved version, got %+v", got)
+	}
+}
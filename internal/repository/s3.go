@@ -0,0 +1,153 @@
+// internal/repository/s3.go
+package repository
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+// S3API is the part of the S3 client the S3 repository uses.
+type S3API interface {
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// S3 stores each tour as the object <id>.yaml in a bucket.
+type S3 struct {
+	client S3API
+	bucket string
+}
+
+func NewS3(client S3API, bucket string) *S3 {
+	return &S3{client: client, bucket: bucket}
+}
+
+func (r *S3) Get(ctx context.Context, id string) (*models.Tour, error) {
+	data, err := r.read(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	return decode(id, data)
+}
+
+// Summaries returns the summaries of the stored tours in ID order.
+func (r *S3) Summaries(ctx context.Context) ([]Summary, error) {
+	ids, err := r.List(ctx)
+	if err != nil {
+		return nil, err
+	}
+	summaries := make([]Summary, 0, len(ids))
+	for _, id := range ids {
+		data, err := r.read(ctx, id)
+		if errors.Is(err, ErrNotFound) {
+			continue // deleted since it was listed
+		}
+		if err != nil {
+			return nil, err
+		}
+		summary, err := decodeSummary(id, data)
+		if err != nil {
+			return nil, err
+		}
+		summaries = append(summaries, summary)
+	}
+	return summaries, nil
+}
+
+// read returns the stored document of the tour id.
+func (r *S3) read(ctx context.Context, id string) ([]byte, error) {
+	if err := checkID(id); err != nil {
+		return nil, err
+	}
+	out, err := r.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(id + extension),
+	})
+	if isNotFound(err) {
+		return nil, ErrNotFound
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading tour %s: %w", id, err)
+	}
+	defer out.Body.Close()
+
+	data, err := io.ReadAll(out.Body)
+	if err != nil {
+		return nil, fmt.Errorf("reading tour %s: %w", id, err)
+	}
+	return data, nil
+}
+
+func (r *S3) Save(ctx context.Context, tour *models.Tour) error {
+	data, err := encode(tour)
+	if err != nil {
+		return err
+	}
+	_, err = r.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(r.bucket),
+		Key:         aws.String(tour.ID + extension),
+		Body:        bytes.NewReader(data),
+		ContentType: aws.String("application/yaml"),
+	})
+	if err != nil {
+		return fmt.Errorf("writing tour %s: %w", tour.ID, err)
+	}
+	return nil
+}
+
+// Delete removes the tour. S3 does not report whether the object existed,
+// so it is looked up first.
+func (r *S3) Delete(ctx context.Context, id string) error {
+	if _, err := r.Get(ctx, id); err != nil {
+		return err
+	}
+	_, err := r.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(r.bucket),
+		Key:    aws.String(id + extension),
+	})
+	if err != nil {
+		return fmt.Errorf("deleting tour %s: %w", id, err)
+	}
+	return nil
+}
+
+func (r *S3) List(ctx context.Context) ([]string, error) {
+	var ids []string
+	input := &s3.ListObjectsV2Input{Bucket: aws.String(r.bucket)}
+	for {
+		out, err := r.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("listing tours: %w", err)
+		}
+		for _, object := range out.Contents {
+			key := aws.ToString(object.Key)
+			if strings.HasSuffix(key, extension) && !strings.Contains(key, "/") {
+				ids = append(ids, strings.TrimSuffix(key, extension))
+			}
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			break
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+	sort.Strings(ids)
+	return ids, nil
+}
+
+func isNotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	return errors.As(err, &noSuchKey) || errors.As(err, &notFound)
+}
@@ -0,0 +1,198 @@
+// internal/services/edit_sessions.go
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/middleware"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+// DefaultSessionTTL is how long a tour stays open in the editor without
+// being used.
+const DefaultSessionTTL = 12 * time.Hour
+
+// maxOpenTours is the number of tours a user can have open at once. Opening
+// another one closes the tour that was used least recently.
+const maxOpenTours = 10
+
+// ErrNoUser is returned when a tour is opened without an authenticated user.
+var ErrNoUser = errors.New("no authenticated user")
+
+// sessionKey identifies the copy of a tour a user is editing.
+type sessionKey struct {
+	user   string
+	tourID string
+}
+
+type editSession struct {
+	tour     *models.Tour
+	lastUsed time.Time
+}
+
+// editSessions holds the tours users have open in the editor, keyed by user
+// and tour ID, so every browser a user signs in with edits the same copy.
+// Tours that are not used for ttl are closed, and closed is called for them
+// with the ID they were open under and whether any user still has that ID
+// open.
+type editSessions struct {
+	mu      sync.Mutex
+	ttl     time.Duration
+	now     func() time.Time
+	closed  func(tour *models.Tour, id string, stillOpen bool)
+	open    map[sessionKey]*editSession
+	current map[string]string // tour ID each user is editing
+}
+
+func newEditSessions(ttl time.Duration, closed func(tour *models.Tour, id string, stillOpen bool)) *editSessions {
+	return &editSessions{
+		ttl:     ttl,
+		now:     time.Now,
+		closed:  closed,
+		open:    make(map[sessionKey]*editSession),
+		current: make(map[string]string),
+	}
+}
+
+// currentTour returns the tour user is editing, or nil.
+func (e *editSessions) currentTour(user string) *models.Tour {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.expire()
+	id, ok := e.current[user]
+	if !ok {
+		return nil
+	}
+	return e.use(sessionKey{user, id})
+}
+
+// openTour returns user's copy of the tour id, or nil if it is not open.
+func (e *editSessions) openTour(user, id string) *models.Tour {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.expire()
+	return e.use(sessionKey{user, id})
+}
+
+// setCurrent makes tour the tour user is editing. A tour whose ID was
+// changed since it was opened moves to its new ID.
+func (e *editSessions) setCurrent(user string, tour *models.Tour) {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	e.expire()
+	if id, ok := e.current[user]; ok && id != tour.ID {
+		key := sessionKey{user, id}
+		if session := e.open[key]; session != nil && session.tour == tour {
+			delete(e.open, key)
+		}
+	}
+
+	key := sessionKey{user, tour.ID}
+	if session := e.open[key]; session != nil && session.tour != tour {
+		e.close(key)
+	}
+	e.open[key] = &editSession{tour: tour, lastUsed: e.now()}
+	e.current[user] = tour.ID
+	e.evict(user)
+}
+
+// use marks the session key as used and returns its tour, or nil.
+func (e *editSessions) use(key sessionKey) *models.Tour {
+	session := e.open[key]
+	if session == nil {
+		return nil
+	}
+	session.lastUsed = e.now()
+	return session.tour
+}
+
+// expire closes the sessions that have not been used for ttl.
+func (e *editSessions) expire() {
+	cutoff := e.now().Add(-e.ttl)
+	for key, session := range e.open {
+		if session.lastUsed.Before(cutoff) {
+			e.close(key)
+		}
+	}
+}
+
+// evict closes the least recently used tours of user beyond maxOpenTours.
+// The tour user is editing stays open.
+func (e *editSessions) evict(user string) {
+	for {
+		var oldest *sessionKey
+		count := 0
+		for key, session := range e.open {
+			if key.user != user {
+				continue
+			}
+			count++
+			if key.tourID != e.current[user] && (oldest == nil || session.lastUsed.Before(e.open[*oldest].lastUsed)) {
+				k := key
+				oldest = &k
+			}
+		}
+		if count <= maxOpenTours || oldest == nil {
+			return
+		}
+		e.close(*oldest)
+	}
+}
+
+func (e *editSessions) close(key sessionKey) {
+	session := e.open[key]
+	delete(e.open, key)
+	if e.current[key.user] == key.tourID {
+		delete(e.current, key.user)
+	}
+	if session != nil && e.closed != nil {
+		e.closed(session.tour, key.tourID, e.isOpen(key.tourID))
+	}
+}
+
+// isOpen reports whether any user has the tour id open.
+func (e *editSessions) isOpen(id string) bool {
+	for key := range e.open {
+		if key.tourID == id {
+			return true
+		}
+	}
+	return false
+}
+
+// SetSessionTTL sets how long a tour stays open in the editor without
+// being used, DefaultSessionTTL unless set.
+func (s *TourService) SetSessionTTL(ttl time.Duration) {
+	s.sessions.mu.Lock()
+	defer s.sessions.mu.Unlock()
+	s.sessions.ttl = ttl
+}
+
+// GetCurrentTour returns the tour the authenticated user of ctx is editing,
+// or nil if there is none or the request is not authenticated.
+func (s *TourService) GetCurrentTour(ctx context.Context) *models.Tour {
+	user := middleware.UserID(ctx)
+	if user == "" {
+		return nil
+	}
+	return s.sessions.currentTour(user)
+}
+
+// SaveTourToSession makes tour the tour the authenticated user of ctx is
+// editing, without storing it.
+func (s *TourService) SaveTourToSession(ctx context.Context, tour *models.Tour) error {
+	user := middleware.UserID(ctx)
+	if user == "" {
+		return ErrNoUser
+	}
+	s.ViewTour(tour, func(tour *models.Tour) {
+		s.sessions.setCurrent(user, tour)
+	})
+	return nil
+}
@@ -0,0 +1,127 @@
+// internal/services/edit_sessions_test.go
+package services
+
+import (
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/middleware"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+func userContext(user string) context.Context {
+	return context.WithValue(context.Background(), middleware.UserIDKey, user)
+}
+
+func TestTourService_EditSessions(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	tour := newTestTour()
+	if err := service.SaveTour(userContext("admin"), tour); err != nil {
+		t.Fatal(err)
+	}
+
+	// Every request of a user sees the same tour
+	if got := service.GetCurrentTour(userContext("admin")); got != tour {
+		t.Errorf("expected the user's tour, got %v", got)
+	}
+	if got := service.GetCurrentTour(userContext("user")); got != nil {
+		t.Errorf("expected no tour for another user, got %s", got.ID)
+	}
+	if got := service.GetCurrentTour(context.Background()); got != nil {
+		t.Errorf("expected no tour without a user, got %s", got.ID)
+	}
+	if err := service.SaveTourToSession(context.Background(), tour); err != ErrNoUser {
+		t.Errorf("expected ErrNoUser, got %v", err)
+	}
+
+	// Another user opens their own copy
+	copied, err := service.OpenTour(userContext("user"), "user", tour.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied == tour {
+		t.Error("expected users to edit separate copies")
+	}
+
+	// Reopening picks up the open copy
+	other := models.NewTour()
+	other.ID = "other"
+	if err := service.SaveTourToSession(userContext("admin"), other); err != nil {
+		t.Fatal(err)
+	}
+	if got, err := service.OpenTour(userContext("admin"), "admin", tour.ID); err != nil || got != tour {
+		t.Errorf("expected the open copy back, got %v, %v", got, err)
+	}
+
+	// A renamed tour moves to its new ID
+	tour.ID = "renamed"
+	if err := service.SaveTourToSession(userContext("admin"), tour); err != nil {
+		t.Fatal(err)
+	}
+	if got := service.sessions.openTour("admin", "test_tour"); got != nil {
+		t.Error("expected the old ID to be closed")
+	}
+	if got := service.sessions.openTour("admin", "renamed"); got != tour {
+		t.Error("expected the tour under its new ID")
+	}
+}
+
+func TestTourService_EditSessionExpiry(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	service.SetSessionTTL(time.Hour)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service.sessions.now = func() time.Time { return now }
+
+	ctx := userContext("admin")
+	draft := models.NewTour()
+	draft.ID = "draft"
+	service.drafts.Store(draft, true)
+	if err := service.SaveTourToSession(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+
+	now = now.Add(59 * time.Minute)
+	if service.GetCurrentTour(ctx) != draft {
+		t.Fatal("expected the tour to stay open while it is used")
+	}
+	now = now.Add(61 * time.Minute)
+	if got := service.GetCurrentTour(ctx); got != nil {
+		t.Errorf("expected the unused tour to be closed, got %s", got.ID)
+	}
+	if service.IsDraft(draft) {
+		t.Error("expected the closed tour to be forgotten")
+	}
+}
+
+func TestTourService_EditSessionEviction(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service.sessions.now = func() time.Time { return now }
+
+	ctx := userContext("admin")
+	var tours []*models.Tour
+	for i := 0; i <= maxOpenTours; i++ {
+		now = now.Add(time.Minute)
+		tour := models.NewTour()
+		tour.ID = fmt.Sprintf("tour_%d", i)
+		tours = append(tours, tour)
+		if err := service.SaveTourToSession(ctx, tour); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if got := service.sessions.openTour("admin", "tour_0"); got != nil {
+		t.Error("expected the least recently used tour to be closed")
+	}
+	for _, tour := range tours[1:] {
+		if service.sessions.openTour("admin", tour.ID) != tour {
+			t.Errorf("expected %s to stay open", tour.ID)
+		}
+	}
+	if service.GetCurrentTour(ctx) != tours[maxOpenTours] {
+		t.Error("expected the last tour to be current")
+	}
+}
@@ -0,0 +1,213 @@
+// internal/services/graph_validator.go
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+// Graph issue codes. These are stable and may be relied upon by clients.
+const (
+	IssueDuplicateNodeID   = "duplicate_node_id"
+	IssueEdgeMissingSource = "edge_missing_source"
+	IssueEdgeMissingTarget = "edge_missing_target"
+	IssueUnreachableNode   = "unreachable_node"
+	IssueDeadEnd           = "dead_end"
+	IssueUnintendedCycle   = "unintended_cycle"
+	IssueMissingStart      = "missing_start_node"
+	IssueMultipleStarts    = "multiple_start_nodes"
+	IssueNoReachableFinish = "no_reachable_finish"
+)
+
+const (
+	SeverityError   = "error"
+	SeverityWarning = "warning"
+)
+
+// GraphIssue is a single structural problem found in a tour. Path points at
+// the offending element using the YAML field names, e.g. "edges[3].to".
+type GraphIssue struct {
+	Code     string `json:"code"`
+	Severity string `json:"severity"`
+	Path     string `json:"path"`
+	Message  string `json:"message"`
+}
+
+// GraphIssues is the result of a graph validation run.
+type GraphIssues []GraphIssue
+
+func (issues GraphIssues) HasErrors() bool {
+	for _, issue := range issues {
+		if issue.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+func (issues GraphIssues) Error() string {
+	messages := make([]string, len(issues))
+	for i, issue := range issues {
+		messages[i] = fmt.Sprintf("%s: %s", issue.Path, issue.Message)
+	}
+	return strings.Join(messages, "; ")
+}
+
+// ValidateGraph checks the node/edge structure of a tour: unique node IDs,
+// edges between existing nodes, a single start node, reachability of every
+// node and of at least one finish node, dead ends and cycles that are not
+// marked as intentional loops.
+func (s *TourService) ValidateGraph(tour *models.Tour) GraphIssues {
+	var issues GraphIssues
+
+	nodeIndex := make(map[int]int, len(tour.Nodes))
+	for i, node := range tour.Nodes {
+		if first, ok := nodeIndex[node.ID]; ok {
+			issues = append(issues, GraphIssue{
+				Code:     IssueDuplicateNodeID,
+				Severity: SeverityError,
+				Path:     fmt.Sprintf("nodes[%d].id", i),
+				Message:  fmt.Sprintf("node ID %d is already used by nodes[%d]", node.ID, first),
+			})
+			continue
+		}
+		nodeIndex[node.ID] = i
+	}
+
+	// Build the adjacency list from edges whose endpoints both exist
+	outgoing := make(map[int][]int, len(tour.Nodes))
+	for i, edge := range tour.Edges {
+		_, fromOK := nodeIndex[edge.From]
+		_, toOK := nodeIndex[edge.To]
+		if !fromOK {
+			issues = append(issues, GraphIssue{
+				Code:     IssueEdgeMissingSource,
+				Severity: SeverityError,
+				Path:     fmt.Sprintf("edges[%d].from", i),
+				Message:  fmt.Sprintf("edge starts at unknown node %d", edge.From),
+			})
+		}
+		if !toOK {
+			issues = append(issues, GraphIssue{
+				Code:     IssueEdgeMissingTarget,
+				Severity: SeverityError,
+				Path:     fmt.Sprintf("edges[%d].to", i),
+				Message:  fmt.Sprintf("edge ends at unknown node %d", edge.To),
+			})
+		}
+		if fromOK && toOK {
+			outgoing[edge.From] = append(outgoing[edge.From], i)
+		}
+	}
+
+	start, ok := tour.StartNodeID()
+	if !ok {
+		return append(issues, GraphIssue{
+			Code:     IssueMissingStart,
+			Severity: SeverityError,
+			Path:     "nodes",
+			Message:  "no node is marked as the start of the tour",
+		})
+	}
+	for i, node := range tour.Nodes {
+		if node.Start && node.ID != start {
+			issues = append(issues, GraphIssue{
+				Code:     IssueMultipleStarts,
+				Severity: SeverityError,
+				Path:     fmt.Sprintf("nodes[%d].start", i),
+				Message:  fmt.Sprintf("node %d is marked as start, but node %d already is", node.ID, start),
+			})
+		}
+	}
+
+	// Reachability
+	reached := map[int]bool{start: true}
+	queue := []int{start}
+	for len(queue) > 0 {
+		id := queue[0]
+		queue = queue[1:]
+		for _, e := range outgoing[id] {
+			if to := tour.Edges[e].To; !reached[to] {
+				reached[to] = true
+				queue = append(queue, to)
+			}
+		}
+	}
+
+	finishReached := false
+	for i, node := range tour.Nodes {
+		if nodeIndex[node.ID] != i {
+			continue // duplicate, already reported
+		}
+		if node.Finish && reached[node.ID] {
+			finishReached = true
+		}
+		if !reached[node.ID] {
+			issues = append(issues, GraphIssue{
+				Code:     IssueUnreachableNode,
+				Severity: SeverityError,
+				Path:     fmt.Sprintf("nodes[%d]", i),
+				Message:  fmt.Sprintf("node %d cannot be reached from the start node %d", node.ID, start),
+			})
+		}
+		if len(outgoing[node.ID]) == 0 && !tour.IsFinishNode(node.ID) {
+			issues = append(issues, GraphIssue{
+				Code:     IssueDeadEnd,
+				Severity: SeverityError,
+				Path:     fmt.Sprintf("nodes[%d]", i),
+				Message:  fmt.Sprintf("node %d has no outgoing edges but is not a finish node", node.ID),
+			})
+		}
+	}
+
+	if !finishReached {
+		issues = append(issues, GraphIssue{
+			Code:     IssueNoReachableFinish,
+			Severity: SeverityError,
+			Path:     "nodes",
+			Message:  "no finish node can be reached from the start node",
+		})
+	}
+
+	// Cycles: a depth-first search reports every back edge that has not been
+	// marked as an intentional loop. Unreachable nodes are searched as well so
+	// that their cycles are not hidden behind the reachability error.
+	const (
+		unvisited = iota
+		inProgress
+		done
+	)
+	state := make(map[int]int, len(tour.Nodes))
+	var visit func(id int)
+	visit = func(id int) {
+		state[id] = inProgress
+		for _, e := range outgoing[id] {
+			edge := tour.Edges[e]
+			switch state[edge.To] {
+			case unvisited:
+				visit(edge.To)
+			case inProgress:
+				if !edge.Loop {
+					issues = append(issues, GraphIssue{
+						Code:     IssueUnintendedCycle,
+						Severity: SeverityWarning,
+						Path:     fmt.Sprintf("edges[%d]", e),
+						Message:  fmt.Sprintf("edge %d -> %d closes a cycle; mark it as a loop if this is intended", edge.From, edge.To),
+					})
+				}
+			}
+		}
+		state[id] = done
+	}
+
+	visit(start)
+	for _, node := range tour.Nodes {
+		if state[node.ID] == unvisited {
+			visit(node.ID)
+		}
+	}
+
+	return issues
+}
@@ -0,0 +1,150 @@
+// internal/services/graph_validator_test.go
+package services
+
+import (
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+func TestTourService_ValidateGraph(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+
+	tests := []struct {
+		name      string
+		nodes     []int
+		edges     []models.Edge
+		mutate    func(*models.Tour) // overrides the default first=start, last=finish
+		wantCodes map[string]string  // path -> code
+	}{
+		{
+			name:      "linear tour",
+			nodes:     []int{1, 2, 3},
+			edges:     []models.Edge{{From: 1, To: 2}, {From: 2, To: 3}},
+			wantCodes: map[string]string{},
+		},
+		{
+			name:  "duplicate node ID",
+			nodes: []int{1, 2, 2, 3},
+			edges: []models.Edge{{From: 1, To: 2}, {From: 2, To: 3}},
+			wantCodes: map[string]string{
+				"nodes[2].id": IssueDuplicateNodeID,
+			},
+		},
+		{
+			name:  "dangling edges",
+			nodes: []int{1, 2},
+			edges: []models.Edge{{From: 1, To: 2}, {From: 7, To: 9}},
+			wantCodes: map[string]string{
+				"edges[1].from": IssueEdgeMissingSource,
+				"edges[1].to":   IssueEdgeMissingTarget,
+			},
+		},
+		{
+			name:  "unreachable node and dead end",
+			nodes: []int{1, 2, 3},
+			edges: []models.Edge{{From: 2, To: 3}},
+			wantCodes: map[string]string{
+				"nodes[0]": IssueDeadEnd,
+				"nodes[1]": IssueUnreachableNode,
+				"nodes[2]": IssueUnreachableNode,
+				"nodes":    IssueNoReachableFinish,
+			},
+		},
+		{
+			name:  "unintended cycle",
+			nodes: []int{1, 2, 3},
+			edges: []models.Edge{{From: 1, To: 2}, {From: 2, To: 1}, {From: 2, To: 3}},
+			wantCodes: map[string]string{
+				"edges[1]": IssueUnintendedCycle,
+			},
+		},
+		{
+			name:   "missing start",
+			nodes:  []int{1, 2},
+			edges:  []models.Edge{{From: 1, To: 2}},
+			mutate: func(tour *models.Tour) { tour.Nodes[1].Finish = true },
+			wantCodes: map[string]string{
+				"nodes": IssueMissingStart,
+			},
+		},
+		{
+			name:  "multiple starts",
+			nodes: []int{1, 2},
+			edges: []models.Edge{{From: 1, To: 2}},
+			mutate: func(tour *models.Tour) {
+				tour.Nodes[0].Start = true
+				tour.Nodes[1].Start = true
+				tour.Nodes[1].Finish = true
+			},
+			wantCodes: map[string]string{
+				"nodes[1].start": IssueMultipleStarts,
+			},
+		},
+		{
+			name:  "finish not reachable",
+			nodes: []int{1, 2, 3},
+			edges: []models.Edge{{From: 1, To: 2}, {From: 2, To: 1, Loop: true}},
+			mutate: func(tour *models.Tour) {
+				tour.Nodes[0].Start = true
+				tour.Nodes[2].Finish = true
+			},
+			wantCodes: map[string]string{
+				"nodes[2]": IssueUnreachableNode,
+				"nodes":    IssueNoReachableFinish,
+			},
+		},
+		{
+			name:      "intended loop",
+			nodes:     []int{1, 2, 3},
+			edges:     []models.Edge{{From: 1, To: 2}, {From: 2, To: 1, Loop: true}, {From: 2, To: 3}},
+			wantCodes: map[string]string{},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tour := &models.Tour{Edges: tt.edges}
+			for _, id := range tt.nodes {
+				tour.Nodes = append(tour.Nodes, models.Node{ID: id})
+			}
+			if tt.mutate != nil {
+				tt.mutate(tour)
+			} else if len(tour.Nodes) > 0 {
+				tour.Nodes[0].Start = true
+				tour.Nodes[len(tour.Nodes)-1].Finish = true
+			}
+
+			issues := service.ValidateGraph(tour)
+
+			got := make(map[string]string, len(issues))
+			for _, issue := range issues {
+				got[issue.Path] = issue.Code
+			}
+			if len(got) != len(tt.wantCodes) {
+				t.Errorf("Expected %d issues, got %v", len(tt.wantCodes), issues)
+			}
+			for path, code := range tt.wantCodes {
+				if got[path] != code {
+					t.Errorf("Expected %s at %s, got %q", code, path, got[path])
+				}
+			}
+		})
+	}
+}
+
+func TestTourService_ExportTourRejectsBrokenGraph(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+
+	tour := newTestTour()
+	tour.Edges = []models.Edge{{From: 1, To: 2}, {From: 2, To: 3}}
+	if _, err := service.ExportTour(tour); err != nil {
+		t.Fatalf("Unexpected error exporting valid tour: %v", err)
+	}
+
+	tour.Edges = tour.Edges[:1]
+	if _, err := service.ExportTour(tour); err == nil {
+		t.Error("Expected error exporting tour with unreachable node, got nil")
+	}
+}
@@ -0,0 +1,231 @@
+// internal/services/media_audio.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidAudio is returned for audio files ffmpeg cannot decode.
+var ErrInvalidAudio = errors.New("invalid audio file")
+
+// errToolNotFound is returned when ffmpeg or ffprobe is not installed.
+var errToolNotFound = errors.New("not found")
+
+// Audio processing defaults for the zero values of MediaConfig.
+const (
+	defaultFFmpeg           = "ffmpeg"
+	defaultFFprobe          = "ffprobe"
+	defaultAudioBitrate     = 64 // kbit/s, plenty for speech in Opus
+	defaultAudioChannels    = 1
+	defaultTranscodeTimeout = time.Minute
+)
+
+// Audio narratives are stored as Ogg/Opus.
+const (
+	narrativeMimeType  = "audio/ogg"
+	narrativeExtension = ".ogg"
+)
+
+// audioInfo is what ffprobe reports about an audio file.
+type audioInfo struct {
+	Duration   time.Duration
+	SampleRate int // Hz
+	Codec      string
+}
+
+// encoders maps the codecs ffprobe reports to the ffmpeg encoders that
+// write them, where the names differ.
+var encoders = map[string]string{
+	"mp3":    "libmp3lame",
+	"opus":   "libopus",
+	"vorbis": "libvorbis",
+}
+
+// transcodeAudio converts data to Ogg/Opus with the configured ffmpeg,
+// bitrate and channels, normalizing its loudness if configured.
+func (s *MediaService) transcodeAudio(data []byte) ([]byte, error) {
+	bitrate := s.config.AudioBitrate
+	if bitrate <= 0 {
+		bitrate = defaultAudioBitrate
+	}
+	channels := s.config.AudioChannels
+	if channels <= 0 {
+		channels = defaultAudioChannels
+	}
+
+	args := []string{"-map", "0:a:0", "-map_metadata", "-1"}
+	if filter := s.loudnessFilter(); filter != "" {
+		args = append(args, "-af", filter)
+	}
+	args = append(args,
+		"-c:a", "libopus", "-b:a", strconv.Itoa(bitrate)+"k", "-ac", strconv.Itoa(channels),
+		"-f", "ogg",
+	)
+	transcoded, err := s.ffmpeg(data, narrativeExtension, args...)
+	if err != nil {
+		return nil, fmt.Errorf("transcoding audio: %w", err)
+	}
+	return transcoded, nil
+}
+
+// normalizeAudio re-encodes data in its own codec and container with its
+// loudness normalized. extension names the container, e.g. ".mp3".
+func (s *MediaService) normalizeAudio(data []byte, extension string) ([]byte, error) {
+	info, err := s.probeAudio(data)
+	if err != nil {
+		return nil, fmt.Errorf("normalizing audio: %w", err)
+	}
+	encoder, ok := encoders[info.Codec]
+	if !ok {
+		encoder = info.Codec
+	}
+	switch extension {
+	case "":
+		return nil, fmt.Errorf("normalizing audio: no container for %s audio", info.Codec)
+	case ".oga":
+		extension = ".ogg" // ffmpeg writes FLAC to .oga
+	}
+
+	normalized, err := s.ffmpeg(data, extension, "-map", "0:a:0", "-af", s.loudnessFilter(), "-c:a", encoder)
+	if err != nil {
+		return nil, fmt.Errorf("normalizing audio: %w", err)
+	}
+	return normalized, nil
+}
+
+// loudnessFilter returns the ffmpeg filter normalizing loudness to the
+// configured target after EBU R128, or "" if loudness is left as recorded.
+func (s *MediaService) loudnessFilter() string {
+	if s.config.LoudnessTarget == 0 {
+		return ""
+	}
+	return fmt.Sprintf("loudnorm=I=%g:TP=-1.5:LRA=11", s.config.LoudnessTarget)
+}
+
+// ffmpeg runs ffmpeg on data with args, and returns the output file it
+// writes, which has extension. The output is bit-exact, so the same upload
+// always gives the same file; see mediaKey.
+func (s *MediaService) ffmpeg(data []byte, extension string, args ...string) ([]byte, error) {
+	dir, input, err := writeTempInput(data)
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+	output := filepath.Join(dir, "output"+extension)
+
+	cmdArgs := append([]string{"-hide_banner", "-nostdin", "-loglevel", "error", "-i", input}, args...)
+	cmdArgs = append(cmdArgs, "-fflags", "+bitexact", "-flags:a", "+bitexact", output)
+	if _, err := s.runTool("ffmpeg", s.config.FFmpeg, input, cmdArgs...); err != nil {
+		return nil, err
+	}
+	return os.ReadFile(output)
+}
+
+// probeAudio reports the duration, sample rate and codec of the first audio
+// stream of data.
+func (s *MediaService) probeAudio(data []byte) (audioInfo, error) {
+	dir, input, err := writeTempInput(data)
+	if err != nil {
+		return audioInfo{}, err
+	}
+	defer os.RemoveAll(dir)
+
+	out, err := s.runTool("ffprobe", s.config.FFprobe, input,
+		"-v", "error", "-select_streams", "a:0",
+		"-show_entries", "stream=codec_name,sample_rate:format=duration",
+		"-of", "json", input)
+	if err != nil {
+		return audioInfo{}, err
+	}
+
+	var probe struct {
+		Streams []struct {
+			CodecName  string `json:"codec_name"`
+			SampleRate string `json:"sample_rate"`
+		} `json:"streams"`
+		Format struct {
+			Duration string `json:"duration"`
+		} `json:"format"`
+	}
+	if err := json.Unmarshal(out, &probe); err != nil {
+		return audioInfo{}, fmt.Errorf("reading ffprobe output: %w", err)
+	}
+	if len(probe.Streams) == 0 {
+		return audioInfo{}, fmt.Errorf("%w: no audio stream", ErrInvalidAudio)
+	}
+
+	info := audioInfo{Codec: probe.Streams[0].CodecName}
+	info.SampleRate, _ = strconv.Atoi(probe.Streams[0].SampleRate)
+	if seconds, err := strconv.ParseFloat(probe.Format.Duration, 64); err == nil {
+		info.Duration = time.Duration(seconds * float64(time.Second)).Round(time.Millisecond)
+	}
+	return info, nil
+}
+
+// writeTempInput writes data to a file in a new temporary directory, as
+// some formats cannot be read from a pipe. The caller removes dir.
+func writeTempInput(data []byte) (dir, input string, err error) {
+	if dir, err = os.MkdirTemp("", "audio-*"); err != nil {
+		return "", "", err
+	}
+	input = filepath.Join(dir, "input")
+	if err := os.WriteFile(input, data, 0o600); err != nil {
+		os.RemoveAll(dir)
+		return "", "", err
+	}
+	return dir, input, nil
+}
+
+// runTool runs the ffmpeg or ffprobe binary at path, the tool's name if
+// empty, within the configured timeout and returns its standard output.
+// Errors about the input file are reported as ErrInvalidAudio.
+func (s *MediaService) runTool(tool, path, input string, args ...string) ([]byte, error) {
+	if path == "" {
+		path = tool
+	}
+	timeout := s.config.TranscodeTimeout
+	if timeout <= 0 {
+		timeout = defaultTranscodeTimeout
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	cmd := exec.CommandContext(ctx, path, args...)
+	var stdout, stderr bytes.Buffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+
+	err := cmd.Run()
+	switch {
+	case errors.Is(ctx.Err(), context.DeadlineExceeded):
+		return nil, fmt.Errorf("%s did not finish within %s", tool, timeout)
+	case errors.Is(err, exec.ErrNotFound) || errors.Is(err, fs.ErrNotExist):
+		return nil, fmt.Errorf("%s %w at %q; install it or set media.%s", tool, errToolNotFound, path, tool)
+	case err != nil:
+		var exitErr *exec.ExitError
+		if !errors.As(err, &exitErr) {
+			return nil, err
+		}
+		// The tools name the temporary file; the author knows it as the upload
+		msg := strings.ReplaceAll(strings.TrimSpace(stderr.String()), input, "upload")
+		if i := strings.LastIndex(msg, "\n"); i >= 0 {
+			msg = msg[i+1:]
+		}
+		if msg == "" {
+			msg = exitErr.Error()
+		}
+		return nil, fmt.Errorf("%w: %s", ErrInvalidAudio, msg)
+	}
+	return stdout.Bytes(), nil
+}
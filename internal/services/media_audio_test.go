@@ -0,0 +1,292 @@
+// internal/services/media_audio_test.go
+package services
+
+import (
+	"bytes"
+	"context"
+	"errors"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/mocks"
+	"github.com/ceesaxp/tour-guide-editor/internal/storage"
+	"github.com/gabriel-vasile/mimetype"
+)
+
+// testMP3 is detected as audio/mpeg.
+var testMP3 = append([]byte("ID3\x03\x00\x00\x00\x00\x00\x00"), make([]byte, 64)...)
+
+// testProbe is what the fake ffprobe of newAudioTestService reports.
+const testProbe = `{"streams": [{"codec_name": "opus", "sample_rate": "48000"}], "format": {"duration": "61.500000"}}`
+
+// fakeFFmpeg writes a shell script standing in for ffmpeg.
+func fakeFFmpeg(t *testing.T, script string) string {
+	return fakeTool(t, "ffmpeg", script)
+}
+
+// fakeFFprobe writes a shell script standing in for ffprobe, which prints
+// output.
+func fakeFFprobe(t *testing.T, output string) string {
+	return fakeTool(t, "ffprobe", "cat <<'EOF'\n"+output+"\nEOF")
+}
+
+func fakeTool(t *testing.T, name, script string) string {
+	t.Helper()
+	if runtime.GOOS == "windows" {
+		t.Skip("fake " + name + " is a shell script")
+	}
+	path := filepath.Join(t.TempDir(), name)
+	if err := os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0o755); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+// newAudioTestService returns a MediaService storing into a fake bucket.
+// Unless config sets one, its ffprobe reports testProbe.
+func newAudioTestService(t *testing.T, config MediaConfig) *MediaService {
+	if config.FFprobe == "" {
+		config.FFprobe = fakeFFprobe(t, testProbe)
+	}
+	config.MaxFileSize = 1024 * 1024
+	config.AllowedFormats = []string{"image/", "audio/"}
+	return NewMediaService(config, storage.NewS3Store(mocks.NewFakeS3(), "media", "https://media.example.com"))
+}
+
+func TestMediaService_TranscodeAudioNarrative(t *testing.T) {
+	// Writes its arguments to the output file, the last argument
+	ffmpeg := fakeFFmpeg(t, `for arg; do out="$arg"; done; printf 'OggS %s' "$*" > "$out"`)
+	service := newAudioTestService(t, MediaConfig{FFmpeg: ffmpeg, AudioBitrate: 96, AudioChannels: 2})
+
+	narrative, err := service.process(testMP3, "story.mp3", useAudioNarrative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if narrative.MimeType != "audio/ogg" || !strings.HasSuffix(narrative.URL, narrative.Hash+".ogg") {
+		t.Errorf("expected an Ogg file, got %+v", narrative)
+	}
+	body, _, err := service.store.Get(context.Background(), mediaKey(narrative.Hash, ".ogg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	var args bytes.Buffer
+	args.ReadFrom(body)
+	for _, want := range []string{"-c:a libopus", "-b:a 96k", "-ac 2", "-f ogg"} {
+		if !strings.Contains(args.String(), want) {
+			t.Errorf("expected %q in the ffmpeg arguments %q", want, args.String())
+		}
+	}
+
+	// Other uses of audio are stored as uploaded
+	media, err := service.process(testMP3, "story.mp3", useMediaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if media.MimeType != "audio/mpeg" || !strings.HasSuffix(media.URL, ".mp3") {
+		t.Errorf("expected the MP3 as uploaded, got %+v", media)
+	}
+
+	if _, err := service.process(createTestImage(t), "photo.jpg", useAudioNarrative); err == nil {
+		t.Error("expected an image to be rejected as audio narrative")
+	}
+}
+
+func TestMediaService_TranscodeAudioErrors(t *testing.T) {
+	tests := []struct {
+		name    string
+		config  MediaConfig
+		wantErr string
+		invalid bool
+	}{
+		{
+			name:    "corrupt input",
+			config:  MediaConfig{FFmpeg: fakeFFmpeg(t, `echo "$6: Invalid data found when processing input" >&2; exit 1`)},
+			wantErr: "upload: Invalid data found when processing input",
+			invalid: true,
+		},
+		{
+			name:    "timeout",
+			config:  MediaConfig{FFmpeg: fakeFFmpeg(t, `exec sleep 5`), TranscodeTimeout: 100 * time.Millisecond},
+			wantErr: "did not finish within 100ms",
+		},
+		{
+			name:    "missing ffmpeg",
+			config:  MediaConfig{FFmpeg: filepath.Join(t.TempDir(), "ffmpeg")},
+			wantErr: "ffmpeg not found",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newAudioTestService(t, tt.config)
+			_, err := service.process(testMP3, "story.mp3", useAudioNarrative)
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Fatalf("expected error %q, got %v", tt.wantErr, err)
+			}
+			if errors.Is(err, ErrInvalidAudio) != tt.invalid {
+				t.Errorf("errors.Is(err, ErrInvalidAudio) = %v, want %v", !tt.invalid, tt.invalid)
+			}
+		})
+	}
+}
+
+func TestMediaService_TranscodeAudioWithFFmpeg(t *testing.T) {
+	ffmpeg, err := exec.LookPath("ffmpeg")
+	if err != nil {
+		t.Skip("ffmpeg is not installed")
+	}
+	ffprobe, err := exec.LookPath("ffprobe")
+	if err != nil {
+		t.Skip("ffprobe is not installed")
+	}
+	wav, err := exec.Command(ffmpeg, "-hide_banner", "-loglevel", "error",
+		"-f", "lavfi", "-i", "sine=frequency=440:duration=1", "-f", "wav", "pipe:1").Output()
+	if err != nil {
+		t.Fatal(err)
+	}
+	service := newAudioTestService(t, MediaConfig{FFmpeg: ffmpeg, FFprobe: ffprobe})
+
+	first, err := service.process(wav, "tone.wav", useAudioNarrative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.Codec != "opus" || first.SampleRate != 48000 || first.Duration < 900*time.Millisecond || first.Duration > 1100*time.Millisecond {
+		t.Errorf("expected a second of 48 kHz Opus, got %v of %d Hz %s", first.Duration, first.SampleRate, first.Codec)
+	}
+	body, _, err := service.store.Get(context.Background(), mediaKey(first.Hash, ".ogg"))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer body.Close()
+	var data bytes.Buffer
+	data.ReadFrom(body)
+	if mime := mimetype.Detect(data.Bytes()); !mime.Is("audio/ogg") {
+		t.Errorf("expected Ogg audio, got %s", mime)
+	}
+
+	// The output is the same every time, so it is stored once
+	second, err := service.process(wav, "tone.wav", useAudioNarrative)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if second.URL != first.URL {
+		t.Errorf("expected the same file, got %s and %s", first.URL, second.URL)
+	}
+
+	// Normalized audio media files keep their format
+	service = newAudioTestService(t, MediaConfig{FFmpeg: ffmpeg, FFprobe: ffprobe, LoudnessTarget: -16})
+	normalized, err := service.process(wav, "tone.wav", useMediaFile)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.HasSuffix(normalized.URL, ".wav") || normalized.Codec != "pcm_s16le" {
+		t.Errorf("expected a normalized WAV file, got %+v", normalized)
+	}
+}
+
+func TestMediaService_ProbeAudio(t *testing.T) {
+	ffmpeg := fakeFFmpeg(t, `for arg; do out="$arg"; done; printf 'OggS' > "$out"`)
+
+	tests := []struct {
+		name    string
+		ffprobe string
+		data    []byte
+		use     mediaUse
+		want    ProcessedMedia
+		wantErr string
+	}{
+		{
+			name: "audio narrative",
+			data: testMP3,
+			use:  useAudioNarrative,
+			want: ProcessedMedia{Duration: 61500 * time.Millisecond, SampleRate: 48000, Codec: "opus"},
+		},
+		{
+			name: "audio media file",
+			data: testMP3,
+			use:  useMediaFile,
+			want: ProcessedMedia{Duration: 61500 * time.Millisecond, SampleRate: 48000, Codec: "opus"},
+		},
+		{
+			name:    "image",
+			ffprobe: fakeFFprobe(t, `{}`),
+			data:    createTestImage(t),
+			use:     useMediaFile,
+		},
+		{
+			name:    "missing ffprobe",
+			ffprobe: filepath.Join(t.TempDir(), "ffprobe"),
+			data:    testMP3,
+			use:     useMediaFile,
+		},
+		{
+			name:    "no audio stream",
+			ffprobe: fakeFFprobe(t, `{"streams": [], "format": {"duration": "1.0"}}`),
+			data:    testMP3,
+			use:     useMediaFile,
+			wantErr: "no audio stream",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := newAudioTestService(t, MediaConfig{FFmpeg: ffmpeg, FFprobe: tt.ffprobe})
+			processed, err := service.process(tt.data, "file", tt.use)
+			if tt.wantErr != "" {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr) || !errors.Is(err, ErrInvalidAudio) {
+					t.Fatalf("expected invalid audio error %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if processed.Duration != tt.want.Duration || processed.SampleRate != tt.want.SampleRate || processed.Codec != tt.want.Codec {
+				t.Errorf("expected %v of %d Hz %q, got %v of %d Hz %q",
+					tt.want.Duration, tt.want.SampleRate, tt.want.Codec,
+					processed.Duration, processed.SampleRate, processed.Codec)
+			}
+		})
+	}
+}
+
+func TestMediaService_NormalizeLoudness(t *testing.T) {
+	// Writes its arguments to the output file, the last argument
+	ffmpeg := fakeFFmpeg(t, `for arg; do out="$arg"; done; printf '%s' "$*" > "$out"`)
+	mp3Probe := fakeFFprobe(t, `{"streams": [{"codec_name": "mp3", "sample_rate": "44100"}], "format": {"duration": "2.0"}}`)
+
+	service := newAudioTestService(t, MediaConfig{FFmpeg: ffmpeg, FFprobe: mp3Probe})
+	kept, err := service.processAudio(testMP3, "audio/mpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !bytes.Equal(kept, testMP3) {
+		t.Errorf("expected audio to be kept as recorded without a loudness target, got %q", kept)
+	}
+
+	service = newAudioTestService(t, MediaConfig{FFmpeg: ffmpeg, FFprobe: mp3Probe, LoudnessTarget: -16})
+	const filter = "-af loudnorm=I=-16:TP=-1.5:LRA=11"
+
+	transcoded, err := service.transcodeAudio(testMP3)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if !strings.Contains(string(transcoded), filter+" -c:a libopus") {
+		t.Errorf("expected narratives to be normalized while transcoding, got arguments %q", transcoded)
+	}
+
+	normalized, err := service.processAudio(testMP3, "audio/mpeg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	args := string(normalized)
+	if !strings.Contains(args, filter+" -c:a libmp3lame") || !strings.HasSuffix(args, ".mp3") {
+		t.Errorf("expected the MP3 to be normalized to MP3, got arguments %q", args)
+	}
+}
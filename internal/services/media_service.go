@@ -6,111 +6,103 @@ import (
 	"context"
 	"crypto/sha256"
 	"encoding/hex"
+	"errors"
 	"fmt"
 	"io"
+	"log"
 	"mime/multipart"
 	"net/http"
-	"os"
 	"path/filepath"
 	"strings"
 	"time"
 
-	"github.com/aws/aws-sdk-go-v2/aws"
-	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/gabriel-vasile/mimetype"
 	"github.com/h2non/bimg"
 
-	"github.com/ceesaxp/tour-guide-editor/internal/mocks"
+	"github.com/ceesaxp/tour-guide-editor/internal/storage"
 )
 
+// MediaConfig configures how uploads are processed. The bitrate and
+// channels apply to audio narratives, which are transcoded to Ogg/Opus;
+// LoudnessTarget, if set, to all audio. Zero values keep the defaults in
+// media_audio.go.
 type MediaConfig struct {
-	MaxFileSize    int64    `yaml:"max_file_size"`
-	AllowedFormats []string `yaml:"allowed_formats"`
-	ImageMaxWidth  int      `yaml:"image_max_width"`
-	ImageMaxHeight int      `yaml:"image_max_height"`
-	S3Bucket       string   `yaml:"s3_bucket"`
+	MaxFileSize      int64         `yaml:"max_file_size"`
+	AllowedFormats   []string      `yaml:"allowed_formats"`
+	ImageMaxWidth    int           `yaml:"image_max_width"`
+	ImageMaxHeight   int           `yaml:"image_max_height"`
+	FFmpeg           string        `yaml:"ffmpeg"`        // path of the ffmpeg binary
+	FFprobe          string        `yaml:"ffprobe"`       // path of the ffprobe binary
+	AudioBitrate     int           `yaml:"audio_bitrate"` // kbit/s
+	AudioChannels    int           `yaml:"audio_channels"`
+	LoudnessTarget   float64       `yaml:"loudness_target"` // integrated loudness in LUFS, e.g. -16; 0 leaves it as recorded
+	TranscodeTimeout time.Duration `yaml:"transcode_timeout"`
 }
 
 type MediaService struct {
-	config   MediaConfig
-	s3Client mocks.S3Client
+	config MediaConfig
+	store  storage.MediaStore
 }
 
-func NewMediaService(config MediaConfig, s3Client mocks.S3Client) *MediaService {
+// NewMediaService processes uploads and keeps them in store.
+func NewMediaService(config MediaConfig, store storage.MediaStore) *MediaService {
 	return &MediaService{
-		config:   config,
-		s3Client: s3Client,
+		config: config,
+		store:  store,
 	}
 }
 
 type ProcessedMedia struct {
 	URL      string
 	Hash     string
-	MimeType string
+	MimeType string // of the stored file, which may differ from the upload's
 	Size     int64
-}
 
-func (s *MediaService) ProcessAndUpload(file multipart.File, header *multipart.FileHeader) (*ProcessedMedia, error) {
-	// Read file into memory for processing
-	data, err := io.ReadAll(file)
-	if err != nil {
-		return nil, fmt.Errorf("reading file: %w", err)
-	}
+	// Probed from audio files; zero for other media or without ffprobe
+	Duration   time.Duration
+	SampleRate int // Hz
+	Codec      string
+}
 
-	// Check file size
-	if int64(len(data)) > s.config.MaxFileSize {
-		return nil, fmt.Errorf("file too large: %d > %d", len(data), s.config.MaxFileSize)
-	}
+// mediaUse is what an uploaded file is for, which decides how it is
+// processed.
+type mediaUse int
 
-	// Detect MIME type
-	mime := mimetype.Detect(data)
-	if !s.isAllowedFormat(mime.String()) {
-		return nil, fmt.Errorf("unsupported file format: %s", mime.String())
-	}
+const (
+	useMediaFile      mediaUse = iota // media file of a node or message; only images are resized
+	useAudioNarrative                 // audio narrative of a node, transcoded to Ogg/Opus
+)
 
-	// Process media based on type
-	processed, err := s.processMedia(data, mime.String())
-	if err != nil {
-		return nil, fmt.Errorf("processing media: %w", err)
-	}
+func (s *MediaService) ProcessAndUpload(file multipart.File, header *multipart.FileHeader) (*ProcessedMedia, error) {
+	return s.processFile(file, header, useMediaFile)
+}
 
-	// Generate hash
-	hash := sha256.Sum256(processed)
-	hashString := hex.EncodeToString(hash[:])
+// ProcessAudioNarrative stores an audio file uploaded as the audio
+// narrative of a node, transcoded to Ogg/Opus.
+func (s *MediaService) ProcessAudioNarrative(file multipart.File, header *multipart.FileHeader) (*ProcessedMedia, error) {
+	return s.processFile(file, header, useAudioNarrative)
+}
 
-	// Generate S3 key
-	extension := filepath.Ext(header.Filename)
-	s3Key := fmt.Sprintf("%s/%s%s", time.Now().Format("2006/01/02"), hashString, extension)
+func (s *MediaService) ProcessURL(url string) (*ProcessedMedia, error) {
+	return s.processURL(url, useMediaFile)
+}
 
-	// Check if file already exists
-	exists, existingURL, err := s.checkFileExists(hashString)
-	if err != nil {
-		return nil, fmt.Errorf("checking file existence: %w", err)
-	}
-	if exists {
-		return &ProcessedMedia{
-			URL:      existingURL,
-			Hash:     hashString,
-			MimeType: mime.String(),
-			Size:     int64(len(processed)),
-		}, nil
-	}
+// ProcessAudioNarrativeURL is ProcessAudioNarrative for the audio file at
+// url.
+func (s *MediaService) ProcessAudioNarrativeURL(url string) (*ProcessedMedia, error) {
+	return s.processURL(url, useAudioNarrative)
+}
 
-	// Upload to S3
-	url, err := s.uploadToS3(processed, s3Key, mime.String())
+func (s *MediaService) processFile(file multipart.File, header *multipart.FileHeader, use mediaUse) (*ProcessedMedia, error) {
+	// Read file into memory for processing
+	data, err := io.ReadAll(file)
 	if err != nil {
-		return nil, fmt.Errorf("uploading to S3: %w", err)
+		return nil, fmt.Errorf("reading file: %w", err)
 	}
-
-	return &ProcessedMedia{
-		URL:      url,
-		Hash:     hashString,
-		MimeType: mime.String(),
-		Size:     int64(len(processed)),
-	}, nil
+	return s.process(data, header.Filename, use)
 }
 
-func (s *MediaService) ProcessURL(url string) (*ProcessedMedia, error) {
+func (s *MediaService) processURL(url string, use mediaUse) (*ProcessedMedia, error) {
 	// Validate URL
 	if err := s.ValidateURL(url); err != nil {
 		return nil, fmt.Errorf("invalid URL: %w", err)
@@ -129,30 +121,90 @@ func (s *MediaService) ProcessURL(url string) (*ProcessedMedia, error) {
 		return nil, fmt.Errorf("reading response body: %w", err)
 	}
 
-	// Create a temporary file
-	tempFile, err := os.CreateTemp("", "download-*")
-	if err != nil {
-		return nil, fmt.Errorf("creating temporary file: %w", err)
+	return s.process(data, filepath.Base(url), use)
+}
+
+func (s *MediaService) process(data []byte, filename string, use mediaUse) (*ProcessedMedia, error) {
+	// Check file size
+	if int64(len(data)) > s.config.MaxFileSize {
+		return nil, fmt.Errorf("file too large: %d > %d", len(data), s.config.MaxFileSize)
 	}
-	defer os.Remove(tempFile.Name())
-	defer tempFile.Close()
 
-	// Write the data to the temporary file
-	if _, err := tempFile.Write(data); err != nil {
-		return nil, fmt.Errorf("writing to temporary file: %w", err)
+	// Detect MIME type
+	mime := mimetype.Detect(data)
+	if !s.isAllowedFormat(mime.String()) {
+		return nil, fmt.Errorf("unsupported file format: %s", mime.String())
 	}
 
-	// Seek back to the beginning
-	if _, err := tempFile.Seek(0, 0); err != nil {
-		return nil, fmt.Errorf("seeking temporary file: %w", err)
+	// Process media based on type and use
+	var (
+		processed []byte
+		mimeType  string
+		extension string
+		err       error
+	)
+	if use == useAudioNarrative {
+		if !strings.HasPrefix(mime.String(), "audio/") {
+			return nil, fmt.Errorf("an audio narrative must be an audio file, not %s", mime.String())
+		}
+		if processed, err = s.transcodeAudio(data); err != nil {
+			return nil, err
+		}
+		mimeType, extension = narrativeMimeType, narrativeExtension
+	} else {
+		if processed, err = s.processMedia(data, mime.String()); err != nil {
+			return nil, fmt.Errorf("processing media: %w", err)
+		}
+		stored := mimetype.Detect(processed)
+		mimeType, extension = stored.String(), stored.Extension()
+		if extension == "" {
+			extension = strings.ToLower(filepath.Ext(filename))
+		}
 	}
 
-	header := &multipart.FileHeader{
-		Filename: filepath.Base(url),
-		Size:     int64(len(data)),
+	// Probe the stored audio; without ffprobe audio is stored unprobed
+	var info audioInfo
+	if strings.HasPrefix(mimeType, "audio/") {
+		info, err = s.probeAudio(processed)
+		switch {
+		case errors.Is(err, errToolNotFound):
+			log.Printf("WARN: not probing audio: %v", err)
+		case err != nil:
+			return nil, fmt.Errorf("probing audio: %w", err)
+		}
 	}
 
-	return s.ProcessAndUpload(tempFile, header)
+	// Store the file under its content, unless it is stored already
+	hash := sha256.Sum256(processed)
+	hashString := hex.EncodeToString(hash[:])
+	key := mediaKey(hashString, extension)
+
+	exists, url, err := s.checkFileExists(key, int64(len(processed)))
+	if err != nil {
+		return nil, fmt.Errorf("checking file existence: %w", err)
+	}
+	if !exists {
+		if url, err = s.upload(processed, key, mimeType); err != nil {
+			return nil, fmt.Errorf("storing media file: %w", err)
+		}
+	}
+
+	return &ProcessedMedia{
+		URL:        url,
+		Hash:       hashString,
+		MimeType:   mimeType,
+		Size:       int64(len(processed)),
+		Duration:   info.Duration,
+		SampleRate: info.SampleRate,
+		Codec:      info.Codec,
+	}, nil
+}
+
+// mediaKey returns the key a file with the SHA-256 hash is stored under,
+// e.g. "sha256/3f/3fa9…e1.jpg". The same content always gets the same key,
+// and the first two digits spread the keys over 256 directories.
+func mediaKey(hash, extension string) string {
+	return fmt.Sprintf("sha256/%s/%s%s", hash[:2], hash, extension)
 }
 
 func (s *MediaService) processMedia(data []byte, mimeType string) ([]byte, error) {
@@ -160,7 +212,7 @@ func (s *MediaService) processMedia(data []byte, mimeType string) ([]byte, error
 	case strings.HasPrefix(mimeType, "image/"):
 		return s.processImage(data)
 	case strings.HasPrefix(mimeType, "audio/"):
-		return s.processAudio(data)
+		return s.processAudio(data, mimeType)
 	case strings.HasPrefix(mimeType, "video/"):
 		return s.processVideo(data)
 	default:
@@ -195,10 +247,18 @@ func (s *MediaService) processImage(data []byte) ([]byte, error) {
 	return data, nil
 }
 
-func (s *MediaService) processAudio(data []byte) ([]byte, error) {
-	// TODO: Implement audio processing with ffmpeg
-	// For now, return original data
-	return data, nil
+// processAudio keeps audio media files as uploaded, apart from normalizing
+// their loudness if configured; audio narratives are transcoded instead,
+// see transcodeAudio.
+func (s *MediaService) processAudio(data []byte, mimeType string) ([]byte, error) {
+	if s.loudnessFilter() == "" {
+		return data, nil
+	}
+	var extension string
+	if mime := mimetype.Lookup(mimeType); mime != nil {
+		extension = mime.Extension()
+	}
+	return s.normalizeAudio(data, extension)
 }
 
 func (s *MediaService) processVideo(data []byte) ([]byte, error) {
@@ -207,25 +267,26 @@ func (s *MediaService) processVideo(data []byte) ([]byte, error) {
 	return data, nil
 }
 
-func (s *MediaService) uploadToS3(data []byte, key string, contentType string) (string, error) {
-	ctx := context.Background()
-
-	_, err := s.s3Client.PutObject(ctx, &s3.PutObjectInput{
-		Bucket:      aws.String(s.config.S3Bucket),
-		Key:         aws.String(key),
-		Body:        bytes.NewReader(data),
-		ContentType: aws.String(contentType),
-	})
-	if err != nil {
-		return "", fmt.Errorf("uploading to S3: %w", err)
+// upload stores data under key and returns the URL it is served from.
+func (s *MediaService) upload(data []byte, key string, contentType string) (string, error) {
+	if err := s.store.Put(context.Background(), key, bytes.NewReader(data), contentType); err != nil {
+		return "", err
 	}
-
-	return fmt.Sprintf("https://%s.s3.amazonaws.com/%s", s.config.S3Bucket, key), nil
+	return s.store.PublicURL(key), nil
 }
 
-func (s *MediaService) checkFileExists(hash string) (bool, string, error) {
-	// TODO: Implement file existence check in S3 or database
-	return false, hash, nil // File does not exist
+// checkFileExists reports whether the file of size bytes is stored under
+// key already, and the URL it is served from. A file of another size is
+// taken to be a broken upload that is replaced.
+func (s *MediaService) checkFileExists(key string, size int64) (bool, string, error) {
+	obj, err := s.store.Head(context.Background(), key)
+	if errors.Is(err, storage.ErrNotFound) {
+		return false, "", nil
+	}
+	if err != nil {
+		return false, "", err
+	}
+	return obj.Size == size, s.store.PublicURL(key), nil
 }
 
 func (s *MediaService) ValidateURL(url string) error {
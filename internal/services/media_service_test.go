@@ -15,6 +15,7 @@ import (
 
 	"github.com/aws/aws-sdk-go-v2/service/s3"
 	"github.com/ceesaxp/tour-guide-editor/internal/mocks"
+	"github.com/ceesaxp/tour-guide-editor/internal/storage"
 	"github.com/ceesaxp/tour-guide-editor/internal/types"
 	"github.com/h2non/bimg"
 )
@@ -50,10 +51,9 @@ func TestMediaService_ProcessAndUpload(t *testing.T) {
 		AllowedFormats: []string{"image/", "audio/", "video/"},
 		ImageMaxWidth:  800,
 		ImageMaxHeight: 600,
-		S3Bucket:       "test-bucket",
 	}
 
-	service := NewMediaService(config, mockS3)
+	service := NewMediaService(config, storage.NewS3Store(mockS3, "test-bucket", "https://test-bucket.s3.amazonaws.com"))
 
 	tests := []struct {
 		name     string
@@ -116,6 +116,94 @@ func TestMediaService_ProcessAndUpload(t *testing.T) {
 	}
 }
 
+func TestMediaService_Upload(t *testing.T) {
+	files, err := storage.NewFilesystem(t.TempDir(), "")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name  string
+		store storage.MediaStore
+		want  string
+	}{
+		{"S3", storage.NewS3Store(mocks.NewFakeS3(), "test-bucket", "http://localhost:4566/test-bucket/"), "http://localhost:4566/test-bucket/2024/01/01/abc.jpg"},
+		{"filesystem", files, "/media/files/2024/01/01/abc.jpg"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewMediaService(MediaConfig{}, tt.store)
+			url, err := service.upload([]byte("data"), "2024/01/01/abc.jpg", "image/jpeg")
+			if err != nil {
+				t.Fatal(err)
+			}
+			if url != tt.want {
+				t.Errorf("upload() = %q, want %q", url, tt.want)
+			}
+			if obj, err := tt.store.Head(context.Background(), "2024/01/01/abc.jpg"); err != nil || obj.Size != 4 {
+				t.Errorf("expected the file stored, got %+v, %v", obj, err)
+			}
+		})
+	}
+}
+
+// countingS3 counts the uploads to a FakeS3.
+type countingS3 struct {
+	*mocks.FakeS3
+	puts int
+}
+
+func (c *countingS3) PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error) {
+	c.puts++
+	return c.FakeS3.PutObject(ctx, params, optFns...)
+}
+
+func TestMediaService_Deduplication(t *testing.T) {
+	client := &countingS3{FakeS3: mocks.NewFakeS3()}
+	service := NewMediaService(MediaConfig{
+		MaxFileSize:    1024 * 1024,
+		AllowedFormats: []string{"image/"},
+		ImageMaxWidth:  800,
+		ImageMaxHeight: 600,
+	}, storage.NewS3Store(client, "test-bucket", "https://cdn.example.com"))
+
+	upload := func(filename string) *ProcessedMedia {
+		t.Helper()
+		file, err := os.CreateTemp(t.TempDir(), "upload-*")
+		if err != nil {
+			t.Fatal(err)
+		}
+		defer file.Close()
+		if _, err := file.Write(createTestImage(t)); err != nil {
+			t.Fatal(err)
+		}
+		if _, err := file.Seek(0, 0); err != nil {
+			t.Fatal(err)
+		}
+		processed, err := service.ProcessAndUpload(types.NewMultipartFile(file), &multipart.FileHeader{Filename: filename})
+		if err != nil {
+			t.Fatal(err)
+		}
+		return processed
+	}
+
+	first := upload("photo.JPG")
+	want := "https://cdn.example.com/sha256/" + first.Hash[:2] + "/" + first.Hash + ".jpg"
+	if first.URL != want {
+		t.Errorf("expected a content-addressed URL %q, got %q", want, first.URL)
+	}
+
+	// The same content under another name is not stored again
+	second := upload("copy.jpeg")
+	if second.URL != first.URL || second.Hash != first.Hash {
+		t.Errorf("expected the existing file, got %+v", second)
+	}
+	if client.puts != 1 {
+		t.Errorf("expected one upload, got %d", client.puts)
+	}
+}
+
 func TestMediaService_ValidateURL(t *testing.T) {
 	config := MediaConfig{
 		MaxFileSize:    1024 * 1024,
@@ -0,0 +1,193 @@
+// internal/services/tour_catalog.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+// TourSummary describes a stored tour for the dashboard.
+type TourSummary struct {
+	ID       string
+	Name     string
+	Version  string
+	Nodes    int
+	Modified time.Time
+	Archived bool
+}
+
+// ListTours returns the tours owner can see, most recently modified first:
+// the archived ones if archived is set, otherwise the others. Tours without
+// an owner are shared by all users.
+func (s *TourService) ListTours(ctx context.Context, owner string, archived bool) ([]TourSummary, error) {
+	stored, err := s.tours.Summaries(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	var summaries []TourSummary
+	for _, tour := range stored {
+		if !canAccess(tour.Record, owner) || tour.Record.Archived != archived {
+			continue
+		}
+		summaries = append(summaries, TourSummary{
+			ID:       tour.ID,
+			Name:     tour.Name,
+			Version:  tour.Version,
+			Nodes:    tour.Nodes,
+			Modified: tour.Record.Modified,
+			Archived: tour.Record.Archived,
+		})
+	}
+
+	sort.SliceStable(summaries, func(i, j int) bool {
+		return summaries[i].Modified.After(summaries[j].Modified)
+	})
+	return summaries, nil
+}
+
+// canAccess reports whether owner can access the tour with record.
+func canAccess(record models.TourRecord, owner string) bool {
+	return record.Owner == "" || record.Owner == owner
+}
+
+// getTour loads the stored tour id if owner can access it. Other users'
+// tours are reported as not found.
+func (s *TourService) getTour(ctx context.Context, owner, id string) (*models.Tour, error) {
+	tour, err := s.tours.Get(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+	if !canAccess(tour.Record, owner) {
+		return nil, fmt.Errorf("%w: %s", repository.ErrNotFound, id)
+	}
+	return tour, nil
+}
+
+// NewTour returns a new, empty tour owned by owner, under an ID no stored
+// tour uses. Unlike CreateTour it is neither stored nor opened.
+func (s *TourService) NewTour(ctx context.Context, owner string) (*models.Tour, error) {
+	tour := models.NewTour()
+	id, err := s.freshTourID(ctx, tour.ID)
+	if err != nil {
+		return nil, err
+	}
+	tour.ID = id
+	tour.Record.Owner = owner
+	return tour, nil
+}
+
+// CreateTour stores a new, empty tour owned by owner and makes it the tour
+// the authenticated user of ctx is editing. It is a draft until its details
+// are filled in.
+func (s *TourService) CreateTour(ctx context.Context, owner string) (*models.Tour, error) {
+	tour := models.NewTour()
+	id, err := s.freshTourID(ctx, tour.ID)
+	if err != nil {
+		return nil, err
+	}
+	tour.ID = id
+	tour.Record.Owner = owner
+
+	s.drafts.Store(tour, true)
+	_, err = s.replaceTour(ctx, tour, editChange("Created tour"), func(*models.Tour) (*models.Tour, error) {
+		return tour, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return tour, nil
+}
+
+// DuplicateTour stores a copy of the tour id under a new ID, with new media
+// file IDs, owned by owner. The copy is not opened.
+func (s *TourService) DuplicateTour(ctx context.Context, owner, id string) (*models.Tour, error) {
+	original, err := s.getTour(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+
+	tour, err := copyTour(original)
+	if err != nil {
+		return nil, err
+	}
+	for _, file := range tour.AllMediaFiles() {
+		file.ID = ""
+	}
+	tour.AssignMediaIDs()
+	tour.Record = models.TourRecord{Owner: owner, Modified: time.Now()}
+
+	// Another tour may take the fresh ID before the copy is stored
+	for stored := false; !stored; {
+		if tour.ID, err = s.freshTourID(ctx, original.ID+"-copy"); err != nil {
+			return nil, err
+		}
+		if stored, err = s.storeNewTour(ctx, tour); err != nil {
+			return nil, err
+		}
+	}
+	return tour, nil
+}
+
+// storeNewTour stores tour unless a tour is already stored under its ID,
+// holding the ID's lock so that no other tour is stored under it meanwhile.
+// It reports whether tour was stored.
+func (s *TourService) storeNewTour(ctx context.Context, tour *models.Tour) (bool, error) {
+	lock := s.locks.id(tour.ID)
+	lock.Lock()
+	defer lock.Unlock()
+
+	if _, err := s.tours.Get(ctx, tour.ID); err == nil {
+		return false, nil
+	} else if !errors.Is(err, repository.ErrNotFound) {
+		return false, fmt.Errorf("loading tour: %w", err)
+	}
+	if err := s.tours.Save(ctx, tour); err != nil {
+		return false, fmt.Errorf("saving tour: %w", err)
+	}
+	return true, nil
+}
+
+// SetArchived archives or restores the tour id. Archived tours are only
+// listed on request. Archiving does not count as a revision of the tour.
+func (s *TourService) SetArchived(ctx context.Context, owner, id string, archived bool) error {
+	lock := s.locks.id(id)
+	lock.Lock()
+	defer lock.Unlock()
+
+	tour, err := s.getTour(ctx, owner, id)
+	if err != nil {
+		return err
+	}
+	tour.Record.Archived = archived
+	tour.Record.Modified = time.Now()
+	if err := s.tours.Save(ctx, tour); err != nil {
+		return fmt.Errorf("saving tour: %w", err)
+	}
+	return nil
+}
+
+// freshTourID returns base, or base with a number appended, such that no
+// stored tour uses it.
+func (s *TourService) freshTourID(ctx context.Context, base string) (string, error) {
+	ids, err := s.tours.List(ctx)
+	if err != nil {
+		return "", err
+	}
+	taken := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		taken[id] = true
+	}
+
+	id := base
+	for n := 2; taken[id]; n++ {
+		id = fmt.Sprintf("%s-%d", base, n)
+	}
+	return id, nil
+}
@@ -0,0 +1,182 @@
+// internal/services/tour_catalog_test.go
+package services
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+func TestTourService_Catalog(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+
+	shared := newTestTour()
+	shared.Nodes[0].MediaFiles = []models.MediaFile{{ID: "m1", Type: "image", URI: "http://example.com/a.jpg"}}
+	if err := service.SaveTour(ctx, shared); err != nil {
+		t.Fatal(err)
+	}
+
+	created, err := service.CreateTour(ctx, "admin")
+	if err != nil {
+		t.Fatalf("CreateTour() error = %v", err)
+	}
+	if service.GetCurrentTour(ctx) != created || !service.IsDraft(created) {
+		t.Error("expected the new tour to be opened as a draft")
+	}
+
+	list := func(owner string, archived bool) []string {
+		t.Helper()
+		summaries, err := service.ListTours(ctx, owner, archived)
+		if err != nil {
+			t.Fatal(err)
+		}
+		var ids []string
+		for _, summary := range summaries {
+			ids = append(ids, summary.ID)
+		}
+		return ids
+	}
+
+	if ids := list("admin", false); len(ids) != 2 || ids[0] != created.ID {
+		t.Errorf("expected the new tour first, then the shared one, got %v", ids)
+	}
+	if ids := list("user", false); len(ids) != 1 || ids[0] != "test_tour" {
+		t.Errorf("expected another user to only see the shared tour, got %v", ids)
+	}
+	if _, err := service.OpenTour(ctx, "user", created.ID); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected another user's tour to be hidden, got %v", err)
+	}
+
+	// Duplicate
+	copied, err := service.DuplicateTour(ctx, "user", "test_tour")
+	if err != nil {
+		t.Fatalf("DuplicateTour() error = %v", err)
+	}
+	if copied.ID != "test_tour-copy" || copied.Record.Owner != "user" || len(copied.Nodes) != 3 {
+		t.Errorf("unexpected copy %s owned by %q", copied.ID, copied.Record.Owner)
+	}
+	if id := copied.Nodes[0].MediaFiles[0].ID; id == "" || id == "m1" {
+		t.Errorf("expected a fresh media ID, got %q", id)
+	}
+	again, err := service.DuplicateTour(ctx, "user", "test_tour")
+	if err != nil || again.ID != "test_tour-copy-2" {
+		t.Errorf("expected a second copy with a new ID, got %v, %v", again, err)
+	}
+
+	// Archive and restore
+	if err := service.SetArchived(ctx, "user", "test_tour-copy", true); err != nil {
+		t.Fatal(err)
+	}
+	if ids := list("user", true); len(ids) != 1 || ids[0] != "test_tour-copy" {
+		t.Errorf("expected the archived tour, got %v", ids)
+	}
+	for _, id := range list("user", false) {
+		if id == "test_tour-copy" {
+			t.Error("archived tour listed with the active ones")
+		}
+	}
+	if err := service.SetArchived(ctx, "admin", "test_tour-copy", false); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected another user's tour to be hidden, got %v", err)
+	}
+	if err := service.SetArchived(ctx, "user", "test_tour-copy", false); err != nil {
+		t.Fatal(err)
+	}
+	if ids := list("user", true); len(ids) != 0 {
+		t.Errorf("expected no archived tours, got %v", ids)
+	}
+}
+
+func TestTourService_NewToursOfTwoUsers(t *testing.T) {
+	tours := repository.NewMemory()
+	service := NewTourService(tours)
+	alice, bob := userContext("alice"), userContext("bob")
+
+	first, err := service.NewTour(alice, "alice")
+	if err != nil {
+		t.Fatal(err)
+	}
+	second, err := service.NewTour(bob, "bob")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if first.ID == second.ID {
+		t.Errorf("expected new tours to get different IDs, both got %s", first.ID)
+	}
+
+	// Should two unsaved tours share an ID, the first one saved keeps it and
+	// the other one sees neither its content nor its revision
+	fill := func(tour *models.Tour, name string) {
+		filled := newTestTour()
+		filled.ID = "tour-shared"
+		filled.Name.Set("", name)
+		filled.Record = tour.Record
+		*tour = *filled
+	}
+	fill(first, "Alice's Tour")
+	fill(second, "Bob's Tour")
+	if err := service.SaveTour(bob, second); err != nil {
+		t.Fatal(err)
+	}
+	err = service.SaveTour(WithRevision(alice, AnyRevision), first)
+	if !errors.Is(err, ErrTourIDTaken) {
+		t.Errorf("expected ErrTourIDTaken, got %v", err)
+	}
+	if first.Name.String() != "Alice's Tour" || first.Record.Owner != "alice" {
+		t.Errorf("alice's tour took bob's: %q owned by %s", first.Name, first.Record.Owner)
+	}
+	if stored, _ := tours.Get(alice, "tour-shared"); stored.Name.String() != "Bob's Tour" {
+		t.Errorf("bob's tour was overwritten by %q", stored.Name)
+	}
+}
+
+// slowSaves makes concurrent saves overlap.
+type slowSaves struct{ *repository.Memory }
+
+func (r slowSaves) Save(ctx context.Context, tour *models.Tour) error {
+	time.Sleep(10 * time.Millisecond)
+	return r.Memory.Save(ctx, tour)
+}
+
+func TestTourService_ConcurrentDuplicates(t *testing.T) {
+	tours := slowSaves{repository.NewMemory()}
+	service := NewTourService(tours)
+	ctx := newTestContext()
+	if err := service.SaveTour(ctx, newTestTour()); err != nil {
+		t.Fatal(err)
+	}
+
+	const copies = 8
+	ids := make(chan string, copies)
+	var wg sync.WaitGroup
+	for i := 0; i < copies; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			copied, err := service.DuplicateTour(ctx, "admin", "test_tour")
+			if err != nil {
+				t.Error(err)
+				return
+			}
+			ids <- copied.ID
+		}()
+	}
+	wg.Wait()
+	close(ids)
+
+	seen := make(map[string]bool)
+	for id := range ids {
+		if seen[id] {
+			t.Errorf("two copies were stored as %s", id)
+		}
+		seen[id] = true
+	}
+	if stored, _ := tours.List(ctx); len(stored) != copies+1 {
+		t.Errorf("expected %d tours, got %v", copies+1, stored)
+	}
+}
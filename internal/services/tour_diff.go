@@ -0,0 +1,191 @@
+// internal/services/tour_diff.go
+package services
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of DiffLine.
+const (
+	DiffSame    = "same"
+	DiffRemoved = "removed"
+	DiffAdded   = "added"
+	DiffSkipped = "skipped" // unchanged lines left out between changes
+)
+
+// DiffLine is a line of the YAML of two versions of a tour.
+type DiffLine struct {
+	Kind string
+	Text string
+}
+
+// diffContext is the number of unchanged lines shown around a change.
+const diffContext = 2
+
+// DiffTours compares the YAML of two versions of a tour line by line. Only
+// the changed lines and the lines around them are returned; nil means the
+// versions are the same.
+func DiffTours(from, to *models.Tour) ([]DiffLine, error) {
+	a, err := yaml.Marshal(from)
+	if err != nil {
+		return nil, fmt.Errorf("rendering tour YAML: %w", err)
+	}
+	b, err := yaml.Marshal(to)
+	if err != nil {
+		return nil, fmt.Errorf("rendering tour YAML: %w", err)
+	}
+	return trimDiff(diffLines(splitLines(a), splitLines(b))), nil
+}
+
+func splitLines(data []byte) []string {
+	text := strings.TrimSuffix(string(data), "\n")
+	if text == "" {
+		return nil
+	}
+	return strings.Split(text, "\n")
+}
+
+// maxDiffCells bounds the work of comparing the changed middle of two
+// tours, counted as lines of one times lines of the other. Beyond it the
+// middle is shown as removed and added as a whole.
+const maxDiffCells = 1 << 24
+
+// diffLines returns the edit script turning a into b, based on their
+// longest common subsequence of lines. The lines a and b start and end
+// with are matched first; the rest is compared in linear space, see
+// diffMiddle.
+func diffLines(a, b []string) []DiffLine {
+	prefix := 0
+	for prefix < len(a) && prefix < len(b) && a[prefix] == b[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(a)-prefix && suffix < len(b)-prefix && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+
+	lines := make([]DiffLine, 0, len(a)+len(b)-prefix-suffix)
+	for _, line := range a[:prefix] {
+		lines = append(lines, DiffLine{Kind: DiffSame, Text: line})
+	}
+	midA, midB := a[prefix:len(a)-suffix], b[prefix:len(b)-suffix]
+	if len(midA)*len(midB) > maxDiffCells {
+		lines = appendLines(lines, DiffRemoved, midA)
+		lines = appendLines(lines, DiffAdded, midB)
+	} else {
+		lines = diffMiddle(lines, midA, midB)
+	}
+	for _, line := range a[len(a)-suffix:] {
+		lines = append(lines, DiffLine{Kind: DiffSame, Text: line})
+	}
+	return lines
+}
+
+// diffMiddle appends the edit script turning a into b to lines, splitting
+// a in half and b where the longest common subsequence crosses the split
+// (Hirschberg's algorithm).
+func diffMiddle(lines []DiffLine, a, b []string) []DiffLine {
+	switch {
+	case len(a) == 0:
+		return appendLines(lines, DiffAdded, b)
+	case len(b) == 0:
+		return appendLines(lines, DiffRemoved, a)
+	case len(a) == 1:
+		for j, line := range b {
+			if line == a[0] {
+				lines = appendLines(lines, DiffAdded, b[:j])
+				lines = append(lines, DiffLine{Kind: DiffSame, Text: line})
+				return appendLines(lines, DiffAdded, b[j+1:])
+			}
+		}
+		lines = append(lines, DiffLine{Kind: DiffRemoved, Text: a[0]})
+		return appendLines(lines, DiffAdded, b)
+	}
+
+	mid := len(a) / 2
+	before := commonBefore(a[:mid], b)
+	after := commonAfter(a[mid:], b)
+	split := 0
+	for j := range before {
+		if before[j]+after[j] > before[split]+after[split] {
+			split = j
+		}
+	}
+	lines = diffMiddle(lines, a[:mid], b[:split])
+	return diffMiddle(lines, a[mid:], b[split:])
+}
+
+// commonBefore returns, for each j, the length of the longest common
+// subsequence of a and b[:j].
+func commonBefore(a, b []string) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := range a {
+		for j := range b {
+			if a[i] == b[j] {
+				cur[j+1] = prev[j] + 1
+			} else {
+				cur[j+1] = max(prev[j+1], cur[j])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+// commonAfter returns, for each j, the length of the longest common
+// subsequence of a and b[j:].
+func commonAfter(a, b []string) []int {
+	prev, cur := make([]int, len(b)+1), make([]int, len(b)+1)
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				cur[j] = prev[j+1] + 1
+			} else {
+				cur[j] = max(prev[j], cur[j+1])
+			}
+		}
+		prev, cur = cur, prev
+	}
+	return prev
+}
+
+func appendLines(lines []DiffLine, kind string, texts []string) []DiffLine {
+	for _, text := range texts {
+		lines = append(lines, DiffLine{Kind: kind, Text: text})
+	}
+	return lines
+}
+
+// trimDiff leaves out the unchanged lines further than diffContext lines
+// from a change.
+func trimDiff(lines []DiffLine) []DiffLine {
+	keep := make([]bool, len(lines))
+	changed := false
+	for i, line := range lines {
+		if line.Kind == DiffSame {
+			continue
+		}
+		changed = true
+		for j := max(0, i-diffContext); j <= min(len(lines)-1, i+diffContext); j++ {
+			keep[j] = true
+		}
+	}
+	if !changed {
+		return nil
+	}
+
+	var trimmed []DiffLine
+	for i, line := range lines {
+		switch {
+		case keep[i]:
+			trimmed = append(trimmed, line)
+		case len(trimmed) == 0 || trimmed[len(trimmed)-1].Kind != DiffSkipped:
+			trimmed = append(trimmed, DiffLine{Kind: DiffSkipped})
+		}
+	}
+	return trimmed
+}
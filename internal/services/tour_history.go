@@ -0,0 +1,355 @@
+// internal/services/tour_history.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+var (
+	// ErrRevisionNotFound is returned for revisions that are not in the
+	// history of a tour.
+	ErrRevisionNotFound = errors.New("revision not found")
+	// ErrNothingToUndo is returned by Undo when no change can be undone.
+	ErrNothingToUndo = errors.New("nothing to undo")
+	// ErrNothingToRedo is returned by Redo when no undone change can be
+	// redone.
+	ErrNothingToRedo = errors.New("nothing to redo")
+)
+
+// maxRevisions is the number of revisions kept in the history of a tour.
+// Older ones are dropped, and changes back to them can no longer be undone.
+const maxRevisions = 100
+
+// Revision is a saved change to a tour, as kept in its history.
+type Revision struct {
+	Number int
+	Author string // user who made the change; empty if unknown
+	Time   time.Time
+	Change string // what was changed, e.g. "Saved node 3"
+
+	tour *models.Tour // the tour as saved, never changed
+}
+
+// historyStep is how a change moves through the undo history of a tour.
+type historyStep int
+
+const (
+	stepEdit historyStep = iota // a new change, which can be undone
+	stepUndo
+	stepRedo
+)
+
+// tourChange describes a change for the history of a tour.
+type tourChange struct {
+	text string
+	step historyStep
+}
+
+func editChange(text string) tourChange {
+	return tourChange{text: text, step: stepEdit}
+}
+
+// undoStep is a revision the tour goes back or forward to when a change is
+// undone or redone.
+type undoStep struct {
+	revision int
+	change   string // the change undone or redone
+}
+
+// revisionLog is the history of one tour. Revisions are only appended:
+// undoing a change saves the revision it goes back to as a new one.
+type revisionLog struct {
+	revisions []Revision
+	undo      []undoStep
+	redo      []undoStep
+}
+
+func (l *revisionLog) revision(number int) *Revision {
+	for i := len(l.revisions) - 1; i >= 0; i-- {
+		if l.revisions[i].Number == number {
+			return &l.revisions[i]
+		}
+	}
+	return nil
+}
+
+// trim drops the oldest revisions beyond maxRevisions, and the undo and redo
+// steps back to them.
+func (l *revisionLog) trim() {
+	extra := len(l.revisions) - maxRevisions
+	if extra <= 0 {
+		return
+	}
+	l.revisions = append([]Revision(nil), l.revisions[extra:]...)
+	for _, steps := range []*[]undoStep{&l.undo, &l.redo} {
+		kept := (*steps)[:0]
+		for _, step := range *steps {
+			if l.revision(step.revision) != nil {
+				kept = append(kept, step)
+			}
+		}
+		*steps = kept
+	}
+}
+
+func (l *revisionLog) steps(step historyStep) *[]undoStep {
+	if step == stepUndo {
+		return &l.undo
+	}
+	return &l.redo
+}
+
+// tourHistory keeps the revision history of tours by ID, the last
+// maxRevisions of each, while users have them open; see forget. It is not
+// stored. The history of a tour starts at the stored revision it was first
+// changed from.
+type tourHistory struct {
+	mu   sync.Mutex
+	logs map[string]*revisionLog
+}
+
+func (h *tourHistory) log(id string) *revisionLog {
+	if h.logs == nil {
+		h.logs = make(map[string]*revisionLog)
+	}
+	l, ok := h.logs[id]
+	if !ok {
+		l = &revisionLog{}
+		h.logs[id] = l
+	}
+	return l
+}
+
+// forget drops the history of the tour id, once no user has it open.
+func (h *tourHistory) forget(id string) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	delete(h.logs, id)
+}
+
+// begin starts the history of the stored tour with its current revision.
+func (h *tourHistory) begin(stored *models.Tour) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l := h.log(stored.ID)
+	if len(l.revisions) > 0 {
+		return
+	}
+	snapshot, err := copyTour(stored)
+	if err != nil {
+		log.Printf("ERR: recording revision %d of tour %s: %v", stored.Record.Revision, stored.ID, err)
+		return
+	}
+	l.revisions = append(l.revisions, Revision{
+		Number: stored.Record.Revision,
+		Time:   stored.Record.Modified,
+		Change: "Stored revision",
+		tour:   snapshot,
+	})
+}
+
+// record appends the revision tour was saved as by author. A tour renamed
+// by the change, formerly previousID, takes its history along.
+func (h *tourHistory) record(previousID string, tour *models.Tour, author string, change tourChange) {
+	if tour.ID == "" {
+		return // not stored yet
+	}
+	snapshot, err := copyTour(tour)
+	if err != nil {
+		log.Printf("ERR: recording revision %d of tour %s: %v", tour.Record.Revision, tour.ID, err)
+		return
+	}
+
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l := h.log(tour.ID)
+	if previous, ok := h.logs[previousID]; ok && previousID != tour.ID && len(l.revisions) == 0 {
+		l.revisions = append(l.revisions, previous.revisions...)
+		l.undo = append(l.undo, previous.undo...)
+		l.redo = append(l.redo, previous.redo...)
+	}
+
+	before := tour.Record.Revision - 1
+	text := change.text
+	switch change.step {
+	case stepEdit:
+		if l.revision(before) != nil {
+			l.undo = append(l.undo, undoStep{revision: before, change: text})
+		}
+		l.redo = nil
+	case stepUndo, stepRedo:
+		steps := l.steps(change.step)
+		if len(*steps) == 0 {
+			break // see undoRedo, which checks under the tour's lock
+		}
+		done := (*steps)[len(*steps)-1]
+		*steps = (*steps)[:len(*steps)-1]
+		back := undoStep{revision: before, change: done.change}
+		if change.step == stepUndo {
+			l.redo = append(l.redo, back)
+			text = "Undid: " + done.change
+		} else {
+			l.undo = append(l.undo, back)
+			text = "Redid: " + done.change
+		}
+	}
+
+	l.revisions = append(l.revisions, Revision{
+		Number: tour.Record.Revision,
+		Author: author,
+		Time:   tour.Record.Modified,
+		Change: text,
+		tour:   snapshot,
+	})
+	l.trim()
+}
+
+// next returns the revision Undo or Redo of the tour id goes to, and the
+// change it undoes or redoes.
+func (h *tourHistory) next(id string, step historyStep) (*models.Tour, string, bool) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l, ok := h.logs[id]
+	if !ok {
+		return nil, "", false
+	}
+	steps := *l.steps(step)
+	if len(steps) == 0 {
+		return nil, "", false
+	}
+	last := steps[len(steps)-1]
+	revision := l.revision(last.revision)
+	if revision == nil {
+		return nil, "", false
+	}
+	return revision.tour, last.change, true
+}
+
+func (h *tourHistory) revisions(id string) []Revision {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	l, ok := h.logs[id]
+	if !ok {
+		return nil
+	}
+	revisions := make([]Revision, len(l.revisions))
+	for i, revision := range l.revisions {
+		revisions[len(revisions)-1-i] = revision
+	}
+	return revisions
+}
+
+func (h *tourHistory) snapshot(id string, number int) (*models.Tour, error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if l, ok := h.logs[id]; ok {
+		if revision := l.revision(number); revision != nil {
+			return revision.tour, nil
+		}
+	}
+	return nil, fmt.Errorf("%w: %d", ErrRevisionNotFound, number)
+}
+
+// History returns the revisions of the tour, newest first.
+func (s *TourService) History(tour *models.Tour) []Revision {
+	var id string
+	s.ViewTour(tour, func(tour *models.Tour) { id = tour.ID })
+	return s.history.revisions(id)
+}
+
+// GetRevision returns a copy of the tour as it was saved at revision
+// number.
+func (s *TourService) GetRevision(tour *models.Tour, number int) (*models.Tour, error) {
+	var id string
+	s.ViewTour(tour, func(tour *models.Tour) { id = tour.ID })
+	snapshot, err := s.history.snapshot(id, number)
+	if err != nil {
+		return nil, err
+	}
+	return copyTour(snapshot)
+}
+
+// DiffRevision compares revision number of the tour with its current state.
+func (s *TourService) DiffRevision(tour *models.Tour, number int) ([]DiffLine, error) {
+	var (
+		diff []DiffLine
+		err  error
+	)
+	s.ViewTour(tour, func(tour *models.Tour) {
+		var snapshot *models.Tour
+		if snapshot, err = s.history.snapshot(tour.ID, number); err == nil {
+			diff, err = DiffTours(snapshot, tour)
+		}
+	})
+	return diff, err
+}
+
+// RestoreRevision saves revision number of the tour as its next revision.
+// The tour keeps its ID.
+func (s *TourService) RestoreRevision(ctx context.Context, tour *models.Tour, number int) error {
+	change := editChange(fmt.Sprintf("Restored revision %d", number))
+	_, err := s.replaceTour(ctx, tour, change, func(tour *models.Tour) (*models.Tour, error) {
+		snapshot, err := s.history.snapshot(tour.ID, number)
+		if err != nil {
+			return nil, err
+		}
+		return tour, s.restoreSnapshot(tour, snapshot)
+	})
+	return err
+}
+
+// Undo reverts the last change to the tour that was not undone yet, by
+// saving the revision before it as the next revision.
+func (s *TourService) Undo(ctx context.Context, tour *models.Tour) error {
+	return s.undoRedo(ctx, tour, stepUndo, ErrNothingToUndo)
+}
+
+// Redo makes the last change undone by Undo again, unless the tour was
+// changed since.
+func (s *TourService) Redo(ctx context.Context, tour *models.Tour) error {
+	return s.undoRedo(ctx, tour, stepRedo, ErrNothingToRedo)
+}
+
+func (s *TourService) undoRedo(ctx context.Context, tour *models.Tour, step historyStep, none error) error {
+	_, err := s.replaceTour(ctx, tour, tourChange{step: step}, func(tour *models.Tour) (*models.Tour, error) {
+		snapshot, _, ok := s.history.next(tour.ID, step)
+		if !ok {
+			return nil, none
+		}
+		return tour, s.restoreSnapshot(tour, snapshot)
+	})
+	return err
+}
+
+// UndoRedo describes the changes Undo and Redo of the tour would revert and
+// make again; empty if there are none.
+func (s *TourService) UndoRedo(tour *models.Tour) (undo, redo string) {
+	var id string
+	s.ViewTour(tour, func(tour *models.Tour) { id = tour.ID })
+	_, undo, _ = s.history.next(id, stepUndo)
+	_, redo, _ = s.history.next(id, stepRedo)
+	return undo, redo
+}
+
+// restoreSnapshot makes tour a copy of a revision snapshot, keeping its ID
+// and record. A revision that does not validate is restored as a draft.
+func (s *TourService) restoreSnapshot(tour, snapshot *models.Tour) error {
+	restored, err := copyTour(snapshot)
+	if err != nil {
+		return err
+	}
+	restored.ID = tour.ID
+	restored.Record = tour.Record
+	if s.ValidateTour(restored) != nil {
+		s.drafts.Store(tour, true)
+	}
+	*tour = *restored
+	return nil
+}
@@ -0,0 +1,206 @@
+// internal/services/tour_history_test.go
+package services
+
+import (
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+func TestTourService_History(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+	tour := newTestTour()
+	if err := service.SaveTour(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{4, 5} {
+		if err := service.SaveNode(ctx, tour, newRevisionTestNode(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	history := service.History(tour)
+	if len(history) != 3 {
+		t.Fatalf("expected 3 revisions, got %d", len(history))
+	}
+	if history[0].Number != 3 || history[0].Change != "Saved node 5" || history[0].Author != "test-user" {
+		t.Errorf("unexpected newest revision %+v", history[0])
+	}
+	if history[2].Number != 1 || history[2].Change != "Saved tour" {
+		t.Errorf("unexpected oldest revision %+v", history[2])
+	}
+
+	// Viewing and comparing revisions
+	old, err := service.GetRevision(tour, 2)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if old.GetNode(4) == nil || old.GetNode(5) != nil {
+		t.Errorf("unexpected nodes in revision 2: %+v", old.Nodes)
+	}
+	if diff, err := service.DiffRevision(tour, 2); err != nil || len(diff) == 0 {
+		t.Errorf("expected revision 2 to differ, got %v, %v", diff, err)
+	}
+	if _, err := service.GetRevision(tour, 99); !errors.Is(err, ErrRevisionNotFound) {
+		t.Errorf("expected ErrRevisionNotFound, got %v", err)
+	}
+
+	// Restoring saves the old revision as a new one
+	if err := service.RestoreRevision(ctx, tour, 1); err != nil {
+		t.Fatal(err)
+	}
+	if tour.GetNode(4) != nil || tour.Record.Revision != 4 {
+		t.Errorf("expected revision 1 restored as revision 4, got revision %d with %d nodes", tour.Record.Revision, len(tour.Nodes))
+	}
+	if history := service.History(tour); len(history) != 4 || history[0].Change != "Restored revision 1" {
+		t.Errorf("expected the restore appended to the history, got %+v", history)
+	}
+}
+
+func TestTourService_UndoRedo(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+	tour := newTestTour()
+	if err := service.SaveTour(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+	for _, id := range []int{4, 5} {
+		if err := service.SaveNode(ctx, tour, newRevisionTestNode(id)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if err := service.Undo(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+	if tour.GetNode(5) != nil || tour.GetNode(4) == nil {
+		t.Errorf("expected node 5 undone, got %+v", tour.Nodes)
+	}
+	if undo, redo := service.UndoRedo(tour); undo != "Saved node 4" || redo != "Saved node 5" {
+		t.Errorf("unexpected undo %q and redo %q", undo, redo)
+	}
+	if err := service.Undo(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+	if tour.GetNode(4) != nil {
+		t.Error("expected node 4 undone")
+	}
+
+	// The tour was not stored before its first revision
+	if err := service.Undo(ctx, tour); !errors.Is(err, ErrNothingToUndo) {
+		t.Errorf("expected ErrNothingToUndo, got %v", err)
+	}
+
+	if err := service.Redo(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+	if tour.GetNode(4) == nil || tour.GetNode(5) != nil {
+		t.Errorf("expected node 4 redone, got %+v", tour.Nodes)
+	}
+	if history := service.History(tour); history[0].Change != "Redid: Saved node 4" || history[1].Change != "Undid: Saved node 4" {
+		t.Errorf("unexpected history %+v", history[:2])
+	}
+
+	// A new change cannot be followed by redoing older ones
+	if err := service.SaveNode(ctx, tour, newRevisionTestNode(6)); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.Redo(ctx, tour); !errors.Is(err, ErrNothingToRedo) {
+		t.Errorf("expected ErrNothingToRedo, got %v", err)
+	}
+	if err := service.Undo(ctx, tour); err != nil || tour.GetNode(6) != nil {
+		t.Errorf("expected node 6 undone, got %v", err)
+	}
+}
+
+func TestTourService_HistoryOfStoredTour(t *testing.T) {
+	store := repository.NewMemory()
+	tour := newTestTour()
+	if err := NewTourService(store).SaveTour(newTestContext(), tour); err != nil {
+		t.Fatal(err)
+	}
+
+	// A restarted service starts the history at the stored revision
+	service := NewTourService(store)
+	ctx := newTestContext()
+	opened, err := service.OpenTour(ctx, "", tour.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SaveNode(ctx, opened, newRevisionTestNode(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	history := service.History(opened)
+	if len(history) != 2 || history[1].Number != 1 || history[1].Change != "Stored revision" {
+		t.Fatalf("unexpected history %+v", history)
+	}
+	if err := service.Undo(ctx, opened); err != nil {
+		t.Fatal(err)
+	}
+	if opened.GetNode(4) != nil || opened.Record.Revision != 3 {
+		t.Errorf("expected node 4 undone as revision 3, got revision %d", opened.Record.Revision)
+	}
+}
+
+func TestTourService_HistoryLifetime(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	service.SetSessionTTL(time.Hour)
+	now := time.Date(2024, 6, 1, 12, 0, 0, 0, time.UTC)
+	service.sessions.now = func() time.Time { return now }
+
+	alice, bob := userContext("alice"), userContext("bob")
+	tour := newTestTour()
+	if err := service.SaveTour(alice, tour); err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < maxRevisions+5; i++ {
+		if err := service.SaveNode(alice, tour, newRevisionTestNode(4)); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	// Only the last revisions are kept, and undo stays within them
+	history := service.History(tour)
+	if len(history) != maxRevisions || history[len(history)-1].Number != tour.Record.Revision-maxRevisions+1 {
+		t.Fatalf("expected the last %d revisions, got %d from %d", maxRevisions, len(history), history[len(history)-1].Number)
+	}
+	for {
+		err := service.Undo(alice, tour)
+		if errors.Is(err, ErrNothingToUndo) {
+			break
+		}
+		if err != nil {
+			t.Fatalf("Undo() error = %v", err)
+		}
+	}
+	if len(service.History(tour)) != maxRevisions {
+		t.Errorf("expected undoing to keep %d revisions, got %d", maxRevisions, len(service.History(tour)))
+	}
+
+	// The history lasts while anyone has the tour open
+	if _, err := service.OpenTour(bob, "", tour.ID); err != nil {
+		t.Fatal(err)
+	}
+	now = now.Add(30 * time.Minute)
+	if service.GetCurrentTour(bob) == nil {
+		t.Fatal("expected bob's tour to stay open")
+	}
+	now = now.Add(45 * time.Minute)
+	if service.GetCurrentTour(alice) != nil {
+		t.Fatal("expected alice's tour to be closed")
+	}
+	if len(service.History(tour)) == 0 {
+		t.Error("expected the history to be kept while bob has the tour open")
+	}
+	now = now.Add(time.Hour)
+	if service.GetCurrentTour(bob) != nil {
+		t.Fatal("expected bob's tour to be closed")
+	}
+	if history := service.History(tour); len(history) != 0 {
+		t.Errorf("expected the history to be dropped, got %d revisions", len(history))
+	}
+}
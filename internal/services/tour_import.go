@@ -0,0 +1,230 @@
+// internal/services/tour_import.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Import modes for ImportOptions.Mode.
+const (
+	ImportReplace = "replace" // the imported tour replaces the current one
+	ImportMerge   = "merge"   // imported nodes and edges are added to the current tour
+)
+
+// Resolutions for imported nodes whose ID the current tour already uses,
+// for ImportOptions.Conflicts.
+const (
+	ConflictRenumber  = "renumber"  // the imported node gets a free ID; its edges follow
+	ConflictKeep      = "keep"      // the existing node is kept and the imported one dropped
+	ConflictOverwrite = "overwrite" // the imported node replaces the existing one
+)
+
+// ErrImportInvalid is returned when a tour that fails validation is
+// imported without the lenient option.
+var ErrImportInvalid = errors.New("imported tour is not valid")
+
+// ImportOptions controls how an uploaded tour is applied to the editor.
+type ImportOptions struct {
+	Mode      string
+	Conflicts string // only used when merging; defaults to ConflictRenumber
+	Lenient   bool   // load tours that fail validation as drafts
+}
+
+// ImportReport describes an uploaded tour and how it fits the current one.
+type ImportReport struct {
+	Tour      *models.Tour // as read from the file
+	Invalid   error        // field validation errors, nil if the tour is valid
+	Issues    GraphIssues  // graph and translation findings
+	Conflicts []int        // IDs of imported nodes the current tour also uses
+}
+
+// DecodeTour reads a tour from YAML without validating it. Media files
+// without an ID are given one.
+func (s *TourService) DecodeTour(r io.Reader) (*models.Tour, error) {
+	var tour models.Tour
+	decoder := yaml.NewDecoder(r)
+	if err := decoder.Decode(&tour); err != nil {
+		return nil, fmt.Errorf("parsing tour YAML: %w", err)
+	}
+	tour.AssignMediaIDs()
+	return &tour, nil
+}
+
+// CheckImport reads a tour and reports its problems and the node IDs it
+// shares with current, which may be nil. It only fails if the file is not
+// a readable tour.
+func (s *TourService) CheckImport(current *models.Tour, r io.Reader) (*ImportReport, error) {
+	tour, err := s.DecodeTour(r)
+	if err != nil {
+		return nil, err
+	}
+
+	report := &ImportReport{
+		Tour:    tour,
+		Invalid: s.ValidateTour(tour),
+		Issues:  append(s.ValidateGraph(tour), s.MissingTranslations(tour)...),
+	}
+	if current != nil {
+		for _, node := range tour.Nodes {
+			if current.GetNode(node.ID) != nil {
+				report.Conflicts = append(report.Conflicts, node.ID)
+			}
+		}
+	}
+	return report, nil
+}
+
+// ApplyImport reads a tour and makes it, or its merge with current, the
+// tour the user is editing, which it returns. It replaces current as an edit
+// of the tour, see EditTour. Unless opts.Lenient is set the
+// resulting tour must validate; otherwise ErrImportInvalid is returned
+// together with the report and the open tour is left unchanged. A lenient
+// import that does not validate is kept as a draft, see SaveTour. Without
+// a current tour the imported one keeps its ID unless a stored tour uses it;
+// then a number is appended.
+func (s *TourService) ApplyImport(ctx context.Context, current *models.Tour, r io.Reader, opts ImportOptions) (*models.Tour, *ImportReport, error) {
+	report, err := s.CheckImport(current, r)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	if opts.Mode != ImportReplace && opts.Mode != ImportMerge {
+		return nil, report, fmt.Errorf("unknown import mode %q", opts.Mode)
+	}
+	imported := func(current *models.Tour) (*models.Tour, error) {
+		if opts.Mode == ImportMerge {
+			return s.mergeTours(current, report.Tour, opts.Conflicts)
+		}
+		return copyTour(report.Tour)
+	}
+
+	if current == nil {
+		tour := report.Tour
+		if err := s.ValidateTour(tour); err != nil {
+			if !opts.Lenient {
+				return nil, report, fmt.Errorf("%w: %w", ErrImportInvalid, err)
+			}
+			s.drafts.Store(tour, true)
+		}
+		if tour.ID != "" {
+			id, err := s.freshTourID(ctx, tour.ID)
+			if err != nil {
+				return nil, report, err
+			}
+			tour.ID = id
+		}
+		_, err := s.replaceTour(ctx, tour, editChange("Imported tour"), func(*models.Tour) (*models.Tour, error) {
+			return tour, nil
+		})
+		if err != nil {
+			return nil, report, err
+		}
+		return tour, report, nil
+	}
+
+	change := editChange("Imported tour")
+	if opts.Mode == ImportMerge {
+		change = editChange("Merged imported tour")
+	}
+	tour, err := s.replaceTour(ctx, current, change, func(current *models.Tour) (*models.Tour, error) {
+		tour, err := imported(current)
+		if err != nil {
+			return nil, err
+		}
+		if current.Stored() {
+			tour.ID = current.ID // see ErrTourIDFixed
+		}
+		tour.Record = current.Record
+		if err := s.ValidateTour(tour); err != nil {
+			if !opts.Lenient {
+				return nil, fmt.Errorf("%w: %w", ErrImportInvalid, err)
+			}
+			s.drafts.Store(tour, true)
+		}
+		return tour, nil
+	})
+	if err != nil {
+		return nil, report, err
+	}
+	return tour, report, nil
+}
+
+// IsDraft reports whether the tour is a draft: imported leniently, newly
+// created or opened while incomplete, and not validated since.
+func (s *TourService) IsDraft(tour *models.Tour) bool {
+	_, ok := s.drafts.Load(tour)
+	return ok
+}
+
+// mergeTours returns a copy of current with the nodes and edges of imported
+// added. current keeps its metadata, messages and start node.
+func (s *TourService) mergeTours(current, imported *models.Tour, conflicts string) (*models.Tour, error) {
+	merged, err := copyTour(current)
+	if err != nil {
+		return nil, err
+	}
+	_, hasStart := merged.StartNodeID()
+
+	// Nodes without a conflict are added first, so IDs allocated for
+	// renumbered nodes cannot clash with them
+	renumbered := make(map[int]int)
+	var deferred []models.Node
+	for _, node := range imported.Nodes {
+		if hasStart {
+			node.Start = false
+		}
+		existing := merged.GetNode(node.ID)
+		switch {
+		case existing == nil:
+			merged.Nodes = append(merged.Nodes, node)
+		case conflicts == ConflictKeep:
+		case conflicts == ConflictOverwrite:
+			node.Start = existing.Start
+			*existing = node
+		case conflicts == ConflictRenumber || conflicts == "":
+			deferred = append(deferred, node)
+		default:
+			return nil, fmt.Errorf("unknown conflict resolution %q", conflicts)
+		}
+	}
+	for _, node := range deferred {
+		id := s.AllocateNodeID(merged)
+		renumbered[node.ID] = id
+		node.ID = id
+		merged.Nodes = append(merged.Nodes, node)
+	}
+
+	for _, edge := range imported.Edges {
+		if id, ok := renumbered[edge.From]; ok {
+			edge.From = id
+		}
+		if id, ok := renumbered[edge.To]; ok {
+			edge.To = id
+		}
+		merged.Edges = append(merged.Edges, edge)
+	}
+
+	merged.ReassignDuplicateMediaIDs()
+	merged.Record = current.Record
+	return merged, nil
+}
+
+// copyTour returns a deep copy of tour, including its record.
+func copyTour(tour *models.Tour) (*models.Tour, error) {
+	data, err := yaml.Marshal(tour)
+	if err != nil {
+		return nil, fmt.Errorf("copying tour: %w", err)
+	}
+	var copied models.Tour
+	if err := yaml.Unmarshal(data, &copied); err != nil {
+		return nil, fmt.Errorf("copying tour: %w", err)
+	}
+	copied.Record = tour.Record // not part of the YAML
+	return &copied, nil
+}
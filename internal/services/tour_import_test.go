@@ -0,0 +1,216 @@
+// internal/services/tour_import_test.go
+package services
+
+import (
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+const importYAML = `
+id: "imported_tour"
+name: "Imported Tour"
+description: "An imported tour"
+start_date: "2024-01-01T00:00:00Z"
+end_date: "2024-12-31T23:59:59Z"
+version: "2.0"
+hero_image: "http://example.com/imported.jpg"
+author:
+  name: "Other Author"
+  profile_link: "http://example.com/other"
+price: 500
+nodes:
+  - id: 2
+    location: {lat: 46.0, lon: 21.0}
+    short_description: "Bridge"
+    narrative: "Over the river"
+    start: true
+  - id: 10
+    location: {lat: 46.1, lon: 21.1}
+    short_description: "Market"
+    narrative: "Stalls"
+    finish: true
+edges:
+  - from: 2
+    to: 10
+`
+
+func TestTourService_ApplyImport(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    ImportOptions
+		wantErr error
+		check   func(t *testing.T, tour *models.Tour)
+	}{
+		{
+			name: "replace keeps the tour ID",
+			opts: ImportOptions{Mode: ImportReplace},
+			check: func(t *testing.T, tour *models.Tour) {
+				if tour.ID != "test_tour" || tour.Name.String() != "Imported Tour" || len(tour.Nodes) != 2 {
+					t.Errorf("expected the imported tour as test_tour, got %s %q with %d nodes", tour.ID, tour.Name, len(tour.Nodes))
+				}
+			},
+		},
+		{
+			name: "merge renumbers conflicting nodes",
+			opts: ImportOptions{Mode: ImportMerge, Conflicts: ConflictRenumber},
+			check: func(t *testing.T, tour *models.Tour) {
+				if tour.ID != "test_tour" || len(tour.Nodes) != 5 {
+					t.Fatalf("expected 5 nodes in test_tour, got %d in %s", len(tour.Nodes), tour.ID)
+				}
+				if tour.GetNode(2).ShortDesc.Value != "Two" {
+					t.Error("existing node 2 was changed")
+				}
+				bridge := tour.GetNode(11)
+				if bridge == nil || bridge.ShortDesc.Value != "Bridge" || bridge.Start {
+					t.Fatalf("expected the imported node 2 as node 11 without the start flag, got %+v", bridge)
+				}
+				if edge := tour.Edges[0]; edge.From != 11 || edge.To != 10 {
+					t.Errorf("edge not remapped: %+v", edge)
+				}
+			},
+		},
+		{
+			name: "merge keeps existing nodes",
+			opts: ImportOptions{Mode: ImportMerge, Conflicts: ConflictKeep},
+			check: func(t *testing.T, tour *models.Tour) {
+				if len(tour.Nodes) != 4 || tour.GetNode(2).ShortDesc.Value != "Two" {
+					t.Errorf("expected node 2 kept and node 10 added, got %+v", tour.Nodes)
+				}
+				if edge := tour.Edges[0]; edge.From != 2 || edge.To != 10 {
+					t.Errorf("unexpected edge %+v", edge)
+				}
+			},
+		},
+		{
+			name: "merge overwrites existing nodes",
+			opts: ImportOptions{Mode: ImportMerge, Conflicts: ConflictOverwrite},
+			check: func(t *testing.T, tour *models.Tour) {
+				if len(tour.Nodes) != 4 || tour.GetNode(2).ShortDesc.Value != "Bridge" {
+					t.Errorf("expected node 2 replaced, got %+v", tour.Nodes)
+				}
+				if start, _ := tour.StartNodeID(); start != 1 {
+					t.Errorf("start node changed to %d", start)
+				}
+			},
+		},
+		{
+			name:    "unknown mode",
+			opts:    ImportOptions{Mode: "append"},
+			wantErr: errors.New("unknown import mode"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewTourService(repository.NewMemory())
+			ctx := newTestContext()
+			current := newTestTour()
+			if err := service.SaveTour(ctx, current); err != nil {
+				t.Fatal(err)
+			}
+
+			report, err := service.CheckImport(current, strings.NewReader(importYAML))
+			if err != nil {
+				t.Fatal(err)
+			}
+			if report.Invalid != nil || len(report.Conflicts) != 1 || report.Conflicts[0] != 2 {
+				t.Fatalf("unexpected report: invalid %v, conflicts %v", report.Invalid, report.Conflicts)
+			}
+
+			tour, _, err := service.ApplyImport(ctx, current, strings.NewReader(importYAML), tt.opts)
+			if tt.wantErr != nil {
+				if err == nil || !strings.Contains(err.Error(), tt.wantErr.Error()) {
+					t.Fatalf("expected error containing %q, got %v", tt.wantErr, err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("ApplyImport() error = %v", err)
+			}
+			if service.GetCurrentTour(ctx) != tour {
+				t.Error("imported tour is not the session's tour")
+			}
+			if current.ID != "test_tour" || len(current.Nodes) != 3 {
+				t.Error("import modified the previous tour in place")
+			}
+			tt.check(t, tour)
+		})
+	}
+}
+
+func TestTourService_ApplyImportLenient(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+	current := newTestTour()
+	if err := service.SaveTour(ctx, current); err != nil {
+		t.Fatal(err)
+	}
+
+	invalid := strings.Replace(importYAML, `short_description: "Market"`, `short_description: ""`, 1)
+
+	_, report, err := service.ApplyImport(ctx, current, strings.NewReader(invalid), ImportOptions{Mode: ImportReplace})
+	if !errors.Is(err, ErrImportInvalid) {
+		t.Fatalf("expected ErrImportInvalid, got %v", err)
+	}
+	if report == nil || report.Invalid == nil {
+		t.Error("expected the report to describe the invalid tour")
+	}
+	if service.GetCurrentTour(ctx) != current {
+		t.Error("strict import of an invalid tour replaced the session's tour")
+	}
+
+	draft, _, err := service.ApplyImport(ctx, current, strings.NewReader(invalid), ImportOptions{Mode: ImportReplace, Lenient: true})
+	if err != nil {
+		t.Fatalf("lenient import failed: %v", err)
+	}
+	if !service.IsDraft(draft) || service.GetCurrentTour(ctx) != draft {
+		t.Fatal("expected the invalid tour to be loaded as a draft")
+	}
+
+	// Drafts can be edited before they validate
+	draft.Name = models.NewText("Renamed")
+	if err := service.SaveTour(ctx, draft); err != nil {
+		t.Fatalf("saving draft: %v", err)
+	}
+
+	draft.GetNode(10).ShortDesc = models.NewText("Market")
+	if err := service.SaveTour(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+	if service.IsDraft(draft) {
+		t.Error("tour is still a draft after it validated")
+	}
+
+	draft.GetNode(10).ShortDesc = models.NewText("")
+	if err := service.SaveTour(ctx, draft); err == nil {
+		t.Error("expected a validated tour to be checked again")
+	}
+}
+
+func TestTourService_ApplyImportWithoutTour(t *testing.T) {
+	tours := repository.NewMemory()
+	service := NewTourService(tours)
+	theirs := newTestTour()
+	theirs.ID = "imported_tour"
+	theirs.Record.Owner = "bob"
+	if err := tours.Save(newTestContext(), theirs); err != nil {
+		t.Fatal(err)
+	}
+
+	// The tour of another user under the file's ID is left alone
+	ctx := userContext("alice")
+	tour, _, err := service.ApplyImport(ctx, nil, strings.NewReader(importYAML), ImportOptions{Mode: ImportReplace})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if tour.ID != "imported_tour-2" || tour.Name.String() != "Imported Tour" {
+		t.Errorf("expected the import under a new ID, got %s %q", tour.ID, tour.Name)
+	}
+	if stored, err := tours.Get(ctx, "imported_tour"); err != nil || stored.Name.String() != "Test Tour" {
+		t.Errorf("bob's tour was overwritten: %v", err)
+	}
+}
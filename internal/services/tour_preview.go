@@ -0,0 +1,223 @@
+// internal/services/tour_preview.go
+package services
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// Kinds of PreviewLine values, for highlighting.
+const (
+	ValueString = "string"
+	ValueNumber = "number"
+	ValueBool   = "bool"
+	ValueNull   = "null"
+	ValueBlock  = "block" // block scalar indicator such as "|-", or its text
+	ValueFlow   = "flow"  // empty flow collection, "[]" or "{}"
+)
+
+// PreviewLine is one line of the YAML preview, split for highlighting.
+// Severity is set when the line belongs to a section with a problem; Issues
+// lists the problems reported for the section that starts on the line.
+type PreviewLine struct {
+	Number    int
+	Indent    string
+	Dash      string // sequence item markers, e.g. "- "
+	Key       string
+	Value     string
+	ValueKind string
+	Severity  string
+	Issues    []GraphIssue
+}
+
+// TourPreview is the tour as it would be exported, with the findings of
+// the validators attached to the lines they concern.
+type TourPreview struct {
+	Lines  []PreviewLine
+	Issues GraphIssues // all findings, including field validation errors
+}
+
+// IssueInvalidField is reported in the preview for every field that fails
+// validation.
+const IssueInvalidField = "invalid_field"
+
+// PreviewTour renders tour as YAML and marks the sections the field, graph
+// and translation validators report on. Field messages are given in the
+// first of langs that is supported.
+func (s *TourService) PreviewTour(tour *models.Tour, langs ...string) (*TourPreview, error) {
+	data, err := yaml.Marshal(tour)
+	if err != nil {
+		return nil, fmt.Errorf("rendering tour YAML: %w", err)
+	}
+
+	var issues GraphIssues
+	fields := s.FieldErrors(s.ValidateTour(tour), tour, langs...)
+	for path, msg := range fields {
+		issues = append(issues, GraphIssue{
+			Code:     IssueInvalidField,
+			Severity: SeverityError,
+			Path:     path,
+			Message:  msg,
+		})
+	}
+	sort.Slice(issues, func(i, j int) bool { return issues[i].Path < issues[j].Path })
+	issues = append(issues, s.ValidateGraph(tour)...)
+	issues = append(issues, s.MissingTranslations(tour)...)
+
+	var doc yaml.Node
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, fmt.Errorf("reading tour YAML: %w", err)
+	}
+	sections := make(map[string]yamlSection)
+	blocks := make(map[int]bool)
+	if len(doc.Content) > 0 {
+		collectSections(doc.Content[0], "", sections, blocks)
+	}
+
+	preview := &TourPreview{Issues: issues}
+	text := strings.TrimSuffix(string(data), "\n")
+	for i, line := range strings.Split(text, "\n") {
+		preview.Lines = append(preview.Lines, splitPreviewLine(i+1, line, blocks[i+1]))
+	}
+
+	for _, issue := range issues {
+		section, ok := findSection(sections, issue.Path)
+		if !ok {
+			continue
+		}
+		first := &preview.Lines[section.start-1]
+		first.Issues = append(first.Issues, issue)
+		for n := section.start; n <= section.end && n <= len(preview.Lines); n++ {
+			line := &preview.Lines[n-1]
+			if line.Severity != SeverityError {
+				line.Severity = issue.Severity
+			}
+		}
+	}
+	return preview, nil
+}
+
+// yamlSection is the range of lines, counted from 1, a path occupies.
+// Findings about a whole list, such as "nodes", only mark its first line.
+type yamlSection struct {
+	start, end int
+}
+
+func newSection(start int, value *yaml.Node) yamlSection {
+	if value.Kind == yaml.SequenceNode {
+		return yamlSection{start: start, end: start}
+	}
+	return yamlSection{start: start, end: lastLine(value)}
+}
+
+// collectSections records the lines of every mapping value and sequence
+// item below node, keyed by paths such as "nodes[0].location.lat", and the
+// lines holding the text of block scalars.
+func collectSections(node *yaml.Node, path string, sections map[string]yamlSection, blocks map[int]bool) {
+	switch node.Kind {
+	case yaml.MappingNode:
+		for i := 0; i+1 < len(node.Content); i += 2 {
+			key, value := node.Content[i], node.Content[i+1]
+			child := key.Value
+			if path != "" {
+				child = path + "." + key.Value
+			}
+			sections[child] = newSection(key.Line, value)
+			collectSections(value, child, sections, blocks)
+		}
+	case yaml.SequenceNode:
+		for i, item := range node.Content {
+			child := fmt.Sprintf("%s[%d]", path, i)
+			sections[child] = newSection(item.Line, item)
+			collectSections(item, child, sections, blocks)
+		}
+	case yaml.ScalarNode:
+		if node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0 {
+			for n := node.Line + 1; n <= lastLine(node); n++ {
+				blocks[n] = true
+			}
+		}
+	}
+}
+
+// lastLine returns the last line node's content extends to.
+func lastLine(node *yaml.Node) int {
+	last := node.Line
+	switch {
+	case node.Kind == yaml.ScalarNode && node.Style&(yaml.LiteralStyle|yaml.FoldedStyle) != 0:
+		last += strings.Count(strings.TrimRight(node.Value, "\n"), "\n") + 1
+	default:
+		for _, child := range node.Content {
+			if l := lastLine(child); l > last {
+				last = l
+			}
+		}
+	}
+	return last
+}
+
+// findSection looks up path, falling back to the closest enclosing section
+// for fields that are not written, such as an empty optional value.
+func findSection(sections map[string]yamlSection, path string) (yamlSection, bool) {
+	for path != "" {
+		if section, ok := sections[path]; ok {
+			return section, true
+		}
+		i := strings.LastIndexAny(path, ".[")
+		if i < 0 {
+			break
+		}
+		path = path[:i]
+	}
+	return yamlSection{}, false
+}
+
+var previewKey = regexp.MustCompile(`^("(?:[^"\\]|\\.)*"|'[^']*'|[^\s'"#][^:#]*?):(?: |$)`)
+
+// splitPreviewLine splits a line of yaml.v3 output into its parts. block
+// is set for the lines of a block scalar's text, which are not split.
+func splitPreviewLine(number int, text string, block bool) PreviewLine {
+	rest := strings.TrimLeft(text, " ")
+	line := PreviewLine{Number: number, Indent: text[:len(text)-len(rest)]}
+	if block {
+		line.Value, line.ValueKind = rest, ValueBlock
+		return line
+	}
+
+	for strings.HasPrefix(rest, "- ") || rest == "-" {
+		line.Dash += "- "
+		rest = strings.TrimPrefix(strings.TrimPrefix(rest, "-"), " ")
+	}
+	if m := previewKey.FindStringSubmatch(rest); m != nil {
+		line.Key = m[1]
+		rest = strings.TrimSpace(rest[len(m[0]):])
+	}
+	line.Value = rest
+	line.ValueKind = valueKind(rest)
+	return line
+}
+
+func valueKind(value string) string {
+	switch {
+	case value == "":
+		return ""
+	case strings.HasPrefix(value, "|") || strings.HasPrefix(value, ">"):
+		return ValueBlock
+	case value == "[]" || value == "{}":
+		return ValueFlow
+	case value == "null" || value == "~":
+		return ValueNull
+	case value == "true" || value == "false":
+		return ValueBool
+	}
+	if _, err := strconv.ParseFloat(value, 64); err == nil {
+		return ValueNumber
+	}
+	return ValueString
+}
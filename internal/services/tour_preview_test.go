@@ -0,0 +1,65 @@
+// internal/services/tour_preview_test.go
+package services
+
+import (
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+func TestTourService_PreviewTour(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	tour := newTestTour()
+	tour.Nodes[1].ShortDesc = models.NewText("")
+	tour.Nodes[0].Narrative = models.NewText("First line\nSecond line")
+	tour.Edges = []models.Edge{{From: 1, To: 2}, {From: 2, To: 3}}
+
+	preview, err := service.PreviewTour(tour)
+	if err != nil {
+		t.Fatalf("PreviewTour() error = %v", err)
+	}
+
+	find := func(key, value string) PreviewLine {
+		t.Helper()
+		for _, line := range preview.Lines {
+			if line.Key == key && line.Value == value {
+				return line
+			}
+		}
+		t.Fatalf("no line %s: %s", key, value)
+		return PreviewLine{}
+	}
+
+	invalid := find("short_description", `""`)
+	if invalid.Severity != SeverityError || len(invalid.Issues) != 1 || invalid.Issues[0].Code != IssueInvalidField {
+		t.Errorf("expected the empty short description to be marked, got %+v", invalid)
+	}
+	if line := find("short_description", "One"); line.Severity != "" {
+		t.Errorf("valid node marked as %q", line.Severity)
+	}
+
+	price := find("price", "1000")
+	if price.ValueKind != ValueNumber || price.Indent != "" {
+		t.Errorf("unexpected price line %+v", price)
+	}
+	if start := find("start", "true"); start.ValueKind != ValueBool {
+		t.Errorf("unexpected start line %+v", start)
+	}
+
+	// Block scalar text is not mistaken for keys
+	block := find("narrative", "|-")
+	next := preview.Lines[block.Number]
+	if next.Key != "" || next.Value != "First line" || next.ValueKind != ValueBlock {
+		t.Errorf("unexpected block scalar line %+v", next)
+	}
+
+	// Items start with their sequence marker
+	if item := preview.Lines[find("id", "2").Number-1]; item.Dash != "- " {
+		t.Errorf("expected sequence marker on %+v", item)
+	}
+
+	if _, err := service.PreviewTour(newTestTour()); err != nil {
+		t.Fatal(err)
+	}
+}
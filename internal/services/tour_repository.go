@@ -0,0 +1,25 @@
+// internal/services/tour_repository.go
+package services
+
+import (
+	"context"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+// TourRepository persists tours by ID. The repository package provides
+// in-memory, filesystem and S3 implementations.
+type TourRepository interface {
+	// Get returns the tour stored under id, or an error wrapping
+	// repository.ErrNotFound.
+	Get(ctx context.Context, id string) (*models.Tour, error)
+	// Save stores tour under its ID, replacing an earlier version.
+	Save(ctx context.Context, tour *models.Tour) error
+	Delete(ctx context.Context, id string) error
+	// List returns the IDs of the stored tours in ascending order.
+	List(ctx context.Context) ([]string, error)
+	// Summaries describes the stored tours in ID order, without loading
+	// their content.
+	Summaries(ctx context.Context) ([]repository.Summary, error)
+}
@@ -0,0 +1,271 @@
+// internal/services/tour_revisions.go
+package services
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/middleware"
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+// ErrRevisionConflict is returned, as a ConflictError, when a tour is changed
+// on top of a revision other than its current one.
+var ErrRevisionConflict = errors.New("the tour was changed in the meantime")
+
+// AnyRevision, passed to WithRevision, applies changes to whatever revision
+// a tour is at.
+const AnyRevision = -1
+
+// ConflictError reports a change made on top of an outdated revision of a
+// tour. Diff shows how the change would alter the current revision.
+type ConflictError struct {
+	Revision int // current revision of the tour
+	Diff     []DiffLine
+}
+
+func (e *ConflictError) Error() string {
+	return fmt.Sprintf("%v: it is at revision %d", ErrRevisionConflict, e.Revision)
+}
+
+func (e *ConflictError) Unwrap() error {
+	return ErrRevisionConflict
+}
+
+type revisionKey struct{}
+
+// WithRevision returns a context under which tours are only changed if they
+// are at revision, the one the change was made on top of.
+func WithRevision(ctx context.Context, revision int) context.Context {
+	return context.WithValue(ctx, revisionKey{}, revision)
+}
+
+// tourLocks serializes changes to tours. An open copy of a tour is locked
+// while it is changed or read; its ID is locked while the stored revision is
+// checked and the tour is saved, as users change separate copies.
+type tourLocks struct {
+	mu     sync.Mutex
+	copies map[*models.Tour]*sync.RWMutex
+	ids    map[string]*sync.Mutex
+}
+
+func (l *tourLocks) copy(tour *models.Tour) *sync.RWMutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.copies == nil {
+		l.copies = make(map[*models.Tour]*sync.RWMutex)
+	}
+	lock, ok := l.copies[tour]
+	if !ok {
+		lock = &sync.RWMutex{}
+		l.copies[tour] = lock
+	}
+	return lock
+}
+
+func (l *tourLocks) id(id string) *sync.Mutex {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	if l.ids == nil {
+		l.ids = make(map[string]*sync.Mutex)
+	}
+	lock, ok := l.ids[id]
+	if !ok {
+		lock = &sync.Mutex{}
+		l.ids[id] = lock
+	}
+	return lock
+}
+
+// forget drops the lock of a copy that is no longer open.
+func (l *tourLocks) forget(tour *models.Tour) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	delete(l.copies, tour)
+}
+
+// EditTour applies edit to tour and saves it, see SaveTour, recording change
+// in its history as what was changed. A tour is edited
+// by one request at a time, and only on top of the revision ctx expects, see
+// WithRevision, or else the revision tour is at. Otherwise a ConflictError
+// is returned. A copy that is behind the stored tour catches up before edit
+// is applied. edit is given a copy of tour, which replaces tour once it is
+// saved, so tour is left as it is if edit or saving fails. edit may only
+// change the tour it is given. It may not change the ID of a stored tour,
+// see ErrTourIDFixed, and may give a draft only an ID no tour uses yet, see
+// ErrTourIDTaken.
+func (s *TourService) EditTour(ctx context.Context, tour *models.Tour, change string, edit func(*models.Tour) error) error {
+	_, err := s.replaceTour(ctx, tour, editChange(change), func(tour *models.Tour) (*models.Tour, error) {
+		return tour, edit(tour)
+	})
+	return err
+}
+
+// replaceTour is EditTour for edits that may return a new tour to save in
+// place of tour, which is left unchanged. It returns the saved tour.
+func (s *TourService) replaceTour(ctx context.Context, tour *models.Tour, change tourChange, edit func(*models.Tour) (*models.Tour, error)) (*models.Tour, error) {
+	lock := s.locks.copy(tour)
+	lock.Lock()
+	defer lock.Unlock()
+
+	id := tour.ID
+	idLock := s.locks.id(id)
+	idLock.Lock()
+	defer idLock.Unlock()
+
+	current, err := s.storedTour(ctx, tour)
+	if err != nil {
+		return nil, err
+	}
+	if current != tour {
+		s.history.begin(current)
+	}
+	expected := tour.Record.Revision
+	if revision, ok := ctx.Value(revisionKey{}).(int); ok && revision != AnyRevision {
+		expected = revision
+	} else if ok {
+		expected = current.Record.Revision
+	}
+	if expected != current.Record.Revision {
+		return nil, s.conflict(current, tour, edit)
+	}
+
+	if tour.Record.Revision != current.Record.Revision {
+		*tour = *current // saved from another copy since this one was loaded
+	}
+	tour.Record.Archived = current.Record.Archived // see SetArchived
+
+	// Edit a copy, so a rejected change does not linger in the open tour
+	working, err := copyTour(tour)
+	if err != nil {
+		return nil, err
+	}
+	if s.IsDraft(tour) {
+		s.drafts.Store(working, true)
+	}
+	defer s.drafts.Delete(working)
+
+	next, err := edit(working)
+	if err != nil {
+		return nil, err
+	}
+
+	// A stored tour keeps its ID; a tour that is not stored yet may only
+	// take an ID no tour uses, including the tours of other users
+	if next.ID != id && current != tour {
+		return nil, ErrTourIDFixed
+	}
+	if current == tour && next.ID != "" {
+		if next.ID != id {
+			newIDLock := s.locks.id(next.ID)
+			newIDLock.Lock()
+			defer newIDLock.Unlock()
+		}
+		if _, err := s.tours.Get(ctx, next.ID); err == nil {
+			return nil, fmt.Errorf("%w: %s", ErrTourIDTaken, next.ID)
+		} else if !errors.Is(err, repository.ErrNotFound) {
+			return nil, fmt.Errorf("loading tour: %w", err)
+		}
+	}
+
+	if err := s.saveTour(ctx, next); err != nil {
+		return nil, err
+	}
+	if next == working {
+		if s.IsDraft(working) {
+			s.drafts.Store(tour, true)
+		} else {
+			s.drafts.Delete(tour)
+		}
+		*tour = *working
+		next = tour
+	}
+	if user := middleware.UserID(ctx); user != "" {
+		s.sessions.setCurrent(user, next)
+	}
+	s.history.record(id, next, middleware.UserID(ctx), change)
+	return next, nil
+}
+
+// ViewTour calls view with tour while no edit of it is in progress.
+func (s *TourService) ViewTour(tour *models.Tour, view func(*models.Tour)) {
+	lock := s.locks.copy(tour)
+	lock.RLock()
+	defer lock.RUnlock()
+	view(tour)
+}
+
+// SnapshotTour returns a shallow copy of tour as it is now, for reading
+// and rendering without holding its lock. Edits replace the contents of an
+// open tour rather than changing them in place, see EditTour, so the
+// snapshot's nodes, edges and messages do not change under the reader. It
+// is not open itself: pass tour, not the snapshot, to methods that lock.
+func (s *TourService) SnapshotTour(tour *models.Tour) *models.Tour {
+	var snapshot models.Tour
+	s.ViewTour(tour, func(tour *models.Tour) { snapshot = *tour })
+	return &snapshot
+}
+
+// RefreshTour brings an open copy of a tour up to the stored revision, for
+// changes saved from other copies since it was loaded.
+func (s *TourService) RefreshTour(ctx context.Context, tour *models.Tour) error {
+	lock := s.locks.copy(tour)
+	lock.Lock()
+	defer lock.Unlock()
+
+	current, err := s.storedTour(ctx, tour)
+	if err != nil {
+		return err
+	}
+	if current.Record.Revision != tour.Record.Revision {
+		*tour = *current
+	}
+	tour.Record.Archived = current.Record.Archived
+	return nil
+}
+
+// storedTour loads the stored version of tour, or returns tour itself if it
+// has not been stored. A tour of another user than the one of ctx under the
+// same ID does not count as stored; see replaceTour, which keeps tour from
+// taking its ID.
+func (s *TourService) storedTour(ctx context.Context, tour *models.Tour) (*models.Tour, error) {
+	if tour.ID == "" {
+		return tour, nil
+	}
+	stored, err := s.getTour(ctx, middleware.UserID(ctx), tour.ID)
+	if errors.Is(err, repository.ErrNotFound) {
+		return tour, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("loading tour: %w", err)
+	}
+	return stored, nil
+}
+
+// conflict describes the conflict of applying edit to the current revision
+// of a tour.
+func (s *TourService) conflict(current, tour *models.Tour, edit func(*models.Tour) (*models.Tour, error)) error {
+	conflict := &ConflictError{Revision: current.Record.Revision}
+
+	copied, err := copyTour(current)
+	if err != nil {
+		return err
+	}
+	mine, err := edit(copied)
+	if err != nil {
+		return conflict // the change no longer applies
+	}
+	if mine != tour {
+		s.drafts.Delete(mine)
+	}
+
+	diff, err := DiffTours(current, mine)
+	if err != nil {
+		return err
+	}
+	conflict.Diff = diff
+	return conflict
+}
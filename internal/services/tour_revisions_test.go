@@ -0,0 +1,405 @@
+// internal/services/tour_revisions_test.go
+package services
+
+import (
+	"errors"
+	"fmt"
+	"math/rand"
+	"runtime"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+func newRevisionTestNode(id int) *models.Node {
+	return &models.Node{
+		ID:        id,
+		Location:  models.Location{Lat: 45.3, Lon: 20.3},
+		ShortDesc: models.NewText("Extra"),
+		Narrative: models.NewText("An extra stop"),
+	}
+}
+
+func TestTourService_Revisions(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+	tour := newTestTour()
+	if err := service.SaveTour(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.SaveNode(ctx, tour, newRevisionTestNode(4)); err != nil {
+		t.Fatal(err)
+	}
+	if tour.Record.Revision != 2 {
+		t.Fatalf("expected revision 2, got %d", tour.Record.Revision)
+	}
+
+	// A change on top of an outdated revision conflicts
+	err := service.SaveNode(WithRevision(ctx, 1), tour, newRevisionTestNode(5))
+	var conflict *ConflictError
+	if !errors.As(err, &conflict) || !errors.Is(err, ErrRevisionConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+	if conflict.Revision != 2 {
+		t.Errorf("expected the conflict at revision 2, got %d", conflict.Revision)
+	}
+	if len(conflict.Diff) == 0 {
+		t.Error("expected the conflict to show the change")
+	}
+	if tour.GetNode(5) != nil || tour.Record.Revision != 2 {
+		t.Error("expected the conflicting change not to be applied")
+	}
+
+	// It can be applied to the current revision on purpose
+	if err := service.SaveNode(WithRevision(ctx, AnyRevision), tour, newRevisionTestNode(5)); err != nil {
+		t.Fatal(err)
+	}
+	if tour.GetNode(5) == nil || tour.Record.Revision != 3 {
+		t.Errorf("expected the change at revision 3, got revision %d", tour.Record.Revision)
+	}
+}
+
+func TestTourService_FailedEditLeavesTour(t *testing.T) {
+	tours := repository.NewMemory()
+	service := NewTourService(tours)
+	ctx := newTestContext()
+	tour := newTestTour()
+	if err := service.SaveTour(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name string
+		edit func(*models.Tour) error
+	}{
+		{
+			name: "edit fails",
+			edit: func(tour *models.Tour) error {
+				tour.Name.Set("", "Half done")
+				return errors.New("bad form")
+			},
+		},
+		{
+			name: "tour does not validate",
+			edit: func(tour *models.Tour) error {
+				tour.Name.Set("", "")
+				tour.Nodes = append(tour.Nodes, *newRevisionTestNode(4))
+				return nil
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := service.EditTour(ctx, tour, "Broken change", tt.edit); err == nil {
+				t.Fatal("expected the edit to fail")
+			}
+			if tour.Name.String() != "Test Tour" || tour.GetNode(4) != nil || tour.Record.Revision != 1 {
+				t.Errorf("failed edit changed the open tour: %q, revision %d", tour.Name, tour.Record.Revision)
+			}
+			stored, err := tours.Get(ctx, tour.ID)
+			if err != nil || stored.Name.String() != "Test Tour" {
+				t.Errorf("failed edit was stored: %v", err)
+			}
+		})
+	}
+
+	// Drafts keep being drafts while they do not validate
+	service.drafts.Store(tour, true)
+	if err := service.EditTour(ctx, tour, "Cleared name", func(tour *models.Tour) error {
+		tour.Name.Set("", "")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if !service.IsDraft(tour) || tour.Name.String() != "" {
+		t.Errorf("expected a draft without name, got draft %v, name %q", service.IsDraft(tour), tour.Name)
+	}
+	if err := service.EditTour(ctx, tour, "Named", func(tour *models.Tour) error {
+		tour.Name.Set("", "Test Tour")
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	if service.IsDraft(tour) {
+		t.Error("expected the tour to stop being a draft once it validates")
+	}
+}
+
+func TestTourService_TourIDChanges(t *testing.T) {
+	tours := repository.NewMemory()
+	service := NewTourService(tours)
+	ctx := newTestContext()
+	tour := newTestTour()
+	if err := service.SaveTour(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+	bobs := newTestTour()
+	bobs.ID = "bobs_tour"
+	bobs.Name.Set("", "Bob's Tour")
+	if err := tours.Save(ctx, bobs); err != nil {
+		t.Fatal(err)
+	}
+	rename := func(id string) func(*models.Tour) error {
+		return func(tour *models.Tour) error {
+			tour.ID = id
+			return nil
+		}
+	}
+
+	// A stored tour keeps its ID, whether the new one is taken or not
+	for _, id := range []string{"bobs_tour", "renamed"} {
+		if err := service.EditTour(ctx, tour, "Renamed", rename(id)); !errors.Is(err, ErrTourIDFixed) {
+			t.Errorf("renaming to %s: expected ErrTourIDFixed, got %v", id, err)
+		}
+	}
+	if tour.ID != "test_tour" || service.GetCurrentTour(ctx) != tour {
+		t.Errorf("rejected rename changed the open tour to %s", tour.ID)
+	}
+	if stored, err := tours.Get(ctx, "bobs_tour"); err != nil || stored.Name.String() != "Bob's Tour" {
+		t.Errorf("bob's tour was overwritten: %v", err)
+	}
+	if ids, _ := tours.List(ctx); len(ids) != 2 {
+		t.Errorf("expected only the two tours to be stored, got %v", ids)
+	}
+
+	// A draft that has not been stored may only take a free ID
+	draft := newTestTour()
+	draft.ID = ""
+	service.drafts.Store(draft, true)
+	if err := service.SaveTour(ctx, draft); err != nil {
+		t.Fatal(err)
+	}
+	if err := service.EditTour(ctx, draft, "Named", rename("bobs_tour")); !errors.Is(err, ErrTourIDTaken) {
+		t.Errorf("expected ErrTourIDTaken, got %v", err)
+	}
+	if err := service.EditTour(ctx, draft, "Named", rename("drafted")); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := tours.Get(ctx, "drafted"); err != nil || !draft.Stored() {
+		t.Errorf("expected the draft to be stored under its new ID: %v", err)
+	}
+	if stored, _ := tours.Get(ctx, "bobs_tour"); stored.Name.String() != "Bob's Tour" {
+		t.Error("bob's tour was overwritten by the draft")
+	}
+}
+
+func TestTourService_RevisionsAcrossCopies(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	tour := newTestTour()
+	if err := service.SaveTour(userContext("admin"), tour); err != nil {
+		t.Fatal(err)
+	}
+	copied, err := service.OpenTour(userContext("user"), "user", tour.ID)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	if err := service.SaveNode(userContext("admin"), tour, newRevisionTestNode(4)); err != nil {
+		t.Fatal(err)
+	}
+
+	// The other copy is behind the stored tour
+	err = service.SaveNode(userContext("user"), copied, newRevisionTestNode(5))
+	if !errors.Is(err, ErrRevisionConflict) {
+		t.Fatalf("expected a conflict, got %v", err)
+	}
+
+	// Saving on top of the current revision catches it up
+	err = service.SaveNode(WithRevision(userContext("user"), tour.Record.Revision), copied, newRevisionTestNode(5))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if copied.GetNode(4) == nil || copied.GetNode(5) == nil {
+		t.Error("expected both changes in the copy")
+	}
+
+	// Refreshing catches up the first copy
+	if err := service.RefreshTour(userContext("admin"), tour); err != nil {
+		t.Fatal(err)
+	}
+	if tour.GetNode(5) == nil || tour.Record.Revision != copied.Record.Revision {
+		t.Error("expected the refreshed copy at the stored revision")
+	}
+}
+
+func TestTourService_ConcurrentEdits(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+	tour := newTestTour()
+	if err := service.SaveTour(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+
+	const edits = 20
+	var wg sync.WaitGroup
+	for i := 0; i < edits; i++ {
+		wg.Add(1)
+		go func(id int) {
+			defer wg.Done()
+			if err := service.SaveNode(ctx, tour, newRevisionTestNode(id)); err != nil {
+				t.Errorf("saving node %d: %v", id, err)
+			}
+			service.ViewTour(tour, func(tour *models.Tour) {
+				_ = tour.GetNode(id)
+			})
+		}(10 + i)
+	}
+	wg.Wait()
+
+	if len(tour.Nodes) != 3+edits {
+		t.Errorf("expected %d nodes, got %d", 3+edits, len(tour.Nodes))
+	}
+	if tour.Record.Revision != 1+edits {
+		t.Errorf("expected revision %d, got %d", 1+edits, tour.Record.Revision)
+	}
+}
+
+func TestTourService_ConcurrentEditsOfOneRevision(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+	tour := newTestTour()
+	if err := service.SaveTour(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+	revision := tour.Record.Revision
+
+	const edits = 10
+	results := make(chan error, edits)
+	for i := 0; i < edits; i++ {
+		go func(id int) {
+			results <- service.SaveNode(WithRevision(ctx, revision), tour, newRevisionTestNode(id))
+		}(10 + i)
+	}
+
+	saved := 0
+	for i := 0; i < edits; i++ {
+		err := <-results
+		switch {
+		case err == nil:
+			saved++
+		case !errors.Is(err, ErrRevisionConflict):
+			t.Errorf("expected a conflict, got %v", err)
+		}
+	}
+	if saved != 1 {
+		t.Errorf("expected exactly one change to be saved, got %d", saved)
+	}
+}
+
+func TestDiffTours(t *testing.T) {
+	from := newTestTour()
+	to := newTestTour()
+
+	if diff, err := DiffTours(from, to); err != nil || diff != nil {
+		t.Errorf("expected no difference, got %v, %v", diff, err)
+	}
+
+	to.Name = models.NewText("Renamed Tour")
+	diff, err := DiffTours(from, to)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	var removed, added bool
+	for _, line := range diff {
+		switch {
+		case line.Kind == DiffRemoved && strings.Contains(line.Text, "Test Tour"):
+			removed = true
+		case line.Kind == DiffAdded && strings.Contains(line.Text, "Renamed Tour"):
+			added = true
+		case line.Kind == DiffRemoved || line.Kind == DiffAdded:
+			t.Errorf("unexpected change %s %q", line.Kind, line.Text)
+		}
+	}
+	if !removed || !added {
+		t.Errorf("expected the name to change, got %v", diff)
+	}
+	if diff[len(diff)-1].Kind != DiffSkipped {
+		t.Errorf("expected the unchanged rest of the tour to be left out, got %v", diff[len(diff)-1])
+	}
+}
+
+func TestDiffLines(t *testing.T) {
+	// lcs is the quadratic reference the diff must match
+	lcs := func(a, b []string) int {
+		common := make([][]int, len(a)+1)
+		for i := range common {
+			common[i] = make([]int, len(b)+1)
+		}
+		for i := len(a) - 1; i >= 0; i-- {
+			for j := len(b) - 1; j >= 0; j-- {
+				if a[i] == b[j] {
+					common[i][j] = common[i+1][j+1] + 1
+				} else {
+					common[i][j] = max(common[i+1][j], common[i][j+1])
+				}
+			}
+		}
+		return common[0][0]
+	}
+	random := rand.New(rand.NewSource(1))
+	lines := func() []string {
+		lines := make([]string, random.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + random.Intn(4)))
+		}
+		return lines
+	}
+
+	for n := 0; n < 500; n++ {
+		a, b := lines(), lines()
+		var gotA, gotB []string
+		same := 0
+		for _, line := range diffLines(a, b) {
+			if line.Kind != DiffAdded {
+				gotA = append(gotA, line.Text)
+			}
+			if line.Kind != DiffRemoved {
+				gotB = append(gotB, line.Text)
+			}
+			if line.Kind == DiffSame {
+				same++
+			}
+		}
+		if strings.Join(gotA, "") != strings.Join(a, "") || strings.Join(gotB, "") != strings.Join(b, "") {
+			t.Fatalf("diff of %v and %v does not turn one into the other", a, b)
+		}
+		if want := lcs(a, b); same != want {
+			t.Fatalf("diff of %v and %v keeps %d lines, want %d", a, b, same, want)
+		}
+	}
+}
+
+func TestDiffLines_LargeTour(t *testing.T) {
+	a := make([]string, 20000)
+	for i := range a {
+		a[i] = fmt.Sprintf("line %d", i)
+	}
+	b := append([]string(nil), a...)
+	b[10000] = "changed"
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	diff := trimDiff(diffLines(a, b))
+	runtime.ReadMemStats(&after)
+
+	if len(diff) != 2*diffContext+4 {
+		t.Errorf("expected one changed line with context, got %d lines", len(diff))
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 16<<20 {
+		t.Errorf("diff of a one-line change allocated %d bytes", allocated)
+	}
+
+	// Changes all over a large tour are shown without comparing every pair
+	// of lines
+	for i := range b {
+		b[i] = fmt.Sprintf("other %d", i)
+	}
+	if diff := diffLines(a, b); len(diff) != len(a)+len(b) {
+		t.Errorf("expected every line removed and added, got %d lines", len(diff))
+	}
+}
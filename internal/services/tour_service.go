@@ -3,25 +3,55 @@ package services
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"sync"
+	"time"
 
+	"github.com/ceesaxp/tour-guide-editor/internal/middleware"
 	"github.com/ceesaxp/tour-guide-editor/internal/models"
 	"github.com/ceesaxp/tour-guide-editor/internal/validators"
+	ut "github.com/go-playground/universal-translator"
 	"github.com/go-playground/validator/v10"
 	"gopkg.in/yaml.v3"
 )
 
+// ErrDeleteStartNode is returned when deleting the tour's start node without
+// first designating a replacement.
+var ErrDeleteStartNode = errors.New("cannot delete the start node; choose a replacement start first")
+
+// ErrInvalidStartNode is returned when the replacement for a deleted start
+// node is not another node of the tour.
+var ErrInvalidStartNode = errors.New("invalid replacement start node")
+
+// ErrNodeIDTaken is returned when a node is given an ID that another node of
+// the tour already uses.
+var ErrNodeIDTaken = errors.New("node ID is already in use")
+
+// ErrTourIDFixed is returned when an edit changes the ID of a stored tour.
+var ErrTourIDFixed = errors.New("the ID of a stored tour cannot be changed")
+
+// ErrTourIDTaken is returned when a draft is given the ID of another tour.
+var ErrTourIDTaken = errors.New("tour ID is already in use")
+
 type TourService struct {
-	validator  *validator.Validate
-	tour       *models.Tour
-	tourStore  sync.Map // For demo purposes, using in-memory storage
-	activeTour sync.Map // Maps session ID to active tour
+	validator *validator.Validate
+	messages  *ut.UniversalTranslator // validation messages
+	tour      *models.Tour
+	tours     TourRepository
+	sessions  *editSessions // Tours open in the editor, per user
+	drafts    sync.Map      // Tours that may be saved before they validate, see IsDraft
+	locks     tourLocks     // Serialize changes to tours, see EditTour
+	history   tourHistory   // Revisions of tours, see History
+
+	idMu       sync.Mutex
+	lastNodeID map[string]int // Highest node ID handed out per tour ID
 }
 
-func NewTourService() *TourService {
+// NewTourService returns a service that persists tours in tours.
+func NewTourService(tours TourRepository) *TourService {
 	validate := validator.New()
 
 	// Register custom validations
@@ -29,18 +59,28 @@ func NewTourService() *TourService {
 		log.Printf("ERR: registering custom validations error: %v", err)
 		return nil
 	}
+	models.RegisterValidation(validate)
 
-	return &TourService{
-		validator: validate,
+	messages, err := validators.NewTranslator()
+	if err != nil {
+		log.Printf("ERR: loading validation messages error: %v", err)
+		return nil
 	}
-}
 
-func (s *TourService) GetCurrentTour(ctx context.Context) *models.Tour {
-	sessionID := ctx.Value("sessionID").(string)
-	if tour, ok := s.activeTour.Load(sessionID); ok {
-		return tour.(*models.Tour)
+	s := &TourService{
+		validator:  validate,
+		messages:   messages,
+		tours:      tours,
+		lastNodeID: make(map[string]int),
 	}
-	return nil
+	s.sessions = newEditSessions(DefaultSessionTTL, func(tour *models.Tour, id string, stillOpen bool) {
+		s.drafts.Delete(tour)
+		s.locks.forget(tour)
+		if !stillOpen {
+			s.history.forget(id)
+		}
+	})
+	return s
 }
 
 func (s *TourService) ValidateTour(tour *models.Tour) error {
@@ -51,30 +91,102 @@ func (s *TourService) ValidateNode(node *models.Node) error {
 	return s.validator.Struct(node)
 }
 
+// FieldErrors describes the validation errors of root, the tour, node or
+// edge that was validated, keyed by form field name. Messages are in the
+// first of langs that has translations, or English. It returns nil if err
+// is not a validation error.
+func (s *TourService) FieldErrors(err error, root interface{}, langs ...string) map[string]string {
+	trans, _ := s.messages.FindTranslator(langs...)
+	return validators.FieldErrors(err, root, trans)
+}
+
+// ValidationMessage returns the validation message key, e.g. "summary", in
+// the first of langs that has translations.
+func (s *TourService) ValidationMessage(key string, langs ...string) string {
+	trans, _ := s.messages.FindTranslator(langs...)
+	return validators.Message(trans, key)
+}
+
+// SaveTour validates the tour, makes it the tour the authenticated user of
+// ctx is editing and stores it in the repository as its next revision. The
+// tour must be at the revision ctx expects, see EditTour.
+// Drafts, see IsDraft, are saved even if they do not validate, so their
+// problems can be fixed in the editor; the first time such a tour validates
+// it stops being a draft.
 func (s *TourService) SaveTour(ctx context.Context, tour *models.Tour) error {
+	_, err := s.replaceTour(ctx, tour, editChange("Saved tour"), func(*models.Tour) (*models.Tour, error) {
+		return tour, nil
+	})
+	return err
+}
+
+// saveTour validates and stores tour for replaceTour, with the tour locked.
+func (s *TourService) saveTour(ctx context.Context, tour *models.Tour) error {
 	if err := s.ValidateTour(tour); err != nil {
-		return err
+		if !s.IsDraft(tour) {
+			return err
+		}
+	} else {
+		s.drafts.Delete(tour)
 	}
 
-	sessionID := ctx.Value("sessionID").(string)
-	s.activeTour.Store(sessionID, tour)
-	s.tourStore.Store(tour.ID, tour)
-
+	// A draft without an ID stays in the session until it is given one
+	tour.Record.Revision++
+	if tour.ID == "" {
+		return nil
+	}
+	tour.Record.Modified = time.Now()
+	if err := s.tours.Save(ctx, tour); err != nil {
+		tour.Record.Revision--
+		return fmt.Errorf("saving tour: %w", err)
+	}
 	return nil
 }
 
+// OpenTour makes the tour id the tour the authenticated user of ctx is
+// editing. A tour the user already has open is picked up where it was left;
+// otherwise the stored tour is loaded. owner must be able to access the
+// tour, see ListTours.
+func (s *TourService) OpenTour(ctx context.Context, owner, id string) (*models.Tour, error) {
+	user := middleware.UserID(ctx)
+	if user == "" {
+		return nil, ErrNoUser
+	}
+	if tour := s.sessions.openTour(user, id); tour != nil {
+		var reopened bool
+		s.ViewTour(tour, func(tour *models.Tour) {
+			if reopened = canAccess(tour.Record, owner); reopened {
+				s.sessions.setCurrent(user, tour)
+			}
+		})
+		if reopened {
+			return tour, nil
+		}
+	}
+
+	tour, err := s.getTour(ctx, owner, id)
+	if err != nil {
+		return nil, err
+	}
+	if s.ValidateTour(tour) != nil {
+		s.drafts.Store(tour, true) // still being written
+	}
+
+	s.sessions.setCurrent(user, tour)
+	return tour, nil
+}
+
 func (s *TourService) ParseTour(r io.Reader) (*models.Tour, error) {
-	var tour models.Tour
-	decoder := yaml.NewDecoder(r)
-	if err := decoder.Decode(&tour); err != nil {
-		return nil, fmt.Errorf("parsing tour YAML: %w", err)
+	tour, err := s.DecodeTour(r)
+	if err != nil {
+		return nil, err
 	}
 
 	if err := s.validator.Struct(tour); err != nil {
 		return nil, fmt.Errorf("validating tour: %w", err)
 	}
 
-	return &tour, nil
+	return tour, nil
 }
 
 func (s *TourService) ValidateEdge(edge *models.Edge) error {
@@ -86,6 +198,10 @@ func (s *TourService) ExportTour(tour *models.Tour) ([]byte, error) {
 		return nil, fmt.Errorf("validating tour before export: %w", err)
 	}
 
+	if issues := s.ValidateGraph(tour); issues.HasErrors() {
+		return nil, fmt.Errorf("validating tour graph before export: %w", issues)
+	}
+
 	return yaml.Marshal(tour)
 }
 
@@ -94,24 +210,115 @@ func (s *TourService) SaveNode(ctx context.Context, tour *models.Tour, node *mod
 		return err
 	}
 
+	return s.EditTour(ctx, tour, fmt.Sprintf("Saved node %d", node.ID), func(tour *models.Tour) error {
+		saveNode(tour, *node)
+		return nil
+	})
+}
+
+// saveNode replaces the node of the tour with the same ID, or adds it.
+func saveNode(tour *models.Tour, node models.Node) {
+	// Keep a single start node; the first node of a tour starts it by default
+	if len(tour.Nodes) == 0 {
+		node.Start = true
+	}
+	if node.Start {
+		for i := range tour.Nodes {
+			if tour.Nodes[i].ID != node.ID {
+				tour.Nodes[i].Start = false
+			}
+		}
+	}
+
 	// Update or add node
-	found := false
 	for i, n := range tour.Nodes {
 		if n.ID == node.ID {
-			tour.Nodes[i] = *node
-			found = true
-			break
+			tour.Nodes[i] = node
+			return
 		}
 	}
+	tour.Nodes = append(tour.Nodes, node)
+}
+
+// AllocateNodeID returns a node ID that is not used by the tour and has not
+// been handed out for it before, so editors opened for new nodes at the same
+// time never share an ID.
+func (s *TourService) AllocateNodeID(tour *models.Tour) int {
+	s.idMu.Lock()
+	defer s.idMu.Unlock()
 
-	if !found {
-		tour.Nodes = append(tour.Nodes, *node)
+	var id int
+	s.ViewTour(tour, func(tour *models.Tour) {
+		id = s.lastNodeID[tour.ID]
+		for _, node := range tour.Nodes {
+			if node.ID > id {
+				id = node.ID
+			}
+		}
+		id++
+		s.lastNodeID[tour.ID] = id
+	})
+
+	return id
+}
+
+// UpdateNode saves node in place of the node currently identified by id. If
+// node.ID differs from id the node is renumbered and every edge referring to
+// it follows; the new ID must not be taken. Without a node identified by id,
+// node is added to the tour.
+func (s *TourService) UpdateNode(ctx context.Context, tour *models.Tour, id int, node *models.Node) error {
+	if err := s.ValidateNode(node); err != nil {
+		return err
 	}
 
-	return s.SaveTour(ctx, tour)
+	change := fmt.Sprintf("Saved node %d", node.ID)
+	if node.ID != id {
+		change = fmt.Sprintf("Saved node %d as node %d", id, node.ID)
+	}
+	return s.EditTour(ctx, tour, change, func(tour *models.Tour) error {
+		if node.ID != id {
+			if tour.GetNode(node.ID) != nil {
+				return fmt.Errorf("%w: %d", ErrNodeIDTaken, node.ID)
+			}
+			if existing := tour.GetNode(id); existing != nil {
+				existing.ID = node.ID
+				for i := range tour.Edges {
+					if tour.Edges[i].From == id {
+						tour.Edges[i].From = node.ID
+					}
+					if tour.Edges[i].To == id {
+						tour.Edges[i].To = node.ID
+					}
+				}
+			}
+		}
+		saveNode(tour, *node)
+		return nil
+	})
 }
 
 func (s *TourService) DeleteNode(ctx context.Context, tour *models.Tour, nodeID int) error {
+	return s.EditTour(ctx, tour, fmt.Sprintf("Deleted node %d", nodeID), func(tour *models.Tour) error {
+		return deleteNode(tour, nodeID)
+	})
+}
+
+// DeleteStartNode deletes the start node nodeID, making replacementID the
+// start node of the tour instead.
+func (s *TourService) DeleteStartNode(ctx context.Context, tour *models.Tour, nodeID, replacementID int) error {
+	return s.EditTour(ctx, tour, fmt.Sprintf("Deleted node %d", nodeID), func(tour *models.Tour) error {
+		if replacementID == nodeID || !tour.SetStartNode(replacementID) {
+			return ErrInvalidStartNode
+		}
+		return deleteNode(tour, nodeID)
+	})
+}
+
+func deleteNode(tour *models.Tour, nodeID int) error {
+	if start, ok := tour.StartNodeID(); ok && start == nodeID {
+		return ErrDeleteStartNode
+	}
+
 	// Remove node
 	for i, node := range tour.Nodes {
 		if node.ID == nodeID {
@@ -121,19 +328,173 @@ func (s *TourService) DeleteNode(ctx context.Context, tour *models.Tour, nodeID
 	}
 
 	// Remove associated edges
-	var newEdges []models.Edge
+	newEdges := make([]models.Edge, 0, len(tour.Edges))
 	for _, edge := range tour.Edges {
 		if edge.From != nodeID && edge.To != nodeID {
 			newEdges = append(newEdges, edge)
 		}
 	}
 	tour.Edges = newEdges
+	return nil
+}
+
+// SaveMediaFile replaces the node's media file that has the same ID, or
+// appends it when the node has no such file.
+func (s *TourService) SaveMediaFile(ctx context.Context, tour *models.Tour, nodeID int, file *models.MediaFile) error {
+	if err := s.validator.Struct(file); err != nil {
+		return err
+	}
+
+	return s.EditTour(ctx, tour, fmt.Sprintf("Saved media file %s of node %d", file.ID, nodeID), func(tour *models.Tour) error {
+		node := tour.GetNode(nodeID)
+		if node == nil {
+			return fmt.Errorf("node %d not found", nodeID)
+		}
 
-	return s.SaveTour(ctx, tour)
+		if existing := node.GetMediaFile(file.ID); existing != nil {
+			*existing = *file
+			return nil
+		}
+
+		for _, other := range tour.AllMediaFiles() {
+			if other.ID == file.ID {
+				return fmt.Errorf("media file ID %s is already in use", file.ID)
+			}
+		}
+		node.MediaFiles = append(node.MediaFiles, *file)
+		return nil
+	})
 }
 
-func (s *TourService) SaveTourToSession(c context.Context, tour *models.Tour) error {
-	sessionID := c.Value("sessionID").(string)
-	s.activeTour.Store(sessionID, tour)
-	return nil
+func (s *TourService) DeleteMediaFile(ctx context.Context, tour *models.Tour, nodeID int, mediaID string) error {
+	return s.EditTour(ctx, tour, fmt.Sprintf("Deleted media file %s of node %d", mediaID, nodeID), func(tour *models.Tour) error {
+		node := tour.GetNode(nodeID)
+		if node == nil {
+			return fmt.Errorf("node %d not found", nodeID)
+		}
+
+		for i := range node.MediaFiles {
+			if node.MediaFiles[i].ID == mediaID {
+				node.MediaFiles = append(node.MediaFiles[:i], node.MediaFiles[i+1:]...)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("media file %s not found", mediaID)
+	})
+}
+
+// MoveMediaFile shifts the node's media file by offset positions, clamping
+// at the ends of the list. Media files are sent in list order.
+func (s *TourService) MoveMediaFile(ctx context.Context, tour *models.Tour, nodeID int, mediaID string, offset int) error {
+	return s.EditTour(ctx, tour, fmt.Sprintf("Moved media file %s of node %d", mediaID, nodeID), func(tour *models.Tour) error {
+		node := tour.GetNode(nodeID)
+		if node == nil {
+			return fmt.Errorf("node %d not found", nodeID)
+		}
+
+		for i := range node.MediaFiles {
+			if node.MediaFiles[i].ID == mediaID {
+				moveItem(node.MediaFiles, i, offset)
+				return nil
+			}
+		}
+
+		return fmt.Errorf("media file %s not found", mediaID)
+	})
+}
+
+// SetAudioNarrative links the node's audio narrative to uri, or removes it
+// if uri is empty. duration is the narrative's playing time; zero if unknown.
+func (s *TourService) SetAudioNarrative(ctx context.Context, tour *models.Tour, nodeID int, uri string, duration time.Duration) error {
+	if err := s.validator.Var(uri, "omitempty,url"); err != nil {
+		return fmt.Errorf("invalid audio narrative URL %q", uri)
+	}
+
+	change := fmt.Sprintf("Saved audio narrative of node %d", nodeID)
+	if uri == "" {
+		change = fmt.Sprintf("Removed audio narrative of node %d", nodeID)
+	}
+	return s.EditTour(ctx, tour, change, func(tour *models.Tour) error {
+		node := tour.GetNode(nodeID)
+		if node == nil {
+			return fmt.Errorf("node %d not found", nodeID)
+		}
+		node.AudioNarrative = uri
+		node.AudioNarrativeDuration = 0
+		if uri != "" {
+			node.AudioNarrativeDuration = models.Seconds(duration)
+		}
+		return nil
+	})
+}
+
+// SaveEdge replaces the edge at index, or appends it when index is out of
+// range. Both endpoints must refer to nodes that exist in the tour.
+func (s *TourService) SaveEdge(ctx context.Context, tour *models.Tour, index int, edge *models.Edge) error {
+	if err := s.ValidateEdge(edge); err != nil {
+		return err
+	}
+
+	change := fmt.Sprintf("Saved edge %d → %d", edge.From, edge.To)
+	return s.EditTour(ctx, tour, change, func(tour *models.Tour) error {
+		if tour.GetNode(edge.From) == nil {
+			return fmt.Errorf("edge source node %d does not exist", edge.From)
+		}
+		if tour.GetNode(edge.To) == nil {
+			return fmt.Errorf("edge target node %d does not exist", edge.To)
+		}
+
+		if index >= 0 && index < len(tour.Edges) {
+			tour.Edges[index] = *edge
+		} else {
+			tour.Edges = append(tour.Edges, *edge)
+		}
+		return nil
+	})
+}
+
+func (s *TourService) DeleteEdge(ctx context.Context, tour *models.Tour, index int) error {
+	return s.EditTour(ctx, tour, fmt.Sprintf("Deleted edge %d", index+1), func(tour *models.Tour) error {
+		if index < 0 || index >= len(tour.Edges) {
+			return fmt.Errorf("edge %d not found", index)
+		}
+
+		tour.Edges = append(tour.Edges[:index], tour.Edges[index+1:]...)
+		return nil
+	})
+}
+
+// MoveEdge shifts the edge at index by offset positions, clamping at the
+// ends of the list. Edge order determines the order in which a node's exits
+// are offered to the visitor.
+func (s *TourService) MoveEdge(ctx context.Context, tour *models.Tour, index, offset int) error {
+	return s.EditTour(ctx, tour, fmt.Sprintf("Moved edge %d", index+1), func(tour *models.Tour) error {
+		if index < 0 || index >= len(tour.Edges) {
+			return fmt.Errorf("edge %d not found", index)
+		}
+
+		moveItem(tour.Edges, index, offset)
+		return nil
+	})
+}
+
+// moveItem shifts the element at index by offset positions, clamping at the
+// ends of the slice.
+func moveItem[T any](items []T, index, offset int) {
+	target := index + offset
+	if target < 0 {
+		target = 0
+	}
+	if target >= len(items) {
+		target = len(items) - 1
+	}
+
+	item := items[index]
+	if target < index {
+		copy(items[target+1:index+1], items[target:index])
+	} else {
+		copy(items[index:target], items[index+1:target+1])
+	}
+	items[target] = item
 }
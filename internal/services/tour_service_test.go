@@ -2,14 +2,20 @@
 package services
 
 import (
+	"bytes"
+	"context"
+	"errors"
 	"strings"
 	"testing"
+	"time"
 
+	"github.com/ceesaxp/tour-guide-editor/internal/middleware"
 	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
 )
 
 func TestTourService_ParseTour(t *testing.T) {
-	service := NewTourService()
+	service := NewTourService(repository.NewMemory())
 
 	tests := []struct {
 		name        string
@@ -65,6 +71,58 @@ edges: []
 			wantErr:     true,
 			errContains: "EndDate",
 		},
+		{
+			name: "valid tour with settings",
+			yaml: `
+id: "test_tour"
+name: "Test Tour"
+description: "A test tour"
+start_date: "2024-01-01T00:00:00Z"
+end_date: "2024-12-31T23:59:59Z"
+version: "1.0"
+hero_image: "http://example.com/image.jpg"
+author:
+  name: "Test Author"
+  profile_link: "http://example.com/author"
+price: 1000
+settings:
+  media_caching: "prefetch_next"
+  preferred_language: "de-CH"
+nodes: []
+edges: []
+`,
+			wantErr: false,
+		},
+		{
+			name: "invalid tour - bad media cache override",
+			yaml: `
+id: "test_tour"
+name: "Test Tour"
+description: "A test tour"
+start_date: "2024-01-01T00:00:00Z"
+end_date: "2024-12-31T23:59:59Z"
+version: "1.0"
+hero_image: "http://example.com/image.jpg"
+author:
+  name: "Test Author"
+  profile_link: "http://example.com/author"
+price: 1000
+nodes:
+  - id: 1
+    location:
+      lat: 45.0
+      lon: 20.0
+    short_description: "Test Node"
+    narrative: "Test narrative"
+    media_files:
+      - type: "image"
+        uri: "http://example.com/image.jpg"
+        cache: "forever"
+edges: []
+`,
+			wantErr:     true,
+			errContains: "Cache",
+		},
 	}
 
 	for _, tt := range tests {
@@ -91,7 +149,7 @@ edges: []
 }
 
 func TestTourService_ValidateNode(t *testing.T) {
-	service := NewTourService()
+	service := NewTourService(repository.NewMemory())
 
 	tests := []struct {
 		name    string
@@ -106,10 +164,11 @@ func TestTourService_ValidateNode(t *testing.T) {
 					Lat: 45.0,
 					Lon: 20.0,
 				},
-				ShortDesc: "Test Node",
-				Narrative: "Test narrative",
+				ShortDesc: models.NewText("Test Node"),
+				Narrative: models.NewText("Test narrative"),
 				MediaFiles: []models.MediaFile{
 					{
+						ID:        "media-1",
 						Type:      "image",
 						URI:       "http://example.com/image.jpg",
 						SendDelay: 0,
@@ -133,8 +192,8 @@ func TestTourService_ValidateNode(t *testing.T) {
 					Lat: 91.0, // Invalid latitude
 					Lon: 20.0,
 				},
-				ShortDesc: "Test Node",
-				Narrative: "Test narrative",
+				ShortDesc: models.NewText("Test Node"),
+				Narrative: models.NewText("Test narrative"),
 			},
 			wantErr: true,
 		},
@@ -155,3 +214,308 @@ func TestTourService_ValidateNode(t *testing.T) {
 		})
 	}
 }
+
+func newTestTour() *models.Tour {
+	return &models.Tour{
+		ID:          "test_tour",
+		Name:        models.NewText("Test Tour"),
+		Description: models.NewText("A test tour"),
+		StartDate:   time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC),
+		EndDate:     time.Date(2024, 12, 31, 0, 0, 0, 0, time.UTC),
+		Version:     "1.0",
+		HeroImage:   "http://example.com/image.jpg",
+		Author: models.Author{
+			Name:        "Test Author",
+			ProfileLink: "http://example.com/author",
+		},
+		Price: 1000,
+		Nodes: []models.Node{
+			{ID: 1, Location: models.Location{Lat: 45.0, Lon: 20.0}, ShortDesc: models.NewText("One"), Narrative: models.NewText("First"), Start: true},
+			{ID: 2, Location: models.Location{Lat: 45.1, Lon: 20.1}, ShortDesc: models.NewText("Two"), Narrative: models.NewText("Second")},
+			{ID: 3, Location: models.Location{Lat: 45.2, Lon: 20.2}, ShortDesc: models.NewText("Three"), Narrative: models.NewText("Third"), Finish: true},
+		},
+		Edges: []models.Edge{},
+	}
+}
+
+func newTestContext() context.Context {
+	return context.WithValue(context.Background(), middleware.UserIDKey, "test-user")
+}
+
+func TestTourService_SaveEdge(t *testing.T) {
+	tests := []struct {
+		name      string
+		index     int
+		edge      models.Edge
+		wantErr   bool
+		wantEdges int
+	}{
+		{
+			name:      "append new edge",
+			index:     -1,
+			edge:      models.Edge{From: 1, To: 3, Instructions: models.NewText("Walk on")},
+			wantEdges: 2,
+		},
+		{
+			name:      "replace existing edge",
+			index:     0,
+			edge:      models.Edge{From: 2, To: 3},
+			wantEdges: 1,
+		},
+		{
+			name:    "unknown target node",
+			index:   -1,
+			edge:    models.Edge{From: 1, To: 42},
+			wantErr: true,
+		},
+		{
+			name:    "self loop",
+			index:   -1,
+			edge:    models.Edge{From: 1, To: 1},
+			wantErr: true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			service := NewTourService(repository.NewMemory())
+			tour := newTestTour()
+			tour.Edges = []models.Edge{{From: 1, To: 2}}
+
+			err := service.SaveEdge(newTestContext(), tour, tt.index, &tt.edge)
+			if tt.wantErr {
+				if err == nil {
+					t.Error("Expected error, got nil")
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("Unexpected error: %v", err)
+			}
+			if len(tour.Edges) != tt.wantEdges {
+				t.Errorf("Expected %d edges, got %d", tt.wantEdges, len(tour.Edges))
+			}
+		})
+	}
+}
+
+func TestTourService_DeleteAndMoveEdge(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+
+	tour := newTestTour()
+	tour.Edges = []models.Edge{
+		{From: 1, To: 2},
+		{From: 2, To: 3},
+		{From: 1, To: 3},
+	}
+
+	if err := service.MoveEdge(ctx, tour, 2, -1); err != nil {
+		t.Fatalf("MoveEdge() error = %v", err)
+	}
+	if tour.Edges[1].From != 1 || tour.Edges[1].To != 3 {
+		t.Errorf("Expected edge 1->3 at index 1, got %d->%d", tour.Edges[1].From, tour.Edges[1].To)
+	}
+
+	if err := service.MoveEdge(ctx, tour, 0, 5); err != nil {
+		t.Fatalf("MoveEdge() error = %v", err)
+	}
+	if tour.Edges[2].From != 1 || tour.Edges[2].To != 2 {
+		t.Errorf("Expected edge 1->2 moved to the end, got %d->%d", tour.Edges[2].From, tour.Edges[2].To)
+	}
+
+	if err := service.DeleteEdge(ctx, tour, 0); err != nil {
+		t.Fatalf("DeleteEdge() error = %v", err)
+	}
+	if len(tour.Edges) != 2 {
+		t.Errorf("Expected 2 edges after delete, got %d", len(tour.Edges))
+	}
+
+	if err := service.DeleteEdge(ctx, tour, 7); err == nil {
+		t.Error("Expected error deleting missing edge, got nil")
+	}
+}
+
+func TestTourService_DeleteNode(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+
+	tour := newTestTour()
+	tour.Edges = []models.Edge{{From: 1, To: 2}, {From: 2, To: 3}}
+
+	if err := service.DeleteNode(ctx, tour, 1); !errors.Is(err, ErrDeleteStartNode) {
+		t.Fatalf("Expected ErrDeleteStartNode, got %v", err)
+	}
+	if len(tour.Nodes) != 3 {
+		t.Fatalf("Start node was removed despite the error")
+	}
+
+	if err := service.DeleteNode(ctx, tour, 2); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tour.Nodes) != 2 || len(tour.Edges) != 0 {
+		t.Errorf("Expected 2 nodes and no edges, got %d nodes and %d edges", len(tour.Nodes), len(tour.Edges))
+	}
+
+	tour.SetStartNode(3)
+	if err := service.DeleteNode(ctx, tour, 1); err != nil {
+		t.Errorf("Unexpected error deleting former start node: %v", err)
+	}
+}
+
+func TestTourService_AllocateNodeID(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+
+	tour := newTestTour()
+	first := service.AllocateNodeID(tour)
+	second := service.AllocateNodeID(tour)
+
+	if first != 4 || second != 5 {
+		t.Errorf("Expected IDs 4 and 5, got %d and %d", first, second)
+	}
+
+	// Manually numbered nodes are skipped
+	tour.Nodes = append(tour.Nodes, models.Node{ID: 10})
+	if id := service.AllocateNodeID(tour); id != 11 {
+		t.Errorf("Expected ID 11, got %d", id)
+	}
+}
+
+func TestTourService_UpdateNode(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+
+	tour := newTestTour()
+	tour.Edges = []models.Edge{{From: 1, To: 2}, {From: 2, To: 3}}
+
+	// Renumbering onto a taken ID is rejected
+	node := *tour.GetNode(2)
+	node.ID = 3
+	if err := service.UpdateNode(ctx, tour, 2, &node); !errors.Is(err, ErrNodeIDTaken) {
+		t.Fatalf("Expected ErrNodeIDTaken, got %v", err)
+	}
+
+	// Renumbering cascades to edges
+	node.ID = 20
+	if err := service.UpdateNode(ctx, tour, 2, &node); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if tour.GetNode(2) != nil || tour.GetNode(20) == nil || len(tour.Nodes) != 3 {
+		t.Errorf("Node not renumbered: %+v", tour.Nodes)
+	}
+	if tour.Edges[0].To != 20 || tour.Edges[1].From != 20 {
+		t.Errorf("Edges not updated: %+v", tour.Edges)
+	}
+
+	// An unknown ID adds the node
+	added := *tour.GetNode(20)
+	added.ID = 30
+	added.Start = false
+	if err := service.UpdateNode(ctx, tour, 30, &added); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tour.Nodes) != 4 {
+		t.Errorf("Expected node to be added, got %d nodes", len(tour.Nodes))
+	}
+}
+
+func TestTourService_MediaFiles(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+
+	tour := newTestTour()
+	file := models.NewMediaFile("image")
+	file.URI = "http://example.com/image.jpg"
+
+	if err := service.SaveMediaFile(ctx, tour, 1, &file); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if err := service.SaveMediaFile(ctx, tour, 2, &file); err == nil {
+		t.Error("Expected error reusing a media file ID on another node, got nil")
+	}
+
+	file.SendDelay = 5
+	if err := service.SaveMediaFile(ctx, tour, 1, &file); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if media := tour.GetNode(1).MediaFiles; len(media) != 1 || media[0].SendDelay != 5 {
+		t.Errorf("Expected media file to be updated in place, got %+v", media)
+	}
+
+	if err := service.DeleteMediaFile(ctx, tour, 1, file.ID); err != nil {
+		t.Fatalf("Unexpected error: %v", err)
+	}
+	if len(tour.GetNode(1).MediaFiles) != 0 {
+		t.Error("Media file not deleted")
+	}
+	if err := service.DeleteMediaFile(ctx, tour, 1, file.ID); err == nil {
+		t.Error("Expected error deleting unknown media file, got nil")
+	}
+}
+
+func TestTourService_ExportTourMessages(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+
+	tour := newTestTour()
+	tour.Edges = []models.Edge{{From: 1, To: 2}, {From: 2, To: 3}}
+	tour.Milestones.At50 = &models.Message{
+		Text: models.NewText("Halfway there!"),
+		MediaFiles: []models.MediaFile{
+			{ID: "media-1", Type: "image", URI: "http://example.com/half.jpg"},
+		},
+	}
+	tour.Farewell = &models.Message{Text: models.NewText("Thanks for joining us")}
+
+	data, err := service.ExportTour(tour)
+	if err != nil {
+		t.Fatalf("ExportTour() error = %v", err)
+	}
+
+	parsed, err := service.ParseTour(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseTour() error = %v", err)
+	}
+
+	if parsed.Milestones.At25 != nil || parsed.Milestones.At75 != nil {
+		t.Errorf("Expected only the 50%% milestone, got %+v", parsed.Milestones)
+	}
+	if parsed.Milestones.At50 == nil || parsed.Milestones.At50.Text.String() != "Halfway there!" ||
+		len(parsed.Milestones.At50.MediaFiles) != 1 {
+		t.Errorf("50%% milestone not round-tripped: %+v", parsed.Milestones.At50)
+	}
+	if parsed.Farewell == nil || parsed.Farewell.Text.String() != "Thanks for joining us" {
+		t.Errorf("Farewell not round-tripped: %+v", parsed.Farewell)
+	}
+
+	tour.Milestones.At25 = &models.Message{}
+	if _, err := service.ExportTour(tour); err == nil {
+		t.Error("Expected error exporting milestone without text, got nil")
+	}
+}
+
+func TestTourService_PersistsTours(t *testing.T) {
+	tours := repository.NewMemory()
+	ctx := newTestContext()
+
+	service := NewTourService(tours)
+	if err := service.SaveTour(ctx, newTestTour()); err != nil {
+		t.Fatal(err)
+	}
+
+	// A new service, as after a restart, can open the saved tour
+	restarted := NewTourService(tours)
+	if restarted.GetCurrentTour(ctx) != nil {
+		t.Fatal("session survived the restart")
+	}
+	tour, err := restarted.OpenTour(ctx, "", "test_tour")
+	if err != nil {
+		t.Fatalf("OpenTour() error = %v", err)
+	}
+	if restarted.GetCurrentTour(ctx) != tour || len(tour.Nodes) != 3 {
+		t.Errorf("expected the saved tour to be the session's tour, got %+v", tour)
+	}
+
+	if _, err := restarted.OpenTour(ctx, "", "missing"); !errors.Is(err, repository.ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+}
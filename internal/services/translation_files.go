@@ -0,0 +1,362 @@
+// internal/services/translation_files.go
+package services
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"strconv"
+	"strings"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+)
+
+// Translation exchange formats understood by ExportTranslations and
+// ImportTranslations.
+const (
+	TranslationFormatXLIFF = "xliff"
+	TranslationFormatPO    = "po"
+)
+
+// TranslationUnit is a single translatable string, keyed by its path in the
+// tour, e.g. "nodes[id=2].narrative"; see models.Tour.KeyedTextFields.
+type TranslationUnit struct {
+	Path   string
+	Source string // text in the tour's default language
+	Target string // translation, empty if not yet translated
+}
+
+// TranslationReport describes the outcome of importing a translation file.
+type TranslationReport struct {
+	Updated  []string // paths whose translation was applied
+	Missing  []string // paths in the tour without a translation in the file
+	Stale    []string // paths whose source text changed since the file was exported; not applied
+	Orphaned []string // paths in the file that no longer exist in the tour
+}
+
+// ExportTranslations writes every translatable string of the tour, together
+// with its current translation into lang, in the given exchange format.
+func (s *TourService) ExportTranslations(tour *models.Tour, lang, format string) ([]byte, error) {
+	if !tour.Settings.HasLanguage(lang) {
+		return nil, fmt.Errorf("language %q is not declared in the tour settings", lang)
+	}
+
+	fields := tour.KeyedTextFields()
+	units := make([]TranslationUnit, len(fields))
+	for i, field := range fields {
+		units[i] = TranslationUnit{
+			Path:   field.Path,
+			Source: field.Text.Value,
+			Target: field.Text.In(lang),
+		}
+	}
+
+	switch format {
+	case TranslationFormatXLIFF:
+		return encodeXLIFF(tour, lang, units)
+	case TranslationFormatPO:
+		return encodePO(tour, lang, units), nil
+	default:
+		return nil, fmt.Errorf("unknown translation format %q", format)
+	}
+}
+
+// ImportTranslations merges the translations for lang read from r into the
+// tour and saves it. Units whose source no longer matches the tour are
+// reported as stale and left untouched.
+func (s *TourService) ImportTranslations(ctx context.Context, tour *models.Tour, lang, format string, r io.Reader) (*TranslationReport, error) {
+	var (
+		units    []TranslationUnit
+		fileLang string
+		err      error
+	)
+	switch format {
+	case TranslationFormatXLIFF:
+		units, fileLang, err = decodeXLIFF(r)
+	case TranslationFormatPO:
+		units, fileLang, err = decodePO(r)
+	default:
+		return nil, fmt.Errorf("unknown translation format %q", format)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("parsing translation file: %w", err)
+	}
+	if fileLang != "" && fileLang != lang {
+		return nil, fmt.Errorf("translation file is for %q, not %q", fileLang, lang)
+	}
+
+	var report *TranslationReport
+	err = s.EditTour(ctx, tour, fmt.Sprintf("Imported %s translations", lang), func(tour *models.Tour) error {
+		var err error
+		report, err = applyTranslations(tour, lang, units)
+		return err
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
+
+// applyTranslations sets the translations for lang of units in the tour.
+func applyTranslations(tour *models.Tour, lang string, units []TranslationUnit) (*TranslationReport, error) {
+	if !tour.Settings.HasLanguage(lang) {
+		return nil, fmt.Errorf("language %q is not declared in the tour settings", lang)
+	}
+
+	// Units of XLIFF files that lost their names are matched by unit ID
+	fields := make(map[string]models.TextField)
+	for _, field := range tour.KeyedTextFields() {
+		fields[field.Path] = field
+		fields[xliffUnitID(field.Path)] = field
+	}
+
+	report := &TranslationReport{}
+	seen := make(map[string]bool, len(units))
+	for _, unit := range units {
+		field, ok := fields[unit.Path]
+		if !ok {
+			report.Orphaned = append(report.Orphaned, unit.Path)
+			continue
+		}
+		text := field.Text
+		unit.Path = field.Path
+		seen[unit.Path] = true
+		switch {
+		case unit.Source != text.Value:
+			report.Stale = append(report.Stale, unit.Path)
+		case unit.Target == "":
+			report.Missing = append(report.Missing, unit.Path)
+		default:
+			text.Set(lang, unit.Target)
+			report.Updated = append(report.Updated, unit.Path)
+		}
+	}
+	for _, field := range tour.KeyedTextFields() {
+		if !seen[field.Path] {
+			report.Missing = append(report.Missing, field.Path)
+		}
+	}
+	return report, nil
+}
+
+// XLIFF 2.0
+
+const xliffNamespace = "urn:oasis:names:tc:xliff:document:2.0"
+
+type xliffDocument struct {
+	XMLName xml.Name    `xml:"urn:oasis:names:tc:xliff:document:2.0 xliff"`
+	Version string      `xml:"version,attr"`
+	SrcLang string      `xml:"srcLang,attr"`
+	TrgLang string      `xml:"trgLang,attr,omitempty"`
+	Files   []xliffFile `xml:"file"`
+}
+
+type xliffFile struct {
+	ID    string      `xml:"id,attr"`
+	Units []xliffUnit `xml:"unit"`
+}
+
+type xliffUnit struct {
+	ID      string       `xml:"id,attr"`
+	Name    string       `xml:"name,attr,omitempty"`
+	Segment xliffSegment `xml:"segment"`
+}
+
+type xliffSegment struct {
+	State  string `xml:"state,attr,omitempty"`
+	Source string `xml:"source"`
+	Target string `xml:"target,omitempty"`
+}
+
+// XLIFF unit IDs must be NMTOKENs, so "edges[from=1,to=3].instructions"
+// becomes "edges.1-3.instructions". The path is kept in the unit name.
+var unitIDReplacer = strings.NewReplacer("[id=", ".", "[from=", ".", ",to=", "-", "[", ".", "]", "")
+
+func xliffUnitID(path string) string {
+	return unitIDReplacer.Replace(path)
+}
+
+func encodeXLIFF(tour *models.Tour, lang string, units []TranslationUnit) ([]byte, error) {
+	srcLang := tour.Settings.PreferredLanguage
+	if srcLang == "" {
+		srcLang = "und"
+	}
+
+	file := xliffFile{ID: tour.ID}
+	for _, unit := range units {
+		segment := xliffSegment{State: "initial", Source: unit.Source, Target: unit.Target}
+		if unit.Target != "" {
+			segment.State = "translated"
+		}
+		file.Units = append(file.Units, xliffUnit{
+			ID:      xliffUnitID(unit.Path),
+			Name:    unit.Path,
+			Segment: segment,
+		})
+	}
+
+	doc := xliffDocument{Version: "2.0", SrcLang: srcLang, TrgLang: lang, Files: []xliffFile{file}}
+	data, err := xml.MarshalIndent(doc, "", "  ")
+	if err != nil {
+		return nil, fmt.Errorf("encoding XLIFF: %w", err)
+	}
+	return append([]byte(xml.Header), append(data, '\n')...), nil
+}
+
+func decodeXLIFF(r io.Reader) ([]TranslationUnit, string, error) {
+	var doc xliffDocument
+	if err := xml.NewDecoder(r).Decode(&doc); err != nil {
+		return nil, "", err
+	}
+	if doc.XMLName.Space != xliffNamespace {
+		return nil, "", fmt.Errorf("not an XLIFF 2.0 document")
+	}
+
+	var units []TranslationUnit
+	for _, file := range doc.Files {
+		for _, unit := range file.Units {
+			path := unit.Name
+			if path == "" {
+				path = unit.ID // see applyTranslations
+			}
+			units = append(units, TranslationUnit{
+				Path:   path,
+				Source: unit.Segment.Source,
+				Target: unit.Segment.Target,
+			})
+		}
+	}
+	return units, doc.TrgLang, nil
+}
+
+// Gettext PO. Each entry uses its path as msgctxt, so identical source
+// strings in different places are translated independently.
+
+var poEscaper = strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`, "\t", `\t`, "\r", `\r`)
+
+func encodePO(tour *models.Tour, lang string, units []TranslationUnit) []byte {
+	var buf bytes.Buffer
+	buf.WriteString("msgid \"\"\nmsgstr \"\"\n")
+	fmt.Fprintf(&buf, "\"Project-Id-Version: %s %s\\n\"\n", poEscaper.Replace(tour.ID), poEscaper.Replace(tour.Version))
+	fmt.Fprintf(&buf, "\"Language: %s\\n\"\n", poEscaper.Replace(lang))
+	buf.WriteString("\"MIME-Version: 1.0\\n\"\n")
+	buf.WriteString("\"Content-Type: text/plain; charset=UTF-8\\n\"\n")
+	buf.WriteString("\"Content-Transfer-Encoding: 8bit\\n\"\n")
+
+	for _, unit := range units {
+		fmt.Fprintf(&buf, "\n#: %s\n", unit.Path)
+		writePOString(&buf, "msgctxt", unit.Path)
+		writePOString(&buf, "msgid", unit.Source)
+		writePOString(&buf, "msgstr", unit.Target)
+	}
+	return buf.Bytes()
+}
+
+// writePOString writes a keyword and its quoted value, splitting multi-line
+// values after each newline as gettext tools do.
+func writePOString(buf *bytes.Buffer, keyword, value string) {
+	lines := strings.SplitAfter(value, "\n")
+	if len(lines) > 1 && lines[len(lines)-1] == "" {
+		lines = lines[:len(lines)-1]
+	}
+	if len(lines) == 1 {
+		fmt.Fprintf(buf, "%s \"%s\"\n", keyword, poEscaper.Replace(value))
+		return
+	}
+	fmt.Fprintf(buf, "%s \"\"\n", keyword)
+	for _, line := range lines {
+		fmt.Fprintf(buf, "\"%s\"\n", poEscaper.Replace(line))
+	}
+}
+
+type poEntry struct {
+	context, id, str string
+	hasID, hasStr    bool
+}
+
+func decodePO(r io.Reader) ([]TranslationUnit, string, error) {
+	var (
+		units   []TranslationUnit
+		lang    string
+		current poEntry
+		field   *string
+	)
+
+	flush := func() {
+		if current.hasID {
+			if current.id == "" {
+				lang = poHeaderValue(current.str, "Language")
+			} else {
+				units = append(units, TranslationUnit{Path: current.context, Source: current.id, Target: current.str})
+			}
+		}
+		current = poEntry{}
+		field = nil
+	}
+
+	scanner := bufio.NewScanner(r)
+	for lineNo := 1; scanner.Scan(); lineNo++ {
+		line := strings.TrimSpace(scanner.Text())
+
+		keyword, rest, _ := strings.Cut(line, " ")
+		switch {
+		case line == "":
+			flush()
+			continue
+		case strings.HasPrefix(line, "#"):
+			if current.hasStr {
+				flush()
+			}
+			continue
+		case strings.HasPrefix(line, `"`):
+			if field == nil {
+				return nil, "", fmt.Errorf("line %d: unexpected string", lineNo)
+			}
+			rest = line
+		case keyword == "msgctxt":
+			if current.hasStr {
+				flush()
+			}
+			field = &current.context
+		case keyword == "msgid":
+			if current.hasStr {
+				flush()
+			}
+			current.hasID = true
+			field = &current.id
+		case keyword == "msgstr":
+			if !current.hasID {
+				return nil, "", fmt.Errorf("line %d: msgstr without msgid", lineNo)
+			}
+			current.hasStr = true
+			field = &current.str
+		default:
+			return nil, "", fmt.Errorf("line %d: unsupported keyword %q", lineNo, keyword)
+		}
+
+		value, err := strconv.Unquote(strings.TrimSpace(rest))
+		if err != nil {
+			return nil, "", fmt.Errorf("line %d: invalid string: %w", lineNo, err)
+		}
+		*field += value
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, "", err
+	}
+	flush()
+
+	return units, lang, nil
+}
+
+// poHeaderValue returns the value of a "Name: value" line of a PO header.
+func poHeaderValue(header, name string) string {
+	for _, line := range strings.Split(header, "\n") {
+		if key, value, ok := strings.Cut(line, ":"); ok && strings.TrimSpace(key) == name {
+			return strings.TrimSpace(value)
+		}
+	}
+	return ""
+}
@@ -0,0 +1,224 @@
+// internal/services/translation_files_test.go
+package services
+
+import (
+	"bytes"
+	"reflect"
+	"regexp"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+func TestTourService_ExportTranslations(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+
+	tour := newTestTour()
+	tour.Settings.PreferredLanguage = "en"
+	tour.Settings.Languages = []string{"de"}
+	tour.Nodes[0].Narrative.Set("de", "Erste \"Station\"\nmit Umbruch")
+
+	tests := []struct {
+		format string
+		want   []string
+	}{
+		{
+			format: TranslationFormatXLIFF,
+			want: []string{
+				`srcLang="en" trgLang="de"`,
+				`<unit id="nodes.1.narrative" name="nodes[id=1].narrative">`,
+				`<segment state="translated">`,
+			},
+		},
+		{
+			format: TranslationFormatPO,
+			want: []string{
+				`"Language: de\n"`,
+				"msgctxt \"nodes[id=1].narrative\"\n",
+				"msgstr \"\"\n\"Erste \\\"Station\\\"\\n\"\n\"mit Umbruch\"\n",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.format, func(t *testing.T) {
+			data, err := service.ExportTranslations(tour, "de", tt.format)
+			if err != nil {
+				t.Fatalf("ExportTranslations() error = %v", err)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(string(data), want) {
+					t.Errorf("Expected %q in export:\n%s", want, data)
+				}
+			}
+		})
+	}
+
+	if _, err := service.ExportTranslations(tour, "fr", TranslationFormatPO); err == nil {
+		t.Error("Expected error exporting undeclared language, got nil")
+	}
+}
+
+func TestTourService_ImportTranslations(t *testing.T) {
+	encoders := map[string]func(units []TranslationUnit) []byte{
+		TranslationFormatXLIFF: func(units []TranslationUnit) []byte {
+			data, err := encodeXLIFF(newTestTour(), "de", units)
+			if err != nil {
+				t.Fatal(err)
+			}
+			return data
+		},
+		TranslationFormatPO: func(units []TranslationUnit) []byte {
+			return encodePO(newTestTour(), "de", units)
+		},
+	}
+
+	for format, encode := range encoders {
+		t.Run(format, func(t *testing.T) {
+			service := NewTourService(repository.NewMemory())
+			ctx := newTestContext()
+
+			tour := newTestTour()
+			tour.Settings.Languages = []string{"de"}
+
+			var units []TranslationUnit
+			for _, field := range tour.KeyedTextFields() {
+				units = append(units, TranslationUnit{Path: field.Path, Source: field.Text.Value})
+			}
+			units[0].Target = "Testtour"             // name: updated
+			units[1].Source = "An older description" // description: stale
+			units[1].Target = "Eine ältere Beschreibung"
+			units = append(units, TranslationUnit{Path: "nodes[id=9].narrative", Source: "Gone", Target: "Weg"})
+
+			report, err := service.ImportTranslations(ctx, tour, "de", format, bytes.NewReader(encode(units)))
+			if err != nil {
+				t.Fatalf("ImportTranslations() error = %v", err)
+			}
+
+			if !reflect.DeepEqual(report.Updated, []string{"name"}) {
+				t.Errorf("Updated = %v", report.Updated)
+			}
+			if !reflect.DeepEqual(report.Stale, []string{"description"}) {
+				t.Errorf("Stale = %v", report.Stale)
+			}
+			if !reflect.DeepEqual(report.Orphaned, []string{"nodes[id=9].narrative"}) {
+				t.Errorf("Orphaned = %v", report.Orphaned)
+			}
+			if len(report.Missing) != len(units)-3 {
+				t.Errorf("Expected %d missing, got %v", len(units)-3, report.Missing)
+			}
+
+			if tour.Name.In("de") != "Testtour" {
+				t.Errorf("Expected translated name, got %+v", tour.Name)
+			}
+			if tour.Description.Has("de") {
+				t.Errorf("Stale translation was applied: %+v", tour.Description)
+			}
+		})
+	}
+}
+
+func TestTourService_ImportTranslationsAfterTourChanges(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+	ctx := newTestContext()
+
+	tour := newTestTour()
+	tour.Settings.Languages = []string{"de"}
+	tour.Nodes[2].MediaFiles = []models.MediaFile{
+		{ID: "media-a", Type: "image", URI: "http://example.com/a.jpg", Narrative: models.NewText("A view")},
+		{ID: "media-b", Type: "image", URI: "http://example.com/b.jpg", Narrative: models.NewText("Another view")},
+	}
+	tour.Edges = []models.Edge{{From: 2, To: 3, Instructions: models.NewText("Walk on")}}
+	if err := service.SaveTour(ctx, tour); err != nil {
+		t.Fatal(err)
+	}
+
+	exports := make(map[string][]byte)
+	for _, format := range []string{TranslationFormatXLIFF, TranslationFormatPO} {
+		data, err := service.ExportTranslations(tour, "de", format)
+		if err != nil {
+			t.Fatal(err)
+		}
+		exports[format] = data
+	}
+
+	// Translate everything, then change the tour before the file comes back
+	if err := service.EditTour(ctx, tour, "Reordered", func(tour *models.Tour) error {
+		tour.Nodes = []models.Node{tour.Nodes[2], tour.Nodes[1]} // node 1 deleted
+		files := tour.Nodes[0].MediaFiles
+		files[0], files[1] = files[1], files[0]
+		tour.Edges = append([]models.Edge{{From: 3, To: 2}}, tour.Edges...)
+		return nil
+	}); err != nil {
+		t.Fatal(err)
+	}
+	translated := map[string]string{
+		"Two":          "Zwei",
+		"Three":        "Drei",
+		"A view":       "Ein Blick",
+		"Another view": "Noch ein Blick",
+		"Walk on":      "Weitergehen",
+	}
+	translate := func(format string) []byte {
+		data := string(exports[format])
+		for source, target := range translated {
+			switch format {
+			case TranslationFormatXLIFF:
+				data = strings.Replace(data, "<source>"+source+"</source>", "<source>"+source+"</source>\n<target>"+target+"</target>", 1)
+			case TranslationFormatPO:
+				data = strings.Replace(data, "msgid \""+source+"\"\nmsgstr \"\"", "msgid \""+source+"\"\nmsgstr \""+target+"\"", 1)
+			}
+		}
+		return []byte(data)
+	}
+
+	for _, format := range []string{TranslationFormatXLIFF, TranslationFormatPO} {
+		t.Run(format, func(t *testing.T) {
+			report, err := service.ImportTranslations(ctx, tour, "de", format, bytes.NewReader(translate(format)))
+			if err != nil {
+				t.Fatalf("ImportTranslations() error = %v", err)
+			}
+			if len(report.Updated) != len(translated) || len(report.Stale) != 0 {
+				t.Errorf("expected %d updated and none stale, got %+v", len(translated), report)
+			}
+			if !reflect.DeepEqual(report.Orphaned, []string{"nodes[id=1].short_description", "nodes[id=1].narrative"}) {
+				t.Errorf("Orphaned = %v", report.Orphaned)
+			}
+
+			files := tour.GetNode(3).MediaFiles
+			if tour.GetNode(2).ShortDesc.In("de") != "Zwei" || tour.GetNode(3).ShortDesc.In("de") != "Drei" {
+				t.Errorf("node translations not applied: %+v, %+v", tour.GetNode(2).ShortDesc, tour.GetNode(3).ShortDesc)
+			}
+			if files[0].Narrative.In("de") != "Noch ein Blick" || files[1].Narrative.In("de") != "Ein Blick" {
+				t.Errorf("media translations not applied by ID: %+v", files)
+			}
+			if tour.Edges[1].Instructions.In("de") != "Weitergehen" {
+				t.Errorf("edge translation not applied: %+v", tour.Edges[1].Instructions)
+			}
+		})
+	}
+
+	// Units of XLIFF files whose names were dropped are matched by ID
+	stripped := regexp.MustCompile(` name="[^"]*"`).ReplaceAll(translate(TranslationFormatXLIFF), nil)
+	report, err := service.ImportTranslations(ctx, tour, "de", TranslationFormatXLIFF, bytes.NewReader(stripped))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(report.Updated) != len(translated) || report.Updated[1] != "nodes[id=3].short_description" {
+		t.Errorf("expected units without names to be matched, got %+v", report)
+	}
+}
+
+func TestTourService_ImportTranslationsRejectsOtherLanguage(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+
+	tour := newTestTour()
+	tour.Settings.Languages = []string{"de", "fr"}
+
+	data := encodePO(tour, "fr", nil)
+	if _, err := service.ImportTranslations(newTestContext(), tour, "de", TranslationFormatPO, bytes.NewReader(data)); err == nil {
+		t.Error("Expected error importing a French file as German, got nil")
+	}
+}
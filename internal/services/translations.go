@@ -0,0 +1,70 @@
+// internal/services/translations.go
+package services
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"gopkg.in/yaml.v3"
+)
+
+// IssueMissingTranslation is reported for every localizable field that has
+// no text in one of the languages declared in the tour settings.
+const IssueMissingTranslation = "missing_translation"
+
+// ErrLanguageNotDeclared is returned for a language the tour is neither
+// written nor translated in.
+var ErrLanguageNotDeclared = errors.New("language is not declared in the tour settings")
+
+// MissingTranslations lists, per declared language, the text fields that
+// have a default value but no translation.
+func (s *TourService) MissingTranslations(tour *models.Tour) GraphIssues {
+	var issues GraphIssues
+	fields := tour.TextFields()
+	for _, lang := range tour.Settings.Languages {
+		if lang == tour.Settings.PreferredLanguage {
+			continue
+		}
+		for _, field := range fields {
+			if field.Text.Has(lang) {
+				continue
+			}
+			issues = append(issues, GraphIssue{
+				Code:     IssueMissingTranslation,
+				Severity: SeverityWarning,
+				Path:     field.Path,
+				Message:  fmt.Sprintf("no %s translation", lang),
+			})
+		}
+	}
+	return issues
+}
+
+// ExportTourLanguage exports the tour in a single language using the plain
+// string schema. Fields without a translation fall back to the default text.
+// lang must be the preferred language of the tour or one of its
+// translations, or else ErrLanguageNotDeclared is returned.
+func (s *TourService) ExportTourLanguage(tour *models.Tour, lang string) ([]byte, error) {
+	if lang != tour.Settings.PreferredLanguage && !tour.Settings.HasLanguage(lang) {
+		return nil, fmt.Errorf("%w: %q", ErrLanguageNotDeclared, lang)
+	}
+	data, err := s.ExportTour(tour)
+	if err != nil {
+		return nil, err
+	}
+
+	// Work on a copy so the session tour keeps its translations
+	var flat models.Tour
+	if err := yaml.Unmarshal(data, &flat); err != nil {
+		return nil, fmt.Errorf("copying tour for export: %w", err)
+	}
+
+	for _, field := range flat.TextFields() {
+		*field.Text = models.NewText(field.Text.Get(lang))
+	}
+	flat.Settings.PreferredLanguage = lang
+	flat.Settings.Languages = nil
+
+	return yaml.Marshal(&flat)
+}
@@ -0,0 +1,84 @@
+// internal/services/translations_test.go
+package services
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/ceesaxp/tour-guide-editor/internal/repository"
+)
+
+func TestTourService_MissingTranslations(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+
+	tour := newTestTour()
+	tour.Settings.PreferredLanguage = "en"
+	tour.Settings.Languages = []string{"de"}
+	tour.Name.Set("de", "Testtour")
+	tour.Description.Set("de", "Eine Testtour")
+	for i := range tour.Nodes {
+		tour.Nodes[i].ShortDesc.Set("de", "Kurz")
+		tour.Nodes[i].Narrative.Set("de", "Erzählung")
+	}
+	tour.Nodes[1].Narrative.Set("de", "")
+
+	issues := service.MissingTranslations(tour)
+	if len(issues) != 1 {
+		t.Fatalf("Expected 1 issue, got %v", issues)
+	}
+	if issues[0].Code != IssueMissingTranslation || issues[0].Path != "nodes[1].narrative" {
+		t.Errorf("Unexpected issue %+v", issues[0])
+	}
+	if issues.HasErrors() {
+		t.Error("Missing translations should be warnings")
+	}
+}
+
+func TestTourService_ExportTourLanguage(t *testing.T) {
+	service := NewTourService(repository.NewMemory())
+
+	tour := newTestTour()
+	tour.Edges = []models.Edge{{From: 1, To: 2}, {From: 2, To: 3}}
+	tour.Settings.PreferredLanguage = "en"
+	tour.Settings.Languages = []string{"de"}
+	tour.Nodes[0].Narrative.Set("de", "Erste")
+
+	data, err := service.ExportTourLanguage(tour, "de")
+	if err != nil {
+		t.Fatalf("ExportTourLanguage() error = %v", err)
+	}
+	if strings.Contains(string(data), "translations:") {
+		t.Errorf("Expected flattened text, got:\n%s", data)
+	}
+
+	parsed, err := service.ParseTour(bytes.NewReader(data))
+	if err != nil {
+		t.Fatalf("ParseTour() error = %v", err)
+	}
+	if got := parsed.Nodes[0].Narrative.String(); got != "Erste" {
+		t.Errorf("Expected translated narrative, got %q", got)
+	}
+	if got := parsed.Nodes[1].Narrative.String(); got != tour.Nodes[1].Narrative.Value {
+		t.Errorf("Expected untranslated narrative to fall back, got %q", got)
+	}
+	if parsed.Settings.PreferredLanguage != "de" || parsed.Settings.Languages != nil {
+		t.Errorf("Unexpected settings %+v", parsed.Settings)
+	}
+
+	// The session tour keeps its translations
+	if !tour.Nodes[0].Narrative.Has("de") {
+		t.Error("Export modified the source tour")
+	}
+
+	if _, err := service.ExportTourLanguage(tour, "en"); err != nil {
+		t.Errorf("exporting the preferred language: %v", err)
+	}
+	for _, lang := range []string{"fr", "not a language"} {
+		if _, err := service.ExportTourLanguage(tour, lang); !errors.Is(err, ErrLanguageNotDeclared) {
+			t.Errorf("exporting %q: expected ErrLanguageNotDeclared, got %v", lang, err)
+		}
+	}
+}
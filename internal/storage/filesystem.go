@@ -0,0 +1,182 @@
+// internal/storage/filesystem.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"mime"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// FilesURL is the path the app serves the files of a Filesystem store
+// under, see handlers.MediaFiles.
+const FilesURL = "/media/files"
+
+// Filesystem keeps media files as <dir>/<key>, for authoring without object
+// storage. Like repository.Filesystem it writes to a temporary file that is
+// renamed into place. The app serves the files itself, under FilesURL.
+type Filesystem struct {
+	dir     string
+	baseURL string
+}
+
+// NewFilesystem stores media files in dir, creating it if needed. baseURL
+// is the URL the files are linked under; FilesURL if empty.
+func NewFilesystem(dir, baseURL string) (*Filesystem, error) {
+	if err := os.MkdirAll(dir, 0o755); err != nil {
+		return nil, fmt.Errorf("creating media directory: %w", err)
+	}
+	if baseURL == "" {
+		baseURL = FilesURL
+	}
+	return &Filesystem{dir: dir, baseURL: strings.TrimSuffix(baseURL, "/")}, nil
+}
+
+// path returns the file of key. Keys must be relative slash-separated
+// paths without hidden elements, so they cannot reach outside dir or the
+// temporary files of uploads.
+func (f *Filesystem) path(key string) (string, error) {
+	if !fs.ValidPath(key) || key == "." || strings.Contains(key, `\`) {
+		return "", fmt.Errorf("%w %q", ErrInvalidKey, key)
+	}
+	for _, elem := range strings.Split(key, "/") {
+		if strings.HasPrefix(elem, ".") {
+			return "", fmt.Errorf("%w %q", ErrInvalidKey, key)
+		}
+	}
+	return filepath.Join(f.dir, filepath.FromSlash(key)), nil
+}
+
+// typeByExtension guesses the type of a file from the extension of its key; the
+// filesystem keeps no metadata.
+func typeByExtension(key string) string {
+	if t := mime.TypeByExtension(path.Ext(key)); t != "" {
+		return t
+	}
+	return "application/octet-stream"
+}
+
+func (f *Filesystem) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	file, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+		return fmt.Errorf("writing media file %s: %w", key, err)
+	}
+
+	tmp, err := os.CreateTemp(filepath.Dir(file), "."+filepath.Base(file)+"-*.tmp")
+	if err != nil {
+		return fmt.Errorf("writing media file %s: %w", key, err)
+	}
+	defer os.Remove(tmp.Name()) // no-op once renamed
+
+	if _, err := io.Copy(tmp, body); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing media file %s: %w", key, err)
+	}
+	if err := tmp.Sync(); err != nil {
+		tmp.Close()
+		return fmt.Errorf("writing media file %s: %w", key, err)
+	}
+	if err := tmp.Close(); err != nil {
+		return fmt.Errorf("writing media file %s: %w", key, err)
+	}
+	if err := os.Chmod(tmp.Name(), 0o644); err != nil {
+		return fmt.Errorf("writing media file %s: %w", key, err)
+	}
+	if err := os.Rename(tmp.Name(), file); err != nil {
+		return fmt.Errorf("writing media file %s: %w", key, err)
+	}
+	return nil
+}
+
+func (f *Filesystem) Head(ctx context.Context, key string) (Object, error) {
+	file, err := f.path(key)
+	if err != nil {
+		return Object{}, err
+	}
+	info, err := os.Stat(file)
+	if errors.Is(err, fs.ErrNotExist) || err == nil && info.IsDir() {
+		return Object{}, ErrNotFound
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("reading media file %s: %w", key, err)
+	}
+	return Object{Key: key, Size: info.Size(), ContentType: typeByExtension(key), Modified: info.ModTime()}, nil
+}
+
+func (f *Filesystem) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	obj, err := f.Head(ctx, key)
+	if err != nil {
+		return nil, Object{}, err
+	}
+	file, _ := f.path(key)
+	r, err := os.Open(file)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, fmt.Errorf("reading media file %s: %w", key, err)
+	}
+	return r, obj, nil
+}
+
+func (f *Filesystem) Delete(ctx context.Context, key string) error {
+	file, err := f.path(key)
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(file); err != nil && !errors.Is(err, fs.ErrNotExist) {
+		return fmt.Errorf("deleting media file %s: %w", key, err)
+	}
+	return nil
+}
+
+func (f *Filesystem) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	err := filepath.WalkDir(f.dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		if strings.HasPrefix(entry.Name(), ".") && file != f.dir {
+			if entry.IsDir() {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			return nil
+		}
+		rel, err := filepath.Rel(f.dir, file)
+		if err != nil {
+			return err
+		}
+		key := filepath.ToSlash(rel)
+		if !strings.HasPrefix(key, prefix) {
+			return nil
+		}
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		objects = append(objects, Object{Key: key, Size: info.Size(), Modified: info.ModTime()})
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("listing media files: %w", err)
+	}
+	sort.Slice(objects, func(i, j int) bool { return objects[i].Key < objects[j].Key })
+	return objects, nil
+}
+
+func (f *Filesystem) PublicURL(key string) string {
+	return f.baseURL + "/" + key
+}
@@ -0,0 +1,93 @@
+// internal/storage/filesystem_test.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestFilesystem(t *testing.T) {
+	dir := t.TempDir()
+	store, err := NewFilesystem(dir, "")
+	if err != nil {
+		t.Fatal(err)
+	}
+	ctx := context.Background()
+	for _, key := range []string{"2024/05/01/a.jpg", "2024/05/02/b.mp3", "c.png"} {
+		if err := store.Put(ctx, key, strings.NewReader("data of "+key), "ignored"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	obj, err := store.Head(ctx, "2024/05/02/b.mp3")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if obj.Size != int64(len("data of 2024/05/02/b.mp3")) || obj.ContentType != "audio/mpeg" {
+		t.Errorf("unexpected object %+v", obj)
+	}
+
+	body, _, err := store.Get(ctx, "2024/05/01/a.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "data of 2024/05/01/a.jpg" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	// Unfinished uploads are left out of listings
+	if err := os.WriteFile(filepath.Join(dir, "2024", "05", ".d.jpg-1.tmp"), nil, 0o644); err != nil {
+		t.Fatal(err)
+	}
+	objects, err := store.List(ctx, "2024/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 2 || objects[0].Key != "2024/05/01/a.jpg" || objects[1].Key != "2024/05/02/b.mp3" {
+		t.Errorf("unexpected listing %+v", objects)
+	}
+
+	if err := store.Delete(ctx, "c.png"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Head(ctx, "c.png"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected ErrNotFound, got %v", err)
+	}
+	if err := store.Delete(ctx, "c.png"); err != nil {
+		t.Errorf("deleting a missing file: %v", err)
+	}
+	if _, err := store.Head(ctx, "2024"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("expected a directory not to be a file, got %v", err)
+	}
+
+	if url := store.PublicURL("2024/05/01/a.jpg"); url != "/media/files/2024/05/01/a.jpg" {
+		t.Errorf("PublicURL() = %q", url)
+	}
+}
+
+func TestFilesystem_InvalidKeys(t *testing.T) {
+	store, err := NewFilesystem(t.TempDir(), "https://media.example.com/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, key := range []string{"", ".", "../a.jpg", "/etc/passwd", "a//b.jpg", "2024/.a.jpg-1.tmp", `a\b.jpg`} {
+		t.Run(key, func(t *testing.T) {
+			if err := store.Put(context.Background(), key, strings.NewReader("x"), ""); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Put() error = %v, want ErrInvalidKey", err)
+			}
+			if _, _, err := store.Get(context.Background(), key); !errors.Is(err, ErrInvalidKey) {
+				t.Errorf("Get() error = %v, want ErrInvalidKey", err)
+			}
+		})
+	}
+	if url := store.PublicURL("a.jpg"); url != "https://media.example.com/a.jpg" {
+		t.Errorf("PublicURL() = %q", url)
+	}
+}
@@ -0,0 +1,46 @@
+// internal/storage/media.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"io"
+	"time"
+)
+
+var (
+	// ErrNotFound is returned for keys that are not in a MediaStore.
+	ErrNotFound = errors.New("media file not found")
+	// ErrInvalidKey is returned for keys a MediaStore cannot keep a file
+	// under.
+	ErrInvalidKey = errors.New("invalid media key")
+)
+
+// Object describes a stored media file.
+type Object struct {
+	Key         string
+	Size        int64
+	ContentType string // empty in listings
+	Modified    time.Time
+}
+
+// MediaStore keeps uploaded media files under keys such as
+// "sha256/3f/3fa9…e1.jpg": the content's SHA-256 hash, after a directory
+// named by its first two hex digits, and the file's extension. Keys are
+// slash-separated whatever the backend.
+type MediaStore interface {
+	// Put stores body under key, replacing any file already there.
+	Put(ctx context.Context, key string, body io.Reader, contentType string) error
+	// Head describes the file at key, or returns ErrNotFound.
+	Head(ctx context.Context, key string) (Object, error)
+	// Get opens the file at key, or returns ErrNotFound. The caller closes
+	// the returned reader.
+	Get(ctx context.Context, key string) (io.ReadCloser, Object, error)
+	// Delete removes the file at key. Deleting a missing file is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// List describes the files whose keys start with prefix, sorted by key.
+	List(ctx context.Context, prefix string) ([]Object, error)
+	// PublicURL returns the URL tours link the file at key with.
+	PublicURL(key string) string
+}
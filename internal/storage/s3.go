@@ -0,0 +1,42 @@
+// internal/storage/s3.go
+
+// Package storage keeps the media files uploaded to the editor, in
+// S3-compatible object storage or on local disk.
+package storage
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	awsconfig "github.com/aws/aws-sdk-go-v2/config"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ceesaxp/tour-guide-editor/internal/config"
+)
+
+// S3Client is the part of the S3 API S3Store uses. *s3.Client implements
+// it; internal/mocks has stand-ins for tests and development.
+type S3Client interface {
+	PutObject(ctx context.Context, params *s3.PutObjectInput, optFns ...func(*s3.Options)) (*s3.PutObjectOutput, error)
+	HeadObject(ctx context.Context, params *s3.HeadObjectInput, optFns ...func(*s3.Options)) (*s3.HeadObjectOutput, error)
+	GetObject(ctx context.Context, params *s3.GetObjectInput, optFns ...func(*s3.Options)) (*s3.GetObjectOutput, error)
+	DeleteObject(ctx context.Context, params *s3.DeleteObjectInput, optFns ...func(*s3.Options)) (*s3.DeleteObjectOutput, error)
+	ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error)
+}
+
+// NewS3Client returns a client for S3 in the configured region, or for the
+// S3-compatible service at cfg.Endpoint, such as LocalStack or MinIO, which
+// is addressed path-style. Credentials are looked up like the AWS CLI does:
+// from the environment, the shared config files or the instance role.
+func NewS3Client(ctx context.Context, cfg config.S3) (*s3.Client, error) {
+	awsCfg, err := awsconfig.LoadDefaultConfig(ctx, awsconfig.WithRegion(cfg.Region))
+	if err != nil {
+		return nil, fmt.Errorf("loading AWS config: %w", err)
+	}
+	return s3.NewFromConfig(awsCfg, func(o *s3.Options) {
+		if cfg.Endpoint != "" {
+			o.BaseEndpoint = aws.String(cfg.Endpoint)
+			o.UsePathStyle = true
+		}
+	}), nil
+}
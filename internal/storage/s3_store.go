@@ -0,0 +1,125 @@
+// internal/storage/s3_store.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/aws/aws-sdk-go-v2/service/s3/types"
+)
+
+// S3Store keeps media files as objects in an S3 bucket, which serves them
+// itself.
+type S3Store struct {
+	client  S3Client
+	bucket  string
+	baseURL string
+}
+
+// NewS3Store stores media files in bucket. baseURL is the URL the bucket's
+// objects are public under, see config.S3.MediaURL.
+func NewS3Store(client S3Client, bucket, baseURL string) *S3Store {
+	return &S3Store{client: client, bucket: bucket, baseURL: strings.TrimSuffix(baseURL, "/")}
+}
+
+func (s *S3Store) Put(ctx context.Context, key string, body io.Reader, contentType string) error {
+	_, err := s.client.PutObject(ctx, &s3.PutObjectInput{
+		Bucket:      aws.String(s.bucket),
+		Key:         aws.String(key),
+		Body:        body,
+		ContentType: aws.String(contentType),
+	})
+	if err != nil {
+		return fmt.Errorf("uploading %s to S3: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3Store) Head(ctx context.Context, key string) (Object, error) {
+	out, err := s.client.HeadObject(ctx, &s3.HeadObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return Object{}, ErrNotFound
+	}
+	if err != nil {
+		return Object{}, fmt.Errorf("checking %s in S3: %w", key, err)
+	}
+	return Object{
+		Key:         key,
+		Size:        aws.ToInt64(out.ContentLength),
+		ContentType: aws.ToString(out.ContentType),
+		Modified:    aws.ToTime(out.LastModified),
+	}, nil
+}
+
+func (s *S3Store) Get(ctx context.Context, key string) (io.ReadCloser, Object, error) {
+	out, err := s.client.GetObject(ctx, &s3.GetObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if isNotFound(err) {
+		return nil, Object{}, ErrNotFound
+	}
+	if err != nil {
+		return nil, Object{}, fmt.Errorf("downloading %s from S3: %w", key, err)
+	}
+	return out.Body, Object{
+		Key:         key,
+		Size:        aws.ToInt64(out.ContentLength),
+		ContentType: aws.ToString(out.ContentType),
+		Modified:    aws.ToTime(out.LastModified),
+	}, nil
+}
+
+func (s *S3Store) Delete(ctx context.Context, key string) error {
+	_, err := s.client.DeleteObject(ctx, &s3.DeleteObjectInput{
+		Bucket: aws.String(s.bucket),
+		Key:    aws.String(key),
+	})
+	if err != nil {
+		return fmt.Errorf("deleting %s from S3: %w", key, err)
+	}
+	return nil
+}
+
+func (s *S3Store) List(ctx context.Context, prefix string) ([]Object, error) {
+	var objects []Object
+	input := &s3.ListObjectsV2Input{
+		Bucket: aws.String(s.bucket),
+		Prefix: aws.String(prefix),
+	}
+	for {
+		out, err := s.client.ListObjectsV2(ctx, input)
+		if err != nil {
+			return nil, fmt.Errorf("listing media files: %w", err)
+		}
+		for _, obj := range out.Contents {
+			objects = append(objects, Object{
+				Key:      aws.ToString(obj.Key),
+				Size:     aws.ToInt64(obj.Size),
+				Modified: aws.ToTime(obj.LastModified),
+			})
+		}
+		if !aws.ToBool(out.IsTruncated) {
+			return objects, nil
+		}
+		input.ContinuationToken = out.NextContinuationToken
+	}
+}
+
+func (s *S3Store) PublicURL(key string) string {
+	return s.baseURL + "/" + key
+}
+
+func isNotFound(err error) bool {
+	var noSuchKey *types.NoSuchKey
+	var notFound *types.NotFound
+	return errors.As(err, &noSuchKey) || errors.As(err, &notFound)
+}
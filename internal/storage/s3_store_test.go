@@ -0,0 +1,80 @@
+// internal/storage/s3_store_test.go
+package storage
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/aws/aws-sdk-go-v2/service/s3"
+	"github.com/ceesaxp/tour-guide-editor/internal/mocks"
+)
+
+var (
+	_ S3Client = (*mocks.MockS3Client)(nil)
+	_ S3Client = (*mocks.FakeS3)(nil)
+
+	_ MediaStore = (*S3Store)(nil)
+	_ MediaStore = (*Filesystem)(nil)
+)
+
+// pagedS3 lists two objects at a time, to test following continuations.
+type pagedS3 struct {
+	*mocks.FakeS3
+}
+
+func (p pagedS3) ListObjectsV2(ctx context.Context, params *s3.ListObjectsV2Input, optFns ...func(*s3.Options)) (*s3.ListObjectsV2Output, error) {
+	params.MaxKeys = aws.Int32(2)
+	return p.FakeS3.ListObjectsV2(ctx, params, optFns...)
+}
+
+func TestS3Store(t *testing.T) {
+	fake := mocks.NewFakeS3()
+	store := NewS3Store(pagedS3{fake}, "media", "http://localhost:4566/media/")
+	ctx := context.Background()
+	for i := 1; i <= 5; i++ {
+		key := fmt.Sprintf("2024/05/0%d/%d.jpg", i, i)
+		if err := store.Put(ctx, key, strings.NewReader("data"), "image/jpeg"); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	if obj, err := store.Head(ctx, "2024/05/03/3.jpg"); err != nil || obj.Size != 4 {
+		t.Errorf("unexpected object %+v, %v", obj, err)
+	}
+	body, _, err := store.Get(ctx, "2024/05/01/1.jpg")
+	if err != nil {
+		t.Fatal(err)
+	}
+	data, _ := io.ReadAll(body)
+	body.Close()
+	if string(data) != "data" {
+		t.Errorf("unexpected content %q", data)
+	}
+
+	objects, err := store.List(ctx, "2024/")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(objects) != 5 || objects[4].Key != "2024/05/05/5.jpg" {
+		t.Errorf("expected all pages listed, got %+v", objects)
+	}
+
+	if err := store.Delete(ctx, "2024/05/01/1.jpg"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := store.Head(ctx, "2024/05/01/1.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Head() error = %v, want ErrNotFound", err)
+	}
+	if _, _, err := store.Get(ctx, "2024/05/01/1.jpg"); !errors.Is(err, ErrNotFound) {
+		t.Errorf("Get() error = %v, want ErrNotFound", err)
+	}
+
+	if url := store.PublicURL("2024/05/02/2.jpg"); url != "http://localhost:4566/media/2024/05/02/2.jpg" {
+		t.Errorf("PublicURL() = %q", url)
+	}
+}
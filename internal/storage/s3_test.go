@@ -0,0 +1,49 @@
+// internal/storage/s3_test.go
+package storage
+
+import (
+	"context"
+	"testing"
+
+	"github.com/aws/aws-sdk-go-v2/aws"
+	"github.com/ceesaxp/tour-guide-editor/internal/config"
+)
+
+func TestNewS3Client(t *testing.T) {
+	tests := []struct {
+		name         string
+		cfg          config.S3
+		wantEndpoint string
+		wantPath     bool
+	}{
+		{
+			name: "AWS",
+			cfg:  config.S3{Region: "eu-central-1"},
+		},
+		{
+			name:         "custom endpoint",
+			cfg:          config.S3{Region: "us-east-1", Endpoint: "http://localhost:4566"},
+			wantEndpoint: "http://localhost:4566",
+			wantPath:     true,
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			client, err := NewS3Client(context.Background(), tt.cfg)
+			if err != nil {
+				t.Fatal(err)
+			}
+			opts := client.Options()
+			if opts.Region != tt.cfg.Region {
+				t.Errorf("region = %q, want %q", opts.Region, tt.cfg.Region)
+			}
+			if got := aws.ToString(opts.BaseEndpoint); got != tt.wantEndpoint {
+				t.Errorf("endpoint = %q, want %q", got, tt.wantEndpoint)
+			}
+			if opts.UsePathStyle != tt.wantPath {
+				t.Errorf("path-style = %v, want %v", opts.UsePathStyle, tt.wantPath)
+			}
+		})
+	}
+}
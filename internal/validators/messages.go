@@ -0,0 +1,162 @@
+// internal/validators/messages.go
+package validators
+
+import (
+	"errors"
+	"reflect"
+	"regexp"
+	"strings"
+
+	"github.com/go-playground/locales/de"
+	"github.com/go-playground/locales/en"
+	ut "github.com/go-playground/universal-translator"
+	"github.com/go-playground/validator/v10"
+)
+
+// messages holds the text shown for each validation tag, per language. {0}
+// is replaced with the tag parameter, e.g. the allowed values of oneof.
+// "invalid" is used for tags without a message of their own and "summary"
+// heads the list of errors. "id_taken" and "id_fixed" are not tags but
+// reject an ID the editor cannot save.
+var messages = map[string]map[string]string{
+	"en": {
+		"required":           "This field is required",
+		"required_if":        "This field is required; a quiz needs at least two options",
+		"url":                "Enter a full URL, e.g. https://example.com/image.jpg",
+		"oneof":              "Choose one of: {0}",
+		"min":                "Must be at least {0}",
+		"latitude":           "Enter a latitude between -90 and 90",
+		"longitude":          "Enter a longitude between -180 and 180",
+		"gtfield":            "Must be later than the start date",
+		"nefield":            "Must differ from the source node",
+		"bcp47_language_tag": "Enter a language tag such as en or pt-BR",
+		"unique_media_id":    "Media file ID {0} is used more than once",
+		"id_taken":           "This ID is already in use",
+		"id_fixed":           "The ID of a saved tour cannot be changed",
+		"invalid":            "This value is not valid",
+		"summary":            "Please correct the highlighted fields",
+	},
+	"de": {
+		"required":           "Dieses Feld ist erforderlich",
+		"required_if":        "Dieses Feld ist erforderlich; ein Quiz braucht mindestens zwei Antworten",
+		"url":                "Geben Sie eine vollständige URL ein, z. B. https://example.com/bild.jpg",
+		"oneof":              "Wählen Sie einen der Werte: {0}",
+		"min":                "Muss mindestens {0} sein",
+		"latitude":           "Geben Sie einen Breitengrad zwischen -90 und 90 ein",
+		"longitude":          "Geben Sie einen Längengrad zwischen -180 und 180 ein",
+		"gtfield":            "Muss nach dem Startdatum liegen",
+		"nefield":            "Muss sich vom Ausgangsknoten unterscheiden",
+		"bcp47_language_tag": "Geben Sie ein Sprachkürzel wie en oder pt-BR ein",
+		"unique_media_id":    "Die Mediendatei-ID {0} wird mehrfach verwendet",
+		"id_taken":           "Diese ID wird bereits verwendet",
+		"id_fixed":           "Die ID einer gespeicherten Tour kann nicht geändert werden",
+		"invalid":            "Dieser Wert ist ungültig",
+		"summary":            "Bitte korrigieren Sie die markierten Felder",
+	},
+}
+
+// NewTranslator returns a translator for the validation messages, falling
+// back to English for unsupported languages.
+func NewTranslator() (*ut.UniversalTranslator, error) {
+	uni := ut.New(en.New(), en.New(), de.New())
+	for lang, texts := range messages {
+		trans, _ := uni.GetTranslator(lang)
+		for key, text := range texts {
+			if err := trans.Add(key, text, false); err != nil {
+				return nil, err
+			}
+		}
+	}
+	return uni, nil
+}
+
+// Message returns the translated text for key, such as "summary".
+func Message(trans ut.Translator, key string, params ...string) string {
+	if msg, err := trans.T(key, params...); err == nil {
+		return msg
+	}
+	msg, _ := trans.T("invalid")
+	return msg
+}
+
+// FieldErrors translates validation errors for root, the struct that was
+// validated, into messages keyed by form field name, e.g.
+// "media_files[0].uri". It returns nil for errors that did not come from
+// the validator.
+func FieldErrors(err error, root interface{}, trans ut.Translator) map[string]string {
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return nil
+	}
+
+	rootType := reflect.TypeOf(root)
+	fields := make(map[string]string, len(verrs))
+	for _, fe := range verrs {
+		name := FieldName(rootType, fe.StructNamespace())
+		if _, ok := fields[name]; ok {
+			continue // keep the first error of each field
+		}
+		fields[name] = Message(trans, fe.Tag(), strings.ReplaceAll(fe.Param(), " ", ", "))
+	}
+	return fields
+}
+
+var namespaceSegment = regexp.MustCompile(`^([^\[]+)((?:\[[^\]]*\])*)$`)
+
+// FieldName converts a validator struct namespace such as
+// "Node.MediaFiles[0].URI" into the form field name "media_files[0].uri",
+// using the yaml names of root's fields. The index of a slice of plain
+// values is dropped, as such slices are posted as a repeated field.
+func FieldName(root reflect.Type, namespace string) string {
+	segments := strings.Split(namespace, ".")
+	if len(segments) > 1 {
+		segments = segments[1:] // the root type itself
+	}
+
+	t := root
+	var path []string
+	for i, segment := range segments {
+		for t != nil && t.Kind() == reflect.Pointer {
+			t = t.Elem()
+		}
+
+		m := namespaceSegment.FindStringSubmatch(segment)
+		if m == nil || t == nil || t.Kind() != reflect.Struct {
+			path = append(path, segment)
+			t = nil
+			continue
+		}
+
+		field, ok := t.FieldByName(m[1])
+		if !ok {
+			path = append(path, strings.ToLower(segment))
+			t = nil
+			continue
+		}
+
+		name, _, _ := strings.Cut(field.Tag.Get("yaml"), ",")
+		if name == "" {
+			name = m[1]
+		}
+
+		t = field.Type
+		index := m[2]
+		for range strings.Count(index, "[") {
+			if t.Kind() == reflect.Slice || t.Kind() == reflect.Array || t.Kind() == reflect.Map {
+				t = t.Elem()
+			}
+		}
+		if last := i == len(segments)-1; last && index != "" && !isStruct(t) {
+			index = ""
+		}
+		path = append(path, name+index)
+	}
+	return strings.Join(path, ".")
+}
+
+func isStruct(t reflect.Type) bool {
+	for t.Kind() == reflect.Pointer {
+		t = t.Elem()
+	}
+	return t.Kind() == reflect.Struct
+}
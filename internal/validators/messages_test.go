@@ -0,0 +1,102 @@
+// internal/validators/messages_test.go
+package validators
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/ceesaxp/tour-guide-editor/internal/models"
+	"github.com/go-playground/validator/v10"
+)
+
+func TestFieldName(t *testing.T) {
+	tests := []struct {
+		root      interface{}
+		namespace string
+		want      string
+	}{
+		{models.Node{}, "Node.ShortDesc", "short_description"},
+		{models.Node{}, "Node.Location.Lat", "location.lat"},
+		{models.Node{}, "Node.MediaFiles[2].URI", "media_files[2].uri"},
+		{&models.Node{}, "Node.EntryCondition.Options", "entry_condition.options"},
+		{models.Tour{}, "Tour.Milestones.At25.Text", "milestones.at_25.text"},
+		{models.Tour{}, "Tour.Settings.Languages[1]", "settings.languages"},
+		{models.Tour{}, "Tour.Nodes[0].ExitCondition.MediaLink", "nodes[0].exit_condition.media_link"},
+		{models.Tour{}, "Tour.Unknown", "unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.namespace, func(t *testing.T) {
+			if got := FieldName(reflect.TypeOf(tt.root), tt.namespace); got != tt.want {
+				t.Errorf("FieldName() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFieldErrors(t *testing.T) {
+	v := validator.New()
+	if err := RegisterCustomValidations(v); err != nil {
+		t.Fatal(err)
+	}
+	models.RegisterValidation(v)
+
+	uni, err := NewTranslator()
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	node := &models.Node{
+		ID:        1,
+		Location:  models.Location{Lat: 95, Lon: 20},
+		ShortDesc: models.NewText("Gate"),
+		MediaFiles: []models.MediaFile{
+			{ID: "media-1", Type: "gif", URI: "http://example.com/a.gif"},
+		},
+	}
+	verr := v.Struct(node)
+
+	tests := []struct {
+		lang string
+		want map[string]string
+	}{
+		{
+			lang: "en",
+			want: map[string]string{
+				"narrative":           "This field is required",
+				"location.lat":        "Enter a latitude between -90 and 90",
+				"media_files[0].type": "Choose one of: image, audio, video",
+			},
+		},
+		{
+			lang: "de",
+			want: map[string]string{
+				"narrative":           "Dieses Feld ist erforderlich",
+				"location.lat":        "Geben Sie einen Breitengrad zwischen -90 und 90 ein",
+				"media_files[0].type": "Wählen Sie einen der Werte: image, audio, video",
+			},
+		},
+		{
+			lang: "fr", // falls back to English
+			want: map[string]string{
+				"narrative":           "This field is required",
+				"location.lat":        "Enter a latitude between -90 and 90",
+				"media_files[0].type": "Choose one of: image, audio, video",
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.lang, func(t *testing.T) {
+			trans, _ := uni.FindTranslator(tt.lang)
+			got := FieldErrors(verr, node, trans)
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("FieldErrors() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+
+	if got := FieldErrors(nil, node, uni.GetFallback()); got != nil {
+		t.Errorf("expected nil for a nil error, got %v", got)
+	}
+}
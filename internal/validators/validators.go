@@ -2,6 +2,9 @@
 package validators
 
 import (
+	"reflect"
+	"strings"
+
 	"github.com/go-playground/validator/v10"
 )
 
@@ -14,25 +17,29 @@ func RegisterCustomValidations(v *validator.Validate) error {
 	return nil
 }
 
-// requiredIf implements conditional required validation
+// requiredIf implements conditional required validation. The parameter is
+// "<Field> <value>"; when the sibling field equals value the tagged field must
+// be set. Slices, such as quiz options, need at least two entries.
 func requiredIf(fl validator.FieldLevel) bool {
-	// Get the field name and expected value from the tag
-	params := fl.Param()
-	if params == "" {
+	params := strings.Fields(fl.Param())
+	if len(params) != 2 {
 		return true
 	}
 
-	// For our specific case with quiz type
-	field := fl.Parent().FieldByName(params)
+	field := fl.Parent().FieldByName(params[0])
 	if !field.IsValid() {
 		return true
 	}
 
-	// Check if the field matches the expected value
-	if field.String() == "quiz" {
-		// For quiz type, options should not be empty
-		return len(fl.Field().Interface().([]string)) >= 2
+	if field.String() != params[1] {
+		return true
 	}
 
-	return true
+	value := fl.Field()
+	switch value.Kind() {
+	case reflect.Slice:
+		return value.Len() >= 2
+	default:
+		return !value.IsZero()
+	}
 }